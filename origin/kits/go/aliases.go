@@ -0,0 +1,95 @@
+// ORIGIN Go Kit - pack ID aliases
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// AliasesFile maps renamed pack IDs to their new IDs, as a JSON object of
+// old ID to new ID. It is optional.
+const AliasesFile = "aliases.json"
+
+// resolveAlias returns the ID that id was renamed to, following chains of
+// renames such as A -> B -> C, or id itself if it is not an alias. An alias
+// that leads into a cycle is left unchanged.
+func resolveAlias(aliases map[string]string, id string) string {
+	to, ok := aliases[id]
+	if !ok {
+		return id
+	}
+	seen := map[string]bool{id: true}
+	for !seen[to] {
+		seen[to] = true
+		next, ok := aliases[to]
+		if !ok {
+			return to
+		}
+		to = next
+	}
+	return id
+}
+
+// ApplyAliases rewrites each edge source and target and each Related entry
+// that is an old ID in aliases to its new ID, and returns how many were
+// rewritten. Pack IDs are left alone. Either index or graph may be nil.
+func ApplyAliases(index *PacksIndex, graph *Graph, aliases map[string]string) int {
+	if len(aliases) == 0 {
+		return 0
+	}
+	rewritten := 0
+	rewrite := func(id *string) {
+		if to := resolveAlias(aliases, *id); to != *id {
+			*id = to
+			rewritten++
+		}
+	}
+	if index != nil {
+		for i := range index.Packs {
+			for j := range index.Packs[i].Related {
+				rewrite(&index.Packs[i].Related[j])
+			}
+		}
+	}
+	if graph != nil {
+		for i := range graph.Edges {
+			rewrite(&graph.Edges[i].Source)
+			rewrite(&graph.Edges[i].Target)
+		}
+	}
+	return rewritten
+}
+
+// loadAliases reads AliasesFile from the base directory, returning nil
+// when there is none
+func (l *Loader) loadAliases() (map[string]string, error) {
+	data, err := fs.ReadFile(l.fsys(), AliasesFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var aliases map[string]string
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("%s: %w", l.resolve(AliasesFile), invalidSchema(err))
+	}
+	return aliases, nil
+}
+
+// applyAliases applies the base directory's aliases to index or graph,
+// logging how many references were rewritten
+func (l *Loader) applyAliases(index *PacksIndex, graph *Graph) error {
+	aliases, err := l.loadAliases()
+	if err != nil || aliases == nil {
+		return err
+	}
+	n := ApplyAliases(index, graph, aliases)
+	l.log().Info("applied aliases", "path", l.resolve(AliasesFile), "aliases", len(aliases), "rewritten", n)
+	return nil
+}
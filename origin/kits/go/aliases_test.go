@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestApplyAliases(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", Related: []string{"old-b", "C"}},
+		{ID: "C", Related: []string{"older-b"}},
+	}}
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "old-b", Type: "related"},
+		{Source: "older-b", Target: "C", Type: "child"},
+		{Source: "x", Target: "y", Type: "loop"},
+	}}
+	aliases := map[string]string{"old-b": "B", "older-b": "old-b", "x": "y", "y": "x"}
+
+	if n := ApplyAliases(&index, &graph, aliases); n != 4 {
+		t.Errorf("rewritten = %d, want 4", n)
+	}
+	if got := index.Packs[0].Related; !slices.Equal(got, []string{"B", "C"}) {
+		t.Errorf("A related = %v, want [B C]", got)
+	}
+	if got := index.Packs[1].Related; !slices.Equal(got, []string{"B"}) {
+		t.Errorf("C related = %v, want the chain followed to B", got)
+	}
+	want := []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "C", Type: "child"},
+		{Source: "x", Target: "y", Type: "loop"},
+	}
+	if !reflect.DeepEqual(graph.Edges, want) {
+		t.Errorf("edges = %v, want %v", graph.Edges, want)
+	}
+
+	if n := ApplyAliases(nil, &graph, nil); n != 0 {
+		t.Errorf("no aliases rewrote %d references", n)
+	}
+}
+
+func TestLoaderAliases(t *testing.T) {
+	var logs bytes.Buffer
+	logger, _ := newLogger(&logs, 1, LogFormatText)
+	loader := &Loader{Logger: logger, FS: fstest.MapFS{
+		IndexFile:   {Data: []byte(`{"packs":[{"id":"A","related":["old"]},{"id":"B"}]}`)},
+		GraphFile:   {Data: []byte(`{"edges":[{"source":"old","target":"A","type":"child"}]}`)},
+		AliasesFile: {Data: []byte(`{"old":"B"}`)},
+	}}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if got := index.Packs[0].Related; !slices.Equal(got, []string{"B"}) {
+		t.Errorf("related = %v, want [B]", got)
+	}
+	if graph.Edges[0].Source != "B" {
+		t.Errorf("edge source = %q, want B", graph.Edges[0].Source)
+	}
+	if !strings.Contains(logs.String(), "applied aliases") || !strings.Contains(logs.String(), "rewritten=1") {
+		t.Errorf("logs = %q, want the rewrite counts", logs.String())
+	}
+
+	loader.FS.(fstest.MapFS)[AliasesFile] = &fstest.MapFile{Data: []byte(`["old"]`)}
+	if _, err := loader.LoadIndex(); err == nil || !strings.Contains(err.Error(), AliasesFile) {
+		t.Errorf("malformed aliases: err = %v, want it to name %s", err, AliasesFile)
+	}
+}
@@ -0,0 +1,126 @@
+// ORIGIN Go Kit - single-file dataset bundles
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// bundle is the file format of WriteBundle: the index and graph as they
+// appear in packs.index.json and graph.json
+type bundle struct {
+	Index *PacksIndex `json:"index"`
+	Graph *Graph      `json:"graph"`
+}
+
+// WriteBundle writes index and graph as one JSON object,
+// {"index": {...}, "graph": {...}}, each member in its dist file format.
+// HTML characters are not escaped, so text is written as it was read.
+func WriteBundle(w io.Writer, index PacksIndex, graph Graph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(bundle{&index, &graph})
+}
+
+// LoadBundle reads a bundle written by WriteBundle. Both members must be
+// present; the schema versions are checked as the loader checks files.
+func LoadBundle(r io.Reader) (PacksIndex, Graph, error) {
+	var b bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return PacksIndex{}, Graph{}, fmt.Errorf("decoding bundle: %w", invalidSchema(err))
+	}
+	if b.Index == nil || b.Graph == nil {
+		return PacksIndex{}, Graph{}, fmt.Errorf(`%w: bundle needs both "index" and "graph" members`, ErrMalformedDist)
+	}
+	for _, version := range []int{b.Index.SchemaVersion, b.Graph.SchemaVersion} {
+		if err := CheckSchemaVersion(version); err != nil {
+			return PacksIndex{}, Graph{}, err
+		}
+	}
+	return *b.Index, *b.Graph, nil
+}
+
+// isBundle reports whether path is a local file, which the loader reads as
+// a bundle, rather than a dist directory or URL
+func isBundle(path string) bool {
+	if isURL(path) {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// bundleFile is a bundle file decoded into its members. It is decoded on
+// first use and again only when the file's modification time or size
+// changes, so a loader reads both members from one decode. It is safe for
+// concurrent use.
+type bundleFile struct {
+	path string
+
+	mu      sync.Mutex
+	stamp   fileStamp
+	members map[string]json.RawMessage
+}
+
+// member returns the raw JSON of the named member, such as "index"
+func (b *bundleFile) member(name string) (json.RawMessage, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return nil, false, err
+	}
+	if stamp := (fileStamp{info.ModTime(), info.Size()}); b.members == nil || stamp != b.stamp {
+		data, err := os.ReadFile(b.path)
+		if err != nil {
+			return nil, false, err
+		}
+		var members map[string]json.RawMessage
+		if err := json.Unmarshal(data, &members); err != nil {
+			return nil, false, fmt.Errorf("bundle %s: %w", b.path, invalidSchema(err))
+		}
+		if members == nil {
+			members = map[string]json.RawMessage{}
+		}
+		b.members, b.stamp = members, stamp
+	}
+	raw, ok := b.members[name]
+	return raw, ok, nil
+}
+
+// bundleFS serves the members of a bundle file under the index and graph
+// file names, so a loader reads a bundle as it reads a dist directory
+type bundleFS struct {
+	file         *bundleFile
+	index, graph string
+}
+
+func (b bundleFS) Open(name string) (fs.File, error) {
+	var member string
+	switch name {
+	case b.index:
+		member = "index"
+	case b.graph:
+		member = "graph"
+	default:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	raw, ok, err := b.file.member(member)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: b.file.path + "#" + name, Err: fs.ErrNotExist}
+	}
+	return &memFile{Reader: bytes.NewReader(raw), name: name, size: int64(len(raw))}, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// bundleDataset returns an index and graph using every modeled field
+func bundleDataset() (PacksIndex, Graph) {
+	packs := samplePacks()
+	packs[0].Tags = []string{"core"}
+	packs[0].Extra = map[string]json.RawMessage{"summary": json.RawMessage(`"R&D notes"`)}
+	index, graph := withCounts(PacksIndex{SchemaVersion: 1, Packs: packs}, Graph{SchemaVersion: 1, Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related", Weight: 2.5},
+		{Source: "B", Target: "C", Type: "child"},
+	}})
+	index.Metadata.Extra = map[string]json.RawMessage{"generated_at": json.RawMessage(`"2026-01-01"`)}
+	return index, graph
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	index, graph := bundleDataset()
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, index, graph); err != nil {
+		t.Fatalf("WriteBundle: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"R&D notes"`) {
+		t.Errorf("bundle escaped HTML characters:\n%s", buf.String())
+	}
+
+	gotIndex, gotGraph, err := LoadBundle(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("LoadBundle: %v", err)
+	}
+	if !reflect.DeepEqual(gotIndex, index) {
+		t.Errorf("index = %+v, want %+v", gotIndex, index)
+	}
+	if !reflect.DeepEqual(gotGraph, graph) {
+		t.Errorf("graph = %+v, want %+v", gotGraph, graph)
+	}
+
+	if _, _, err := LoadBundle(strings.NewReader(`{"index":{"packs":[]}}`)); err == nil {
+		t.Error("bundle without a graph loaded")
+	}
+	if _, _, err := LoadBundle(strings.NewReader(`{"index":{"schema_version":9,"packs":[]},"graph":{"edges":[]}}`)); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Errorf("newer schema: err = %v, want ErrUnsupportedSchema", err)
+	}
+}
+
+func TestLoaderBundle(t *testing.T) {
+	index, graph := bundleDataset()
+	path := filepath.Join(t.TempDir(), "dist.bundle.json")
+	if err := writeJSONFile(path, func(w io.Writer) error { return WriteBundle(w, index, graph) }); err != nil {
+		t.Fatal(err)
+	}
+
+	gotIndex, gotGraph, err := LoadAll(NewLoader(path))
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if !reflect.DeepEqual(gotIndex, index) || !reflect.DeepEqual(gotGraph, graph) {
+		t.Errorf("loaded %+v and %+v, want the bundled dataset", gotIndex, gotGraph)
+	}
+
+	loader := NewLoader(path)
+	if _, err := loader.LoadIndex(); err != nil {
+		t.Fatalf("LoadIndex: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"index":{"packs":[]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := loader.LoadIndex(); err != nil || len(got.Packs) != 0 {
+		t.Errorf("after rewriting the bundle: %d packs, %v; want the new, empty index", len(got.Packs), err)
+	}
+	if _, err := loader.LoadGraph(); !errors.Is(err, ErrDistNotFound) || !strings.Contains(err.Error(), path+"#"+GraphFile) {
+		t.Errorf("missing member: err = %v, want ErrDistNotFound naming it", err)
+	}
+}
+
+func TestBundleCommand(t *testing.T) {
+	a, out, _ := testApp()
+	index, graph := bundleDataset()
+	dir := t.TempDir()
+	if err := SaveIndex(filepath.Join(dir, IndexFile), index); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJSONFile(filepath.Join(dir, GraphFile), graph.WriteJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.runCommand(NewLoader(dir), "bundle", nil); err != nil {
+		t.Fatalf("bundle: %v", err)
+	}
+	gotIndex, gotGraph, err := LoadBundle(out)
+	if err != nil || !reflect.DeepEqual(gotIndex, index) || !reflect.DeepEqual(gotGraph, graph) {
+		t.Errorf("bundle output loads as %+v, %+v, %v", gotIndex, gotGraph, err)
+	}
+}
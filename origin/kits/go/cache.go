@@ -0,0 +1,189 @@
+// ORIGIN Go Kit - in-memory dataset cache
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"math"
+	"sync"
+)
+
+// Cache holds a loaded index and graph for long-lived processes. Each is
+// loaded on first use and kept until Invalidate is called. It is safe for
+// concurrent use; concurrent first calls share a single load.
+type Cache struct {
+	Loader *Loader
+
+	mu    sync.RWMutex
+	index *PacksIndex
+	graph *Graph
+}
+
+// NewCache returns an empty cache reading through l
+func NewCache(l *Loader) *Cache {
+	return &Cache{Loader: l}
+}
+
+// Index returns the cached index, loading it if needed. Load errors are
+// not cached, so a later call retries.
+func (c *Cache) Index() (PacksIndex, error) {
+	c.mu.RLock()
+	if c.index != nil {
+		index := *c.index
+		c.mu.RUnlock()
+		return index, nil
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.index != nil {
+		return *c.index, nil
+	}
+	index, err := c.Loader.LoadIndex()
+	if err != nil {
+		return PacksIndex{}, err
+	}
+	c.index = &index
+	return index, nil
+}
+
+// Graph returns the cached graph, loading it if needed
+func (c *Cache) Graph() (Graph, error) {
+	c.mu.RLock()
+	if c.graph != nil {
+		graph := *c.graph
+		c.mu.RUnlock()
+		return graph, nil
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.graph != nil {
+		return *c.graph, nil
+	}
+	graph, err := c.Loader.LoadGraph()
+	if err != nil {
+		return Graph{}, err
+	}
+	c.graph = &graph
+	return graph, nil
+}
+
+// UpdateGraph applies update to a copy of the cached graph, loading it if
+// needed, and keeps the result unless update fails. Updates last until
+// the next Invalidate.
+func (c *Cache) UpdateGraph(update func(*Graph) error) (Graph, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.graph == nil {
+		loaded, err := c.Loader.LoadGraph()
+		if err != nil {
+			return Graph{}, err
+		}
+		c.graph = &loaded
+	}
+	graph := *c.graph
+	if err := update(&graph); err != nil {
+		return Graph{}, err
+	}
+	c.graph = &graph
+	return graph, nil
+}
+
+// PackDetail is a pack together with every edge incident to it
+type PackDetail struct {
+	Pack
+	Neighbors []neighbor `json:"neighbors"`
+	// RelatedPacks and Unresolved are set by show -expand: the packs named
+	// in Related, and how many of its IDs are not in the index
+	RelatedPacks []Pack `json:"related_packs,omitempty"`
+	Unresolved   int    `json:"unresolved_related,omitempty"`
+}
+
+// packDetail joins id's incident edges with neighbor titles from index.
+// Neighbors missing from the index are kept with an empty title.
+func packDetail(index PacksIndex, graph Graph, id string) (PackDetail, error) {
+	p, err := requirePack(index.ByID(), id)
+	if err != nil {
+		return PackDetail{}, err
+	}
+	n := neighborsOf(index, graph.BuildAdjacency(), p, math.MaxInt)
+	return PackDetail{Pack: p, Neighbors: n.Neighbors}, nil
+}
+
+// PackDetail returns the cached pack id with its neighbors. A dataset
+// without a graph file gives the pack with no neighbors.
+func (c *Cache) PackDetail(id string) (PackDetail, error) {
+	index, err := c.Index()
+	if err != nil {
+		return PackDetail{}, err
+	}
+	graph, err := c.Graph()
+	if err != nil && !errors.Is(err, ErrDistNotFound) {
+		return PackDetail{}, err
+	}
+	return packDetail(index, graph, id)
+}
+
+// Invalidate drops the cached data so the next call reloads it
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.index = nil
+	c.graph = nil
+}
+
+// memoFS keeps every file it reads from FS in memory, so repeated loads
+// skip the disk. Files found missing, such as an absent AliasesFile, are
+// remembered as missing.
+type memoFS struct {
+	FS fs.FS
+
+	mu      sync.Mutex
+	files   map[string][]byte
+	missing map[string]bool
+}
+
+func (m *memoFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.missing[name] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	data, ok := m.files[name]
+	if !ok {
+		var err error
+		if data, err = fs.ReadFile(m.FS, name); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				if m.missing == nil {
+					m.missing = make(map[string]bool)
+				}
+				m.missing[name] = true
+			}
+			return nil, err
+		}
+		if m.files == nil {
+			m.files = make(map[string][]byte)
+		}
+		m.files[name] = data
+	}
+	return &memFile{Reader: bytes.NewReader(data), name: name, size: int64(len(data))}, nil
+}
+
+// ReadDir lists FS directly, so shard globs see new files
+func (m *memoFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(m.FS, name)
+}
+
+// memoize returns a loader that reads each of l's files at most once
+func memoize(l *Loader) *Loader {
+	m := *l
+	m.FS = &memoFS{FS: l.fsys()}
+	return &m
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+// countingFS counts how many times each file is opened
+type countingFS struct {
+	fs.FS
+	opens atomic.Int64
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opens.Add(1)
+	return c.FS.Open(name)
+}
+
+func TestCacheLoadsOnce(t *testing.T) {
+	fsys := &countingFS{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A"},{"id":"B"}]}`)},
+	}}
+	cache := NewCache(&Loader{FS: fsys})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				index, err := cache.Index()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if len(index.Packs) != 2 {
+					t.Errorf("got %d packs, want 2", len(index.Packs))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Each load opens the index and probes for AliasesFile
+	if got := fsys.opens.Load(); got != 2 {
+		t.Errorf("opened %d files, want 2", got)
+	}
+
+	cache.Invalidate()
+	if _, err := cache.Index(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fsys.opens.Load(); got != 4 {
+		t.Errorf("after Invalidate, opened %d files, want 4", got)
+	}
+}
+
+func TestPackDetailMissingNeighbor(t *testing.T) {
+	index := PacksIndex{Packs: samplePacks()}
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "Z", Target: "A", Type: "child"},
+	}}
+
+	d, err := packDetail(index, graph, "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Title != "Alpha" || len(d.Neighbors) != 2 {
+		t.Fatalf("detail = %+v", d)
+	}
+	if n := d.Neighbors[1]; n.ID != "Z" || n.Title != "" || n.Direction != "in" {
+		t.Errorf("missing neighbor = %+v, want Z with an empty title", n)
+	}
+}
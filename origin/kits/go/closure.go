@@ -0,0 +1,108 @@
+// ORIGIN Go Kit - transitive closure
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ErrGraphTooLarge is returned when a computation would exceed its size
+// limit
+var ErrGraphTooLarge = errors.New("graph too large")
+
+// ClosureNodeLimit caps the number of nodes TransitiveClosure accepts. A
+// closure holds one entry per reachable pair, so memory grows with the
+// square of the node count: a fully connected hierarchy at the default
+// limit is 4M entries, several hundred MB. Set it to 0 to opt in to any
+// size.
+var ClosureNodeLimit = 2000
+
+// Closure maps each node to the set of nodes it reaches. Nodes with no
+// outgoing edges have no entry.
+type Closure map[string]map[string]bool
+
+// Reaches reports whether from reaches to in the closure
+func (c Closure) Reaches(from, to string) bool {
+	return c[from][to]
+}
+
+// successors maps each source to the targets of its edgeType edges and
+// reports the number of distinct nodes those edges touch
+func (g Graph) successors(edgeType string) (map[string][]string, int) {
+	out := make(map[string][]string)
+	nodes := make(map[string]bool)
+	for _, edge := range g.Edges {
+		if edge.Type == edgeType {
+			out[edge.Source] = append(out[edge.Source], edge.Target)
+			nodes[edge.Source] = true
+			nodes[edge.Target] = true
+		}
+	}
+	return out, len(nodes)
+}
+
+// reachFrom returns every node reachable from src in one or more steps.
+// src itself is included only if it lies on a cycle.
+func reachFrom(out map[string][]string, src string) map[string]bool {
+	reach := make(map[string]bool)
+	stack := slices.Clone(out[src])
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reach[id] {
+			continue
+		}
+		reach[id] = true
+		stack = append(stack, out[id]...)
+	}
+	return reach
+}
+
+// TransitiveClosure returns, for every node, the set of nodes reachable by
+// following edgeType edges from source to target. It fails with
+// ErrGraphTooLarge when those edges touch more than ClosureNodeLimit nodes.
+func (g Graph) TransitiveClosure(edgeType string) (Closure, error) {
+	return g.TransitiveClosureContext(context.Background(), edgeType, ClosureNodeLimit)
+}
+
+// TransitiveClosureContext is TransitiveClosure with an explicit node cap
+// (0 means none) that also fails with ErrLimitExceeded when ctx is done
+func (g Graph) TransitiveClosureContext(ctx context.Context, edgeType string, maxNodes int) (Closure, error) {
+	out, n := g.successors(edgeType)
+	if maxNodes > 0 && n > maxNodes {
+		return nil, fmt.Errorf("%w: closure over %d %s nodes exceeds the limit of %d",
+			ErrGraphTooLarge, n, edgeType, maxNodes)
+	}
+
+	closure := make(Closure, len(out))
+	for src := range out {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %w after %d of %d nodes", ErrLimitExceeded, err, len(closure), len(out))
+		}
+		closure[src] = reachFrom(out, src)
+	}
+	return closure, nil
+}
+
+// Reaches reports whether from reaches to by following edgeType edges,
+// searching the graph on each call. For many queries on one graph, compute
+// TransitiveClosure once and ask its Reaches, which is O(1).
+func (g Graph) Reaches(from, to, edgeType string) bool {
+	out, _ := g.successors(edgeType)
+	return reachFrom(out, from)[to]
+}
+
+// DependencyClosure returns, sorted, every node id reaches by following
+// edgeType edges from source to target: a pack's transitive dependencies
+// for depends_on. id itself is included only if it depends on itself
+// through a cycle.
+func (g Graph) DependencyClosure(id, edgeType string) []string {
+	out, _ := g.successors(edgeType)
+	return slices.Sorted(maps.Keys(reachFrom(out, id)))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTransitiveClosure(t *testing.T) {
+	g := cycleGraph()
+
+	related, err := g.TransitiveClosure("related")
+	if err != nil {
+		t.Fatalf("TransitiveClosure: %v", err)
+	}
+	all := map[string]bool{"A": true, "B": true, "C": true}
+	for _, id := range []string{"A", "B", "C"} {
+		if !reflect.DeepEqual(related[id], all) {
+			t.Errorf("related closure of %s = %v, want %v", id, related[id], all)
+		}
+	}
+
+	child, err := g.TransitiveClosure("child")
+	if err != nil {
+		t.Fatalf("TransitiveClosure: %v", err)
+	}
+	want := Closure{"C": {"D": true}}
+	if !reflect.DeepEqual(child, want) {
+		t.Errorf("child closure = %v, want %v", child, want)
+	}
+	if child.Reaches("D", "C") {
+		t.Error("closure follows child edges backwards")
+	}
+}
+
+func TestReaches(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "parent"},
+		{Source: "B", Target: "C", Type: "parent"},
+		{Source: "C", Target: "D", Type: "related"},
+	}}
+
+	check := func() {
+		t.Helper()
+		if !g.Reaches("A", "C", "parent") {
+			t.Error("A should reach C through parent edges")
+		}
+		if g.Reaches("C", "A", "parent") || g.Reaches("A", "D", "parent") || g.Reaches("A", "A", "parent") {
+			t.Error("Reaches followed a missing, reversed or other-typed edge")
+		}
+	}
+	check()
+
+	// Edits to the edges are seen by the next query
+	g.Edges[1].Target = "D"
+	if g.Reaches("A", "C", "parent") || !g.Reaches("A", "D", "parent") {
+		t.Error("Reaches answered from the edges before the edit")
+	}
+
+	old := ClosureNodeLimit
+	ClosureNodeLimit = 2
+	defer func() { ClosureNodeLimit = old }()
+	if _, err := g.TransitiveClosure("parent"); !errors.Is(err, ErrGraphTooLarge) {
+		t.Errorf("over the limit: err = %v, want ErrGraphTooLarge", err)
+	}
+	if !g.Reaches("A", "D", "parent") {
+		t.Error("Reaches is limited by ClosureNodeLimit")
+	}
+}
+
+func TestTransitiveClosureContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cycleGraph().TransitiveClosureContext(ctx, "related", 0); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("canceled: err = %v, want ErrLimitExceeded", err)
+	}
+	if _, err := cycleGraph().TransitiveClosureContext(context.Background(), "related", 2); !errors.Is(err, ErrGraphTooLarge) {
+		t.Errorf("node cap: err = %v, want ErrGraphTooLarge", err)
+	}
+}
+
+func TestDependencyClosure(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "app", Target: "lib", Type: "depends_on"},
+		{Source: "lib", Target: "core", Type: "depends_on"},
+		{Source: "app", Target: "util", Type: "depends_on"},
+		{Source: "util", Target: "core", Type: "depends_on"},
+		{Source: "core", Target: "docs", Type: "mentions"},
+		{Source: "x", Target: "y", Type: "depends_on"},
+		{Source: "y", Target: "x", Type: "depends_on"},
+	}}
+
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{"app", []string{"core", "lib", "util"}},
+		{"core", nil},
+		{"x", []string{"x", "y"}}, // x is on a cycle
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		if got := g.DependencyClosure(tt.id, "depends_on"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DependencyClosure(%s) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
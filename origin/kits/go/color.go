@@ -0,0 +1,53 @@
+// ORIGIN Go Kit - terminal colors
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import "os"
+
+// ANSI escape sequences used by the text output
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiCyan   = "\x1b[36m"
+	ansiYellow = "\x1b[33m"
+)
+
+// colorOutput enables ANSI colors in text output. It is off by default so
+// library callers and tests get plain text; main turns it on for terminals.
+var colorOutput bool
+
+// useColor reports whether output to f should be colored: not when
+// disabled by flag, not when NO_COLOR is set (https://no-color.org), and
+// only when f is a terminal
+func useColor(noColor bool, f *os.File) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the given escape sequence when colors are enabled
+func paint(code, s string) string {
+	if !colorOutput {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// colorID styles a pack ID
+func colorID(s string) string { return paint(ansiCyan, s) }
+
+// colorTitle styles a pack title
+func colorTitle(s string) string { return paint(ansiBold, s) }
+
+// colorType styles an edge type. Pad s before coloring, since escape
+// sequences would count towards a %-Ns width.
+func colorType(s string) string { return paint(ansiYellow, s) }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPaint(t *testing.T) {
+	if got := colorID("A"); got != "A" {
+		t.Errorf("colors off: colorID = %q, want plain", got)
+	}
+
+	colorOutput = true
+	defer func() { colorOutput = false }()
+	if got, want := colorType("child"), ansiYellow+"child"+ansiReset; got != want {
+		t.Errorf("colors on: colorType = %q, want %q", got, want)
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	t.Setenv("NO_COLOR", "")
+	if useColor(false, f) {
+		t.Error("a regular file should not get colors")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if useColor(false, os.Stdout) {
+		t.Error("NO_COLOR should disable colors")
+	}
+	if useColor(true, os.Stdout) {
+		t.Error("-no-color should disable colors")
+	}
+}
@@ -0,0 +1,3230 @@
+// ORIGIN Go Kit - subcommands
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type pathHop struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+type pathResult struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Path []pathHop `json:"path"`
+	Cost *float64  `json:"cost,omitempty"`
+
+	labels idLabels
+}
+
+func (r pathResult) writeText(w io.Writer) {
+	if r.Cost != nil {
+		fmt.Fprintf(w, "Path from %s to %s (%d hops, cost %g):\n", r.From, r.To, len(r.Path)-1, *r.Cost)
+	} else {
+		fmt.Fprintf(w, "Path from %s to %s (%d hops):\n", r.From, r.To, len(r.Path)-1)
+	}
+	fmt.Fprintf(w, "  %s\n", r.labels.label(r.Path[0].ID))
+	for _, hop := range r.Path[1:] {
+		fmt.Fprintf(w, "  → %s: %s\n", colorType(hop.Type), r.labels.label(hop.ID))
+	}
+}
+
+func (r pathResult) ids() []string {
+	return listIDs(r.Path, func(x pathHop) string { return x.ID })
+}
+
+// cmdPath prints a shortest path between two packs
+func (a *app) cmdPath(loader *Loader, args []string) error {
+	fs := a.flagSet("path")
+	weighted := fs.Bool("weighted", false, "minimize total edge weight instead of hops")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: path [-weighted] <from> <to>")
+	}
+	args = fs.Args()
+	from, to := args[0], args[1]
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	for _, id := range args {
+		if _, err := requirePack(byID, id); err != nil {
+			return err
+		}
+	}
+
+	var path []string
+	var cost *float64
+	if *weighted {
+		var c float64
+		path, c, err = graph.WeightedShortestPath(from, to)
+		cost = &c
+	} else {
+		path, err = graph.ShortestPath(from, to)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := newPathResult(graph.BuildAdjacency(), path)
+	result.Cost = cost
+	result.labels = newIDLabels(index)
+	return a.output().Result(result)
+}
+
+// loadIDLabels returns the -with-titles labels for a command that otherwise
+// needs only the graph, reading the index only when titles are shown
+func loadIDLabels(loader *Loader) (idLabels, error) {
+	if !withTitles {
+		return nil, nil
+	}
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("loading index: %w", err)
+	}
+	return newIDLabels(index), nil
+}
+
+// newPathResult labels each hop of path with the type of the edge in adj
+// it crosses
+func newPathResult(adj map[string][]GraphEdge, path []string) pathResult {
+	result := pathResult{From: path[0], To: path[len(path)-1], Path: []pathHop{{ID: path[0]}}}
+	for i := 1; i < len(path); i++ {
+		edge, _ := edgeBetween(adj, path[i-1], path[i])
+		result.Path = append(result.Path, pathHop{ID: path[i], Type: edge.Type})
+	}
+	return result
+}
+
+type batchPathResult struct {
+	Results []PathResult `json:"results"`
+
+	labels idLabels
+}
+
+func (r batchPathResult) writeText(w io.Writer) {
+	found := 0
+	for _, res := range r.Results {
+		if !res.Found {
+			fmt.Fprintf(w, "  %s -> %s: no path\n", r.labels.label(res.From), r.labels.label(res.To))
+			continue
+		}
+		found++
+		hops := make([]string, len(res.Path))
+		for i, id := range res.Path {
+			hops[i] = r.labels.label(id)
+		}
+		fmt.Fprintf(w, "  %s -> %s: %d hops: %s\n", r.labels.label(res.From), r.labels.label(res.To), res.Hops, strings.Join(hops, ", "))
+	}
+	fmt.Fprintf(w, "%d of %d pairs connected.\n", found, len(r.Results))
+}
+
+func (r batchPathResult) records() iter.Seq[any] {
+	return listRecords(r.Results)
+}
+
+// cmdBatchPath prints a shortest path for each from,target row of a CSV
+// file read from stdin or -file
+func (a *app) cmdBatchPath(loader *Loader, args []string) error {
+	fs := a.flagSet("batch-path")
+	file := fs.String("file", "", "read from,target rows from this CSV file instead of stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: batch-path [-file=pairs.csv] < pairs.csv")
+	}
+
+	in := a.In
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("reading pairs: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	pairs, err := readPairs(in)
+	if err != nil {
+		return fmt.Errorf("reading pairs: %w", err)
+	}
+
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+	labels, err := loadIDLabels(loader)
+	if err != nil {
+		return err
+	}
+	results := graph.BatchShortestPathWithProgress(pairs, newProgress(a.Err, "paths"))
+	return a.output().Result(batchPathResult{Results: results, labels: labels})
+}
+
+// readPairs reads two-column CSV rows, skipping a from,to or from,target
+// header
+func readPairs(r io.Reader) ([][2]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	pairs := [][2]string{}
+	for first := true; ; first = false {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return pairs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		pair := [2]string{strings.TrimSpace(row[0]), strings.TrimSpace(row[1])}
+		if first && strings.EqualFold(pair[0], "from") &&
+			(strings.EqualFold(pair[1], "to") || strings.EqualFold(pair[1], "target")) {
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+}
+
+type whyResult struct {
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Path        []pathHop `json:"path"`
+	Explanation string    `json:"explanation"`
+}
+
+func (r whyResult) writeText(w io.Writer) {
+	if r.From == r.To {
+		fmt.Fprintf(w, "%s and %s are the same pack:\n", r.From, r.To)
+	} else {
+		fmt.Fprintf(w, "%s is connected to %s in %d hops:\n", r.From, r.To, len(r.Path)-1)
+	}
+	fmt.Fprintf(w, "  %s\n", r.Explanation)
+}
+
+func (r whyResult) ids() []string {
+	return pathResult{Path: r.Path}.ids()
+}
+
+// cmdWhyConnected explains a shortest path between two packs as prose
+func (a *app) cmdWhyConnected(loader *Loader, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: why-connected <from> <to>")
+	}
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	for _, id := range args {
+		if _, err := requirePack(byID, id); err != nil {
+			return err
+		}
+	}
+	path, err := graph.ShortestPath(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	adj := graph.BuildAdjacency()
+	result := whyResult{
+		From:        args[0],
+		To:          args[1],
+		Path:        newPathResult(adj, path).Path,
+		Explanation: explainPath(adj, index, path),
+	}
+	return a.output().Result(result)
+}
+
+type diffResult struct {
+	Packs IndexDiff `json:"packs"`
+	Edges GraphDiff `json:"edges"`
+}
+
+func (r diffResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Packs: %d added, %d removed, %d changed\n",
+		len(r.Packs.Added), len(r.Packs.Removed), len(r.Packs.Changed))
+	for _, p := range r.Packs.Added {
+		fmt.Fprintf(w, "  + %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+	for _, p := range r.Packs.Removed {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+	for _, c := range r.Packs.Changed {
+		var changes []string
+		if c.Old.Title != c.New.Title {
+			changes = append(changes, fmt.Sprintf("title %q → %q", c.Old.Title, c.New.Title))
+		}
+		if c.Old.DisclosureTier != c.New.DisclosureTier {
+			changes = append(changes, fmt.Sprintf("tier %s → %s", c.Old.DisclosureTier, c.New.DisclosureTier))
+		}
+		fmt.Fprintf(w, "  ~ %s: %s\n", c.ID, strings.Join(changes, "; "))
+	}
+
+	fmt.Fprintf(w, "Edges: %d added, %d removed\n", len(r.Edges.Added), len(r.Edges.Removed))
+	for _, e := range r.Edges.Added {
+		fmt.Fprintf(w, "  + %s -> %s (%s)\n", colorID(e.Source), colorID(e.Target), colorType(e.Type))
+	}
+	for _, e := range r.Edges.Removed {
+		fmt.Fprintf(w, "  - %s -> %s (%s)\n", colorID(e.Source), colorID(e.Target), colorType(e.Type))
+	}
+}
+
+// cmdDiff compares the packs and edges of two dist directories
+func (a *app) cmdDiff(_ *Loader, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: diff <oldDir> <newDir>")
+	}
+
+	oldIndex, oldGraph, err := LoadAll(NewLoader(args[0]))
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	newIndex, newGraph, err := LoadAll(NewLoader(args[1]))
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[1], err)
+	}
+
+	return a.output().Result(diffResult{
+		Packs: DiffIndexes(oldIndex, newIndex),
+		Edges: DiffGraphs(oldGraph, newGraph),
+	})
+}
+
+type pathsResult struct {
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Paths [][]pathHop `json:"paths"`
+
+	labels idLabels
+}
+
+func (r pathsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Paths from %s to %s (%d):\n", r.From, r.To, len(r.Paths))
+	for _, path := range r.Paths {
+		var b strings.Builder
+		b.WriteString(r.labels.label(path[0].ID))
+		for _, hop := range path[1:] {
+			fmt.Fprintf(&b, " -%s-> %s", colorType(hop.Type), r.labels.label(hop.ID))
+		}
+		fmt.Fprintf(w, "  %s\n", b.String())
+	}
+}
+
+// cmdPaths prints every simple path between two packs up to -depth hops
+func (a *app) cmdPaths(loader *Loader, args []string) error {
+	fs := a.flagSet("paths")
+	depth := fs.Int("depth", 4, fmt.Sprintf("maximum hops per path (at most %d)", MaxPathDepth))
+	maxPaths := fs.Int("max-paths", 1000, "fail if there are more paths than this (0 for no limit)")
+	timeout := fs.Duration("timeout", 30*time.Second, "fail if the search takes longer than this (0 for no limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: paths [-depth=n] [-max-paths=n] [-timeout=d] <from> <to>")
+	}
+	if *depth > MaxPathDepth {
+		return fmt.Errorf("-depth %d exceeds the limit of %d", *depth, MaxPathDepth)
+	}
+	from, to := fs.Arg(0), fs.Arg(1)
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	for _, id := range fs.Args() {
+		if _, err := requirePack(byID, id); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	paths, err := graph.AllPathsContext(ctx, from, to, *depth, *maxPaths)
+	if err != nil {
+		return err
+	}
+
+	result := pathsResult{From: from, To: to, Paths: [][]pathHop{}, labels: newIDLabels(index)}
+	adj := graph.BuildAdjacency()
+	for _, path := range paths {
+		result.Paths = append(result.Paths, newPathResult(adj, path).Path)
+	}
+	return a.output().Result(result)
+}
+
+type validateResult struct {
+	Problems []string `json:"problems"`
+	Warnings []string `json:"warnings"`
+	// Cached is set when the result was read from -cache-file
+	Cached bool `json:"cached,omitempty"`
+}
+
+func (r validateResult) writeText(w io.Writer) {
+	if r.Cached && len(r.Problems) == 0 {
+		fmt.Fprintln(w, "unchanged, OK")
+		return
+	}
+	if r.Cached {
+		fmt.Fprintln(w, "unchanged since the last validation:")
+	}
+	for _, p := range r.Problems {
+		fmt.Fprintf(w, "  - %s\n", p)
+	}
+	for _, warning := range r.Warnings {
+		fmt.Fprintf(w, "  - warning: %s\n", warning)
+	}
+	if len(r.Problems) == 0 {
+		fmt.Fprintln(w, "OK: no problems found.")
+	}
+}
+
+// titleWarnings describes each title shared by several packs, sorted
+func titleWarnings(packs []Pack) []string {
+	warnings := []string{}
+	for title, ids := range FindDuplicateTitles(packs) {
+		warnings = append(warnings, fmt.Sprintf("title %q is shared by %s", title, strings.Join(ids, ", ")))
+	}
+	slices.Sort(warnings)
+	return warnings
+}
+
+// tierAsymmetryWarnings describes each edge joining packs more than one
+// tier apart, in graph order
+func tierAsymmetryWarnings(index PacksIndex, graph Graph) []string {
+	byID := index.ByID()
+	var warnings []string
+	for _, edge := range TierAsymmetries(index, graph) {
+		warnings = append(warnings, fmt.Sprintf("edge %s -> %s (%s) joins %s and %s packs",
+			edge.Source, edge.Target, edge.Type, byID[edge.Source].DisclosureTier, byID[edge.Target].DisclosureTier))
+	}
+	return warnings
+}
+
+// validateCache is the -cache-file format: the key of the last validate
+// run and its result
+type validateCache struct {
+	Key    string         `json:"key"`
+	Result validateResult `json:"result"`
+}
+
+// validateCacheKey identifies a validate run by the dataset, including the
+// metadata counts DatasetHash leaves out, and the options that change the
+// result
+func validateCacheKey(index PacksIndex, graph Graph, options ...string) string {
+	h := sha256.New()
+	fmt.Fprintln(h, DatasetHash(index, graph), index.Metadata.PackCount, graph.Metadata.NodeCount, graph.Metadata.EdgeCount)
+	for _, option := range options {
+		fmt.Fprintf(h, "%q\n", option)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readValidateCache returns the result cached at path under key. A
+// missing, unreadable or stale cache is a miss.
+func readValidateCache(path, key string) (validateResult, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return validateResult{}, false
+	}
+	var cache validateCache
+	if json.Unmarshal(data, &cache) != nil || cache.Key != key {
+		return validateResult{}, false
+	}
+	cache.Result.Cached = true
+	return cache.Result, true
+}
+
+// writeValidateCache stores result at path under key
+func writeValidateCache(path, key string, result validateResult) error {
+	data, err := json.MarshalIndent(validateCache{Key: key, Result: result}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// fixDangling reports the dangling edges of graph on a.Err and, when
+// confirmed, rewrites the graph file without them. It returns the graph
+// left to validate: the cleaned one once written, else graph unchanged.
+func (a *app) fixDangling(loader *Loader, index PacksIndex, graph Graph, confirmed bool) (Graph, error) {
+	path, err := localGraphPath(loader, "validate -fix")
+	if err != nil {
+		return graph, err
+	}
+	cleaned, removed := graph.DropDangling(index)
+	if len(removed) == 0 {
+		fmt.Fprintln(a.Err, "No dangling edges to remove.")
+		return graph, nil
+	}
+	for _, edge := range removed {
+		fmt.Fprintf(a.Err, "Removing dangling edge %s -> %s (%s)\n", edge.Source, edge.Target, edge.Type)
+	}
+	if !confirmed {
+		fmt.Fprintf(a.Err, "Dry run; pass -yes to remove %d edges from %s.\n", len(removed), path)
+		return graph, nil
+	}
+	source, err := sourceGraph(path, graph)
+	if err != nil {
+		return graph, err
+	}
+	// Aliases rename endpoints in place, so the loaded edges line up with
+	// the file's; drop the file's edges at the dangling positions
+	known := index.ByID()
+	kept := []GraphEdge{}
+	for i, edge := range graph.Edges {
+		_, src := known[edge.Source]
+		_, dst := known[edge.Target]
+		if src && dst {
+			kept = append(kept, source.Edges[i])
+		}
+	}
+	source.Edges = kept
+	if err := rewriteGraphFile(path, source.RecomputeMetadata()); err != nil {
+		return graph, err
+	}
+	fmt.Fprintf(a.Err, "Removed %d edges; wrote %s.\n", len(removed), path)
+	return cleaned, nil
+}
+
+// cliValidateChecks are the check names validate -checks accepts: those
+// of Validate followed by the ones the command adds
+func cliValidateChecks() []string {
+	return append(ValidateChecks(), "tiers", "edge-types", "symmetry", "reciprocal")
+}
+
+// cmdValidate reports dataset problems and fails if any are found
+func (a *app) cmdValidate(loader *Loader, args []string) error {
+	fs := a.flagSet("validate")
+	tiers := fs.String("tiers", strings.Join(Tiers(), ","), "comma-separated allowed disclosure tiers")
+	edgeVocab := fs.String("edge-vocab", "", "file of allowed edge types, one per line")
+	symmetric := fs.String("symmetric", "", "comma-separated edge types that must have a reverse edge")
+	requireReciprocal := fs.Bool("require-reciprocal", false, "report one-way related declarations as problems rather than warnings")
+	fix := fs.Bool("fix", false, "remove edges whose source or target is not in the index (a dry run without -yes)")
+	yes := fs.Bool("yes", false, "with -fix, rewrite graph.json")
+	failFast := fs.Bool("fail-fast", false, "stop at the first problem found")
+	checks := fs.String("checks", "", "comma-separated checks to run (default all): "+strings.Join(cliValidateChecks(), ", "))
+	cacheFile := fs.String("cache-file", "", "skip validation when the dataset and options match the run recorded in this file")
+	force := fs.Bool("force", false, "with -cache-file, validate even if the dataset is unchanged")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *cacheFile != "" && *fix {
+		return fmt.Errorf("-cache-file cannot be used with -fix")
+	}
+	opts := ValidateOptions{FailFast: *failFast, Checks: splitList(*checks), Progress: newProgress(a.Err, "validating")}
+	for _, name := range opts.Checks {
+		if !slices.Contains(cliValidateChecks(), name) {
+			return fmt.Errorf("unknown check %q (want one of %s)", name, strings.Join(cliValidateChecks(), ", "))
+		}
+	}
+	var vocab []string
+	if *edgeVocab != "" {
+		var err error
+		if vocab, err = LoadVocabulary(*edgeVocab); err != nil {
+			return fmt.Errorf("loading edge vocabulary: %w", err)
+		}
+	}
+
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+	if *fix {
+		if graph, err = a.fixDangling(loader, index, graph, *yes); err != nil {
+			return err
+		}
+	}
+
+	var cacheKey string
+	if *cacheFile != "" {
+		cacheKey = validateCacheKey(index, graph, *tiers, strings.Join(vocab, ","), *symmetric, *checks,
+			strconv.FormatBool(*failFast), strconv.FormatBool(*requireReciprocal), strings.Join(Tiers(), ","))
+		if cached, ok := readValidateCache(*cacheFile, cacheKey); ok && !*force {
+			loader.log().Info("dataset unchanged; skipped validation", "cache", *cacheFile)
+			return a.finishValidate(cached)
+		}
+	}
+
+	result := validateResult{Problems: []string{}, Warnings: titleWarnings(index.Packs)}
+	result.Warnings = append(result.Warnings, tierAsymmetryWarnings(index, graph)...)
+	if !*requireReciprocal {
+		for _, e := range RelatedReciprocity(index) {
+			result.Warnings = append(result.Warnings, e.Error())
+		}
+	}
+	opts.Extra = []ValidateCheck{
+		{"tiers", func() []error { return ValidateTiers(index.Packs, splitList(*tiers)) }},
+		{"edge-types", func() []error {
+			if *edgeVocab == "" {
+				return nil
+			}
+			return ValidateEdgeTypes(graph, vocab)
+		}},
+		{"symmetry", func() []error { return CheckSymmetry(graph, splitList(*symmetric)) }},
+		{"reciprocal", func() []error {
+			if !*requireReciprocal {
+				return nil
+			}
+			return RelatedReciprocity(index)
+		}},
+	}
+	errs := ValidateWith(index, graph, opts)
+	loader.log().Info("validated dataset", "problems", len(errs))
+	for _, e := range errs {
+		result.Problems = append(result.Problems, e.Error())
+	}
+	if *cacheFile != "" {
+		if err := writeValidateCache(*cacheFile, cacheKey, result); err != nil {
+			return fmt.Errorf("writing validate cache: %w", err)
+		}
+	}
+	return a.finishValidate(result)
+}
+
+// finishValidate prints result and fails if it has problems
+func (a *app) finishValidate(result validateResult) error {
+	if err := a.output().Result(result); err != nil {
+		return err
+	}
+	if len(result.Problems) > 0 {
+		return fmt.Errorf("validation found %d problems", len(result.Problems))
+	}
+	return nil
+}
+
+// cmdExport writes the dataset in another format to stdout. Export formats
+// are already machine-readable, so -json does not apply.
+func (a *app) cmdExport(loader *Loader, args []string) error {
+	const usage = "usage: export <dot|csv|edges-csv|adjacency|graphml|d3|deduped> [-tier=a,b] [-attrs=k1,k2] | export subgraph -from=<id> [-depth=n]"
+	if len(args) == 0 {
+		return fmt.Errorf(usage)
+	}
+	if args[0] == "subgraph" {
+		return a.exportSubgraph(loader, args[1:])
+	}
+
+	fs := a.flagSet("export " + args[0])
+	tier := fs.String("tier", "", "comma-separated tiers to keep; edges need both endpoints in them")
+	attrs := fs.String("attrs", "", "comma-separated edge attributes to include in dot labels and graphml data")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf(usage)
+	}
+	tiers := splitList(*tier)
+
+	switch args[0] {
+	case "adjacency":
+		graph, err := exportGraph(loader, tiers)
+		if err != nil {
+			return err
+		}
+		return graph.ToAdjacencyJSON(a.Out)
+	case "csv":
+		index, err := loader.LoadIndex()
+		if err != nil {
+			return fmt.Errorf("loading index: %w", err)
+		}
+		packs := index.Packs
+		if len(tiers) > 0 {
+			packs = FilterByTier(packs, tiers)
+		}
+		return csvFormatter{a.Out}.Packs(packs)
+	case "d3":
+		index, graph, err := LoadAll(loader)
+		if err != nil {
+			return err
+		}
+		if len(tiers) > 0 {
+			graph = tiersSubgraph(index, graph, tiers)
+			index.Packs = FilterByTier(index.Packs, tiers)
+		}
+		return graph.ToD3JSON(a.Out, index)
+	case "deduped":
+		graph, err := exportGraph(loader, tiers)
+		if err != nil {
+			return err
+		}
+		deduped := graph.Dedupe()
+		fmt.Fprintf(a.Err, "Dropped %d duplicate edges.\n", len(graph.Edges)-len(deduped.Edges))
+		return deduped.WriteJSON(a.Out)
+	case "edges-csv":
+		graph, err := exportGraph(loader, tiers)
+		if err != nil {
+			return err
+		}
+		return graph.WriteEdgesCSV(a.Out)
+	case "dot":
+		graph, err := exportGraph(loader, tiers)
+		if err != nil {
+			return err
+		}
+		return graph.ToDOTWithAttributes(a.Out, splitList(*attrs))
+	case "graphml":
+		index, graph, err := LoadAll(loader)
+		if err != nil {
+			return err
+		}
+		if len(tiers) > 0 {
+			graph = tiersSubgraph(index, graph, tiers)
+			index.Packs = FilterByTier(index.Packs, tiers)
+		}
+		return graph.ToGraphMLWithAttributes(a.Out, index, splitList(*attrs))
+	default:
+		return fmt.Errorf("unknown export format %q", args[0])
+	}
+}
+
+// exportGraph loads the graph, restricted to packs in tiers when any are
+// given
+func exportGraph(loader *Loader, tiers []string) (Graph, error) {
+	if len(tiers) == 0 {
+		graph, err := loader.LoadGraph()
+		if err != nil {
+			return Graph{}, fmt.Errorf("loading graph: %w", err)
+		}
+		return graph, nil
+	}
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return Graph{}, err
+	}
+	return tiersSubgraph(index, graph, tiers), nil
+}
+
+// exportSubgraph writes the neighborhood of a pack as a standalone
+// graph.json
+func (a *app) exportSubgraph(loader *Loader, args []string) error {
+	fs := a.flagSet("export subgraph")
+	from := fs.String("from", "", "pack ID at the center of the subgraph")
+	depth := fs.Int("depth", 2, "maximum hops from -from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || fs.NArg() != 0 {
+		return fmt.Errorf("usage: export subgraph -from=<id> [-depth=n]")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	if _, err := requirePack(index.ByID(), *from); err != nil {
+		return err
+	}
+
+	return graph.Subgraph(graph.BFS(*from, *depth, Both)).WriteJSON(a.Out)
+}
+
+// cmdExtract writes a pack and its neighborhood as a standalone dist
+// directory. Only packs in the -tier tiers are included or traversed, so
+// the default keeps restricted packs out.
+func (a *app) cmdExtract(loader *Loader, args []string) error {
+	fs := a.flagSet("extract")
+	depth := fs.Int("depth", 2, "maximum hops from the pack")
+	out := fs.String("out", "", "directory to write the index and graph to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *out == "" {
+		return fmt.Errorf("usage: extract [-depth=n] -out=<dir> <id>")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	center, err := requirePack(index.ByID(), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	index.Packs = FilterByTier(index.Packs, splitList(*tierFlag))
+	if len(FilterByTier([]Pack{center}, splitList(*tierFlag))) == 0 {
+		return fmt.Errorf("pack %s is in tier %q, outside -tier %s", center.ID, center.DisclosureTier, *tierFlag)
+	}
+	graph = graph.Subgraph(packIDs(index.Packs))
+
+	subIndex, subGraph := ExtractNeighborhood(index, graph, fs.Arg(0), *depth)
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	if err := writeJSONFile(filepath.Join(*out, IndexFile), subIndex.WriteJSON); err != nil {
+		return err
+	}
+	if err := writeJSONFile(filepath.Join(*out, GraphFile), subGraph.WriteJSON); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "Wrote %d packs and %d edges to %s.\n", len(subIndex.Packs), len(subGraph.Edges), *out)
+	return nil
+}
+
+// writeJSONFile fills path with write. It writes a temporary file in the
+// same directory and renames it over path, so a failed write leaves any
+// existing file as it was; an existing file keeps its permissions.
+func writeJSONFile(path string, write func(io.Writer) error) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// cmdBackbone writes the minimum spanning forest of the graph as a
+// graph.json, or as DOT with -dot
+func (a *app) cmdBackbone(loader *Loader, args []string) error {
+	fs := a.flagSet("backbone")
+	dot := fs.Bool("dot", false, "write Graphviz DOT instead of graph.json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: backbone [-dot]")
+	}
+
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+	tree := graph.MinimumSpanningTree()
+	fmt.Fprintf(a.Err, "Kept %d of %d edges.\n", len(tree.Edges), len(graph.Edges))
+	if *dot {
+		return tree.ToDOT(a.Out)
+	}
+	return tree.WriteJSON(a.Out)
+}
+
+// cmdBundle writes the index and graph as one bundle file, which the
+// loader reads in place of a dist directory
+func (a *app) cmdBundle(loader *Loader, args []string) error {
+	fs := a.flagSet("bundle")
+	out := fs.String("out", "", "file to write the bundle to (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: bundle [-out=file]")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	write := func(w io.Writer) error { return WriteBundle(w, index, graph) }
+	if *out == "" {
+		return write(a.Out)
+	}
+	if err := writeJSONFile(*out, write); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Err, "Wrote %d packs and %d edges to %s.\n", len(index.Packs), len(graph.Edges), *out)
+	return nil
+}
+
+// printEdgePlan lists the edges a command would add on a.Err, followed by
+// the added and skipped counts
+func (a *app) printEdgePlan(added, skipped []GraphEdge) {
+	for _, edge := range added {
+		fmt.Fprintf(a.Err, "+ %s -> %s (%s)\n", edge.Source, edge.Target, edge.Type)
+	}
+	fmt.Fprintf(a.Err, "%d edges to add, %d already present.\n", len(added), len(skipped))
+}
+
+// cmdBackfill builds a graph.json from the packs' related lists, for
+// datasets whose graph is empty or missing. The graph goes to stdout, or
+// to -out only with -apply.
+func (a *app) cmdBackfill(loader *Loader, args []string) error {
+	fs := a.flagSet("backfill")
+	edgeType := fs.String("type", "related", "type of the generated edges")
+	out := fs.String("out", "", "file to write the graph to (default stdout)")
+	apply := fs.Bool("apply", false, "write -out; without it only the plan is printed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: backfill [-type=t] [-out=file [-apply]]")
+	}
+
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+
+	added, skipped := PlanEdgeChanges(Graph{}, EdgesFromRelated(index.Packs, *edgeType))
+	a.printEdgePlan(added, skipped)
+	graph := Graph{Edges: added}
+	graph.recount()
+	if *out == "" {
+		return graph.WriteJSON(a.Out)
+	}
+	if !*apply {
+		fmt.Fprintf(a.Err, "Dry run; pass -apply to write %s.\n", *out)
+		return nil
+	}
+	return writeJSONFile(*out, graph.WriteJSON)
+}
+
+// cmdMergeRelated adds an edge to graph.json for each Related entry it
+// does not already have, after printing the plan. Without -apply the file
+// is left unchanged.
+func (a *app) cmdMergeRelated(loader *Loader, args []string) error {
+	fs := a.flagSet("merge-related")
+	edgeType := fs.String("type", "related", "type of the added edges")
+	apply := fs.Bool("apply", false, "rewrite graph.json; without it only the plan is printed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: merge-related [-type=t] [-apply]")
+	}
+	path, err := localGraphPath(loader, "merge-related")
+	if err != nil {
+		return err
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	added, skipped := PlanEdgeChanges(graph, EdgesFromRelated(index.Packs, *edgeType))
+	a.printEdgePlan(added, skipped)
+	if len(added) == 0 {
+		return nil
+	}
+	if !*apply {
+		fmt.Fprintf(a.Err, "Dry run; pass -apply to add %d edges to %s.\n", len(added), path)
+		return nil
+	}
+	source, err := sourceGraph(path, graph)
+	if err != nil {
+		return err
+	}
+	merged := Graph{Edges: slices.Concat(source.Edges, added)}
+	merged.recount()
+	if err := rewriteGraphFile(path, merged); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Err, "Added %d edges; wrote %s.\n", len(added), path)
+	return nil
+}
+
+// localGraphPath returns the uncompressed local graph file that cmd
+// rewrites in place. Remote, bundled, in-memory and gzipped graphs, and
+// loaders that change the graph as they read it, are refused.
+func localGraphPath(loader *Loader, cmd string) (string, error) {
+	if isURL(loader.BasePath) {
+		return "", fmt.Errorf("%s needs a local dist directory, not %s", cmd, loader.BasePath)
+	}
+	if loader.IncludeRelated {
+		return "", fmt.Errorf("%s rewrites the graph file and cannot be used with -include-related", cmd)
+	}
+	if loader.Prefix != "" {
+		return "", fmt.Errorf("%s rewrites the graph file and cannot be used with -prefix", cmd)
+	}
+	if len(loader.SymmetricTypes) > 0 {
+		return "", fmt.Errorf("%s rewrites the graph file and cannot be used with -normalize-symmetric", cmd)
+	}
+	if (loader.FS != nil && loader.BasePath == "") || loader.bundle != nil {
+		return "", fmt.Errorf("%s needs a local dist directory, not a bundle or in-memory dist", cmd)
+	}
+	path := filepath.Join(loader.BasePath, loader.graphName())
+	if strings.HasSuffix(path, ".gz") {
+		return "", fmt.Errorf("%s cannot rewrite the gzipped %s", cmd, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		if _, gzErr := os.Stat(path + ".gz"); gzErr == nil {
+			return "", fmt.Errorf("%s cannot rewrite the gzipped %s.gz", cmd, path)
+		}
+		return "", fmt.Errorf("%s rewrites %s: %w", cmd, path, err)
+	}
+	return path, nil
+}
+
+// sourceGraph reads the graph file at path as written, without the
+// aliases the loader applied to loaded, so that rewriting it in place does
+// not save the renames into the file. The two have the same edges in the
+// same order, up to renamed endpoints.
+func sourceGraph(path string, loaded Graph) (Graph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Graph{}, err
+	}
+	var graph Graph
+	if err := decodeJSON(path, data, &graph); err != nil {
+		return Graph{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(graph.Edges) != len(loaded.Edges) {
+		return Graph{}, fmt.Errorf("%s changed while loading; try again", path)
+	}
+	return graph, nil
+}
+
+// cmdFixMetadata rewrites graph.json with node and edge counts recomputed
+// from its edges, after printing the old and new counts
+func (a *app) cmdFixMetadata(loader *Loader, args []string) error {
+	fs := a.flagSet("fix-metadata")
+	dryRun := fs.Bool("dry-run", false, "print the changes without writing the file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: fix-metadata [-dry-run]")
+	}
+	path, err := localGraphPath(loader, "fix-metadata")
+	if err != nil {
+		return err
+	}
+
+	loaded, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+	graph, err := sourceGraph(path, loaded)
+	if err != nil {
+		return err
+	}
+	fixed := graph.RecomputeMetadata()
+	if fixed.Metadata == graph.Metadata {
+		fmt.Fprintf(a.Out, "Metadata of %s is up to date (%d nodes, %d edges).\n",
+			path, graph.Metadata.NodeCount, graph.Metadata.EdgeCount)
+		return nil
+	}
+	fmt.Fprintf(a.Out, "node_count: %d -> %d\n", graph.Metadata.NodeCount, fixed.Metadata.NodeCount)
+	fmt.Fprintf(a.Out, "edge_count: %d -> %d\n", graph.Metadata.EdgeCount, fixed.Metadata.EdgeCount)
+	if *dryRun {
+		fmt.Fprintf(a.Out, "Dry run; %s not written.\n", path)
+		return nil
+	}
+	if err := rewriteGraphFile(path, fixed); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "Wrote %s.\n", path)
+	return nil
+}
+
+// cmdReport writes a generated document about the dataset to stdout
+func (a *app) cmdReport(loader *Loader, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: report <md>")
+	}
+
+	switch args[0] {
+	case "md":
+		index, graph, err := LoadAll(loader)
+		if err != nil {
+			return err
+		}
+		return WriteMarkdownReport(a.Out, index, graph)
+	default:
+		return fmt.Errorf("unknown report format %q", args[0])
+	}
+}
+
+type neighbor struct {
+	Direction string `json:"direction"`
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+}
+
+type neighborsResult struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Total     int        `json:"total"`
+	Neighbors []neighbor `json:"neighbors"`
+}
+
+func (r neighborsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Neighbors of %s (%s), %d edges:\n", r.ID, r.Title, r.Total)
+	for _, n := range r.Neighbors {
+		fmt.Fprintf(w, "  %-3s %s %s: %s\n", n.Direction, colorType(fmt.Sprintf("%-10s", n.Type)), colorID(n.ID), colorTitle(n.Title))
+	}
+	if more := r.Total - len(r.Neighbors); more > 0 {
+		fmt.Fprintf(w, "  ... and %d more\n", more)
+	}
+}
+
+func (r neighborsResult) ids() []string {
+	return listIDs(r.Neighbors, func(x neighbor) string { return x.ID })
+}
+
+func (r neighborsResult) records() iter.Seq[any] {
+	return listRecords(r.Neighbors)
+}
+
+// neighborsOf lists up to limit edges incident to p with the direction
+// seen from p and the title of the other endpoint
+func neighborsOf(index PacksIndex, adj map[string][]GraphEdge, p Pack, limit int) neighborsResult {
+	byID := index.ByID()
+	edges := adj[p.ID]
+	result := neighborsResult{ID: p.ID, Title: p.Title, Total: len(edges), Neighbors: []neighbor{}}
+	for i, edge := range edges {
+		if i >= limit {
+			break
+		}
+		dir := Outgoing
+		if edge.Source != p.ID {
+			dir = Incoming
+		}
+		other := otherEnd(edge, p.ID)
+		result.Neighbors = append(result.Neighbors, neighbor{
+			Direction: dir.String(), Type: edge.Type, ID: other, Title: byID[other].Title,
+		})
+	}
+	return result
+}
+
+// neighborLimit caps how many edges neighbors lists. Like withTitles it is
+// set by main: to -limit when that flag is given and not negative, and
+// otherwise left unlimited.
+var neighborLimit = math.MaxInt
+
+// cmdNeighbors lists every edge incident to a pack, truncated only by an
+// explicit -limit
+func (a *app) cmdNeighbors(loader *Loader, args []string) error {
+	fs := a.flagSet("neighbors")
+	minShared := fs.Int("min-shared", 0, "only list neighbors sharing at least this many neighbors with the pack")
+	edgeType := fs.String("type", "", "only list edges of this type")
+	var exclude listFlag
+	fs.Var(&exclude, "exclude-tier", "hide neighbors in this tier (repeatable, or comma-separated)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: neighbors [-min-shared=k] [-type=t] [-exclude-tier=t]... <id>")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	p, err := requirePack(index.ByID(), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	adj := graph.BuildAdjacency()
+	if *minShared > 0 {
+		strong := graph.StrongNeighbors(p.ID, *minShared)
+		adj[p.ID] = slices.DeleteFunc(adj[p.ID], func(edge GraphEdge) bool {
+			_, found := slices.BinarySearch(strong, otherEnd(edge, p.ID))
+			return !found
+		})
+	}
+	if *edgeType != "" {
+		adj[p.ID] = filterTypes(adj[p.ID], []string{*edgeType})
+	}
+	if len(exclude) > 0 {
+		var others []string
+		for _, edge := range adj[p.ID] {
+			others = append(others, otherEnd(edge, p.ID))
+		}
+		shown := make(map[string]struct{}, len(others))
+		for _, id := range FilterPacksExcludingTiers(index, others, exclude) {
+			shown[id] = struct{}{}
+		}
+		adj[p.ID] = slices.DeleteFunc(adj[p.ID], func(edge GraphEdge) bool {
+			_, ok := shown[otherEnd(edge, p.ID)]
+			return !ok
+		})
+	}
+	return a.output().Result(neighborsOf(index, adj, p, neighborLimit))
+}
+
+func (d PackDetail) writeText(w io.Writer) {
+	fmt.Fprintf(w, "%s: %s\n", colorID(d.ID), colorTitle(d.Title))
+	if d.Synthetic {
+		fmt.Fprintln(w, "  (placeholder synthesized from the graph; title and tier are not real)")
+	}
+	fmt.Fprintf(w, "  Tier:    %s\n", d.DisclosureTier)
+	if d.RelatedPacks == nil {
+		fmt.Fprintf(w, "  Related: %s\n", strings.Join(d.Related, ", "))
+	} else {
+		fmt.Fprintf(w, "  Related (%d):\n", len(d.RelatedPacks))
+		for _, p := range d.RelatedPacks {
+			fmt.Fprintf(w, "    %s: %s [%s]\n", colorID(p.ID), colorTitle(p.Title), p.DisclosureTier)
+		}
+		if d.Unresolved > 0 {
+			fmt.Fprintf(w, "    ... and %d not in the index\n", d.Unresolved)
+		}
+	}
+	fmt.Fprintf(w, "  Neighbors (%d):\n", len(d.Neighbors))
+	for _, n := range d.Neighbors {
+		title := n.Title
+		if title == "" {
+			title = "(not in index)"
+		}
+		fmt.Fprintf(w, "    %-3s %s %s: %s\n", n.Direction, colorType(fmt.Sprintf("%-10s", n.Type)), colorID(n.ID), colorTitle(title))
+	}
+}
+
+func (d PackDetail) ids() []string {
+	return listIDs(d.Neighbors, func(n neighbor) string { return n.ID })
+}
+
+// cmdShow prints a pack with every edge incident to it, and with -expand
+// its related packs
+func (a *app) cmdShow(loader *Loader, args []string) error {
+	fs := a.flagSet("show")
+	expand := fs.Bool("expand", false, "list related packs with their titles and tiers")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: show [-expand] <id>")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	detail, err := packDetail(index, graph, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *expand {
+		detail.RelatedPacks = ExpandRelated(index, detail.Pack)
+		detail.Unresolved = len(detail.Related) - len(detail.RelatedPacks)
+	}
+	return a.output().Result(detail)
+}
+
+// cmdServe loads the dataset once and answers queries over HTTP until
+// interrupted
+func (a *app) cmdServe(loader *Loader, args []string) error {
+	fs := a.flagSet("serve")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	editToken := fs.String("edit-token", os.Getenv("ORIGIN_EDIT_TOKEN"), "bearer token enabling POST /edges (default $ORIGIN_EDIT_TOKEN; empty disables edits)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cache := NewCache(loader)
+	if _, err := cache.Index(); err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+	// Index-only routes keep working while the graph is missing; graph
+	// routes retry the load on each request
+	if _, err := cache.Graph(); err != nil {
+		fmt.Fprintf(a.Err, "Warning: loading graph: %v\n", err)
+	}
+
+	server := NewServer(cache)
+	server.EditToken = *editToken
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(a.Err, "Serving on %s\n", *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+type orphansResult struct {
+	Orphans []Pack `json:"orphans"`
+}
+
+func (r orphansResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Orphan packs (%d):\n", len(r.Orphans))
+	for _, p := range r.Orphans {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+}
+
+func (r orphansResult) ids() []string {
+	return packIDs(r.Orphans)
+}
+
+func (r orphansResult) packList() []Pack {
+	return r.Orphans
+}
+
+// cmdOrphans lists packs with no relationships
+func (a *app) cmdOrphans(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: orphans")
+	}
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+
+	result := orphansResult{Orphans: filterListing(FindOrphans(index, graph))}
+	if result.Orphans == nil {
+		result.Orphans = []Pack{}
+	}
+	if err := SortPacks(result.Orphans, *sortFlag); err != nil {
+		return err
+	}
+	return a.output().Result(result)
+}
+
+type cutpointsResult struct {
+	Packs []Pack `json:"packs"`
+}
+
+func (r cutpointsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Cut vertices, whose removal disconnects the graph (%d):\n", len(r.Packs))
+	for _, p := range r.Packs {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+}
+
+func (r cutpointsResult) ids() []string {
+	return packIDs(r.Packs)
+}
+
+func (r cutpointsResult) packList() []Pack {
+	return r.Packs
+}
+
+// cmdCutpoints lists the articulation points of the graph: packs that must
+// not be deleted until their neighbors are linked some other way
+func (a *app) cmdCutpoints(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: cutpoints")
+	}
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+
+	byID := index.ByID()
+	result := cutpointsResult{Packs: []Pack{}}
+	for _, id := range graph.ArticulationPoints() {
+		p, ok := byID[id]
+		if !ok {
+			p = Pack{ID: id}
+		}
+		result.Packs = append(result.Packs, p)
+	}
+	result.Packs = filterListing(result.Packs)
+	if err := SortPacks(result.Packs, *sortFlag); err != nil {
+		return err
+	}
+	return a.output().Result(result)
+}
+
+type healthResult struct {
+	Packs []PackHealth `json:"packs"`
+}
+
+func (r healthResult) writeText(w io.Writer) {
+	for _, h := range r.Packs {
+		fmt.Fprintf(w, "  %3d  %s: %s\n", h.Score, colorID(h.ID), colorTitle(h.Title))
+		for _, problem := range h.Problems {
+			fmt.Fprintf(w, "         - %s\n", problem)
+		}
+	}
+}
+
+func (r healthResult) ids() []string {
+	return listIDs(r.Packs, func(h PackHealth) string { return h.ID })
+}
+
+// cmdHealth lists the -n least healthy packs, lowest score first, with
+// the problems behind each score
+func (a *app) cmdHealth(loader *Loader, args []string) error {
+	fs := a.flagSet("health")
+	n := fs.Int("n", 10, "number of packs to list; 0 lists all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: health [-n=k]")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+
+	report := graph.HealthReport(index, DefaultHealthOptions)
+	if *n > 0 {
+		report = report[:min(*n, len(report))]
+	}
+	return a.output().Result(healthResult{Packs: report})
+}
+
+type incompletePack struct {
+	// Position is the pack's 1-based place in the index, which identifies
+	// packs with no ID
+	Position int      `json:"position"`
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Missing  []string `json:"missing"`
+}
+
+type incompleteResult struct {
+	Packs []incompletePack `json:"packs"`
+}
+
+func (r incompleteResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Incomplete packs (%d):\n", len(r.Packs))
+	for _, p := range r.Packs {
+		name := colorID(p.ID)
+		if strings.TrimSpace(p.ID) == "" {
+			name = fmt.Sprintf("pack #%d (no id)", p.Position)
+		}
+		fmt.Fprintf(w, "  - %s: missing %s\n", name, strings.Join(p.Missing, ", "))
+	}
+}
+
+func (r incompleteResult) ids() []string {
+	return listIDs(r.Packs, func(x incompletePack) string { return x.ID })
+}
+
+// cmdIncomplete lists packs with a blank ID, title or tier, with the
+// fields each lacks. Every tier is checked, since a blank tier matches
+// none of -tier.
+func (a *app) cmdIncomplete(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: incomplete")
+	}
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+
+	result := incompleteResult{Packs: []incompletePack{}}
+	for i, p := range index.Packs {
+		if missing := p.MissingFields(); len(missing) > 0 {
+			result.Packs = append(result.Packs, incompletePack{Position: i + 1, ID: p.ID, Title: p.Title, Missing: missing})
+		}
+	}
+	result.Packs = filterListingBy(result.Packs, func(p incompletePack) Pack { return index.Packs[p.Position-1] })
+	return a.output().Result(result)
+}
+
+type impactResult struct {
+	DeleteImpact
+	Title           string `json:"title"`
+	SplitsComponent bool   `json:"splits_component"`
+}
+
+func (r impactResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Deleting %s: %s\n", colorID(r.ID), colorTitle(r.Title))
+	fmt.Fprintf(w, "Edges dropped (%d):\n", len(r.DroppedEdges))
+	for _, e := range r.DroppedEdges {
+		fmt.Fprintf(w, "  - %s -> %s (%s)\n", colorID(e.Source), colorID(e.Target), colorType(e.Type))
+	}
+	fmt.Fprintf(w, "New orphans (%d):\n", len(r.NewOrphans))
+	for _, id := range r.NewOrphans {
+		fmt.Fprintf(w, "  - %s\n", colorID(id))
+	}
+	fmt.Fprintf(w, "Broken related references (%d):\n", len(r.BrokenRelated))
+	for _, id := range r.BrokenRelated {
+		fmt.Fprintf(w, "  - %s\n", colorID(id))
+	}
+	fmt.Fprintf(w, "Components: %d before, %d after", r.ComponentsBefore, r.ComponentsAfter)
+	if r.SplitsComponent {
+		fmt.Fprint(w, " (splits a component)")
+	}
+	fmt.Fprintln(w)
+}
+
+func (r impactResult) ids() []string {
+	return r.NewOrphans
+}
+
+// cmdImpact reports what deleting a pack would break, without deleting it
+func (a *app) cmdImpact(loader *Loader, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: impact <id>")
+	}
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	p, err := requirePack(index.ByID(), args[0])
+	if err != nil {
+		return err
+	}
+
+	impact := SimulateDelete(index, graph, p.ID)
+	return a.output().Result(impactResult{DeleteImpact: impact, Title: p.Title, SplitsComponent: impact.SplitsComponent()})
+}
+
+type leavesResult struct {
+	Leaves []Pack `json:"leaves"`
+}
+
+func (r leavesResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Leaf packs with one edge (%d):\n", len(r.Leaves))
+	for _, p := range r.Leaves {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+}
+
+func (r leavesResult) ids() []string {
+	return packIDs(r.Leaves)
+}
+
+func (r leavesResult) packList() []Pack {
+	return r.Leaves
+}
+
+// cmdLeaves lists packs connected by exactly one edge
+func (a *app) cmdLeaves(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: leaves")
+	}
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+
+	byID := index.ByID()
+	result := leavesResult{Leaves: []Pack{}}
+	for _, id := range graph.Leaves() {
+		p, ok := byID[id]
+		if !ok {
+			p = Pack{ID: id}
+		}
+		result.Leaves = append(result.Leaves, p)
+	}
+	result.Leaves = filterListing(result.Leaves)
+	if err := SortPacks(result.Leaves, *sortFlag); err != nil {
+		return err
+	}
+	return a.output().Result(result)
+}
+
+type referrer struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+type referrersResult struct {
+	ID        string     `json:"id"`
+	Referrers []referrer `json:"referrers"`
+}
+
+func (r referrersResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Packs referring to %s (%d):\n", r.ID, len(r.Referrers))
+	for _, ref := range r.Referrers {
+		fmt.Fprintf(w, "  %s %s: %s\n", colorType(fmt.Sprintf("%-10s", ref.Type)), colorID(ref.ID), colorTitle(ref.Title))
+	}
+}
+
+func (r referrersResult) ids() []string {
+	return listIDs(r.Referrers, func(x referrer) string { return x.ID })
+}
+
+// relatedListType labels referrers found only in a Related list
+const relatedListType = "listed"
+
+// cmdReferrers lists packs with an edge to the given pack or that name it
+// in their related list
+func (a *app) cmdReferrers(loader *Loader, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: referrers <id>")
+	}
+	id := args[0]
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	if _, err := requirePack(byID, id); err != nil {
+		return err
+	}
+
+	result := referrersResult{ID: id, Referrers: []referrer{}}
+	seen := make(map[string]bool)
+	for _, edge := range graph.Edges {
+		if edge.Target != id || edge.Source == id {
+			continue
+		}
+		seen[edge.Source] = true
+		result.Referrers = append(result.Referrers, referrer{
+			ID: edge.Source, Title: byID[edge.Source].Title, Type: edge.Type,
+		})
+	}
+	for _, ref := range index.Referrers(id) {
+		if !seen[ref] {
+			result.Referrers = append(result.Referrers, referrer{
+				ID: ref, Title: byID[ref].Title, Type: relatedListType,
+			})
+		}
+	}
+	return a.output().Result(result)
+}
+
+type rankedPack struct {
+	ID    string  `json:"id"`
+	Title string  `json:"title"`
+	Score float64 `json:"score"`
+}
+
+type rankResult struct {
+	Packs []rankedPack `json:"packs"`
+}
+
+func (r rankResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Top packs by PageRank (%d):\n", len(r.Packs))
+	for _, p := range r.Packs {
+		fmt.Fprintf(w, "  %.4f  %s: %s\n", p.Score, colorID(p.ID), colorTitle(p.Title))
+	}
+}
+
+func (r rankResult) ids() []string {
+	return listIDs(r.Packs, func(x rankedPack) string { return x.ID })
+}
+
+// cmdRank lists the top -limit packs by PageRank
+func (a *app) cmdRank(loader *Loader, args []string) error {
+	fs := a.flagSet("rank")
+	damping := fs.Float64("damping", DefaultDamping, "probability of following an edge rather than jumping")
+	iterations := fs.Int("iterations", DefaultIterations, "number of power iterations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: rank [-damping=d] [-iterations=n]")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+
+	byID := index.ByID()
+	scores := graph.PageRankWithProgress(*damping, *iterations, newProgress(a.Err, "pagerank"))
+	result := rankResult{Packs: []rankedPack{}}
+	for i, id := range rankByScore(scores) {
+		if i >= *limitFlag {
+			break
+		}
+		result.Packs = append(result.Packs, rankedPack{ID: id, Title: byID[id].Title, Score: scores[id]})
+	}
+	return a.output().Result(result)
+}
+
+type reachPack struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Reach     int    `json:"reach"`
+	OutDegree int    `json:"out_degree"`
+}
+
+type reachResult struct {
+	Packs []reachPack `json:"packs"`
+}
+
+func (r reachResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Top packs by outgoing reach (%d):\n", len(r.Packs))
+	for _, p := range r.Packs {
+		fmt.Fprintf(w, "  %4d  (%d out)  %s: %s\n", p.Reach, p.OutDegree, colorID(p.ID), colorTitle(p.Title))
+	}
+}
+
+func (r reachResult) ids() []string {
+	return listIDs(r.Packs, func(x reachPack) string { return x.ID })
+}
+
+// cmdReach lists the top -limit packs by how many packs their outgoing
+// edges lead to, with their out-degree for comparison
+func (a *app) cmdReach(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: reach")
+	}
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+
+	byID := index.ByID()
+	adj := graph.BuildAdjacency()
+	sizes := graph.ReachSizesWithProgress(Outgoing, newProgress(a.Err, "reach"))
+	result := reachResult{Packs: []reachPack{}}
+	for i, id := range rankByScore(sizes) {
+		if i >= *limitFlag {
+			break
+		}
+		result.Packs = append(result.Packs, reachPack{
+			ID: id, Title: byID[id].Title, Reach: sizes[id], OutDegree: len(filterDirection(adj[id], id, Outgoing)),
+		})
+	}
+	return a.output().Result(result)
+}
+
+type bridgesResult struct {
+	Packs []rankedPack `json:"packs"`
+}
+
+func (r bridgesResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Top bridging packs by betweenness (%d):\n", len(r.Packs))
+	for _, p := range r.Packs {
+		fmt.Fprintf(w, "  %8.2f  %s: %s\n", p.Score, colorID(p.ID), colorTitle(p.Title))
+	}
+}
+
+func (r bridgesResult) ids() []string {
+	return listIDs(r.Packs, func(x rankedPack) string { return x.ID })
+}
+
+// cmdBridges lists the top -limit packs by betweenness centrality: the
+// packs whose removal would cut the most shortest paths
+func (a *app) cmdBridges(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: bridges")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+
+	byID := index.ByID()
+	scores := graph.BetweennessCentralityWithProgress(newProgress(a.Err, "betweenness"))
+	result := bridgesResult{Packs: []rankedPack{}}
+	for i, id := range rankByScore(scores) {
+		if i >= *limitFlag {
+			break
+		}
+		result.Packs = append(result.Packs, rankedPack{ID: id, Title: byID[id].Title, Score: scores[id]})
+	}
+	return a.output().Result(result)
+}
+
+type reconcileEntry struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	Missing []string `json:"missing"`
+	Extra   []string `json:"extra"`
+}
+
+type reconcileResult struct {
+	Total        int              `json:"total"`
+	Inconsistent []reconcileEntry `json:"inconsistent"`
+}
+
+func (r reconcileResult) writeText(w io.Writer) {
+	for _, e := range r.Inconsistent {
+		fmt.Fprintf(w, "%s: %s\n", colorID(e.ID), colorTitle(e.Title))
+		if len(e.Missing) > 0 {
+			fmt.Fprintf(w, "  related without edge: %s\n", strings.Join(e.Missing, ", "))
+		}
+		if len(e.Extra) > 0 {
+			fmt.Fprintf(w, "  edge not in related:  %s\n", strings.Join(e.Extra, ", "))
+		}
+	}
+	fmt.Fprintf(w, "\n%d of %d packs have discrepancies.\n", len(r.Inconsistent), r.Total)
+}
+
+func (r reconcileResult) ids() []string {
+	return listIDs(r.Inconsistent, func(x reconcileEntry) string { return x.ID })
+}
+
+// cmdReconcile summarizes Related fields that disagree with the graph
+func (a *app) cmdReconcile(loader *Loader, args []string) error {
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+
+	result := reconcileResult{Total: len(index.Packs), Inconsistent: []reconcileEntry{}}
+	for _, p := range index.Packs {
+		missing, extra := p.RelatedConsistency(graph)
+		if len(missing) == 0 && len(extra) == 0 {
+			continue
+		}
+		result.Inconsistent = append(result.Inconsistent, reconcileEntry{
+			ID: p.ID, Title: p.Title, Missing: missing, Extra: extra,
+		})
+	}
+	return a.output().Result(result)
+}
+
+type crumb struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type lineageResult struct {
+	ID    string  `json:"id"`
+	Type  string  `json:"type"`
+	Chain []crumb `json:"chain"`
+}
+
+// writeText prints the chain as breadcrumbs, root first
+func (r lineageResult) writeText(w io.Writer) {
+	parts := make([]string, len(r.Chain))
+	for i, c := range r.Chain {
+		parts[len(r.Chain)-1-i] = fmt.Sprintf("%s (%s)", colorTitle(c.Title), colorID(c.ID))
+	}
+	fmt.Fprintln(w, strings.Join(parts, " › "))
+}
+
+func (r lineageResult) ids() []string {
+	return listIDs(r.Chain, func(x crumb) string { return x.ID })
+}
+
+// cmdLineage prints the breadcrumb from a pack's root down to the pack
+func (a *app) cmdLineage(loader *Loader, args []string) error {
+	fs := a.flagSet("lineage")
+	edgeType := fs.String("type", "parent", "edge type pointing from a pack to its parent")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: lineage [-type=t] <id>")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	if _, err := requirePack(byID, fs.Arg(0)); err != nil {
+		return err
+	}
+
+	chain, err := graph.AncestorChain(fs.Arg(0), *edgeType)
+	if err != nil {
+		return err
+	}
+	result := lineageResult{ID: fs.Arg(0), Type: *edgeType, Chain: []crumb{}}
+	for _, id := range chain {
+		result.Chain = append(result.Chain, crumb{ID: id, Title: byID[id].Title})
+	}
+	return a.output().Result(result)
+}
+
+type listResult struct {
+	Offset int    `json:"offset"`
+	Total  int    `json:"total"`
+	Packs  []Pack `json:"packs"`
+}
+
+func (r listResult) writeText(w io.Writer) {
+	for _, p := range r.Packs {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+	if len(r.Packs) == 0 {
+		fmt.Fprintf(w, "showing 0 of %d\n", r.Total)
+		return
+	}
+	fmt.Fprintf(w, "showing %d-%d of %d\n", r.Offset+1, r.Offset+len(r.Packs), r.Total)
+}
+
+func (r listResult) ids() []string {
+	return packIDs(r.Packs)
+}
+
+func (r listResult) packList() []Pack {
+	return r.Packs
+}
+
+// cmdList prints one page of the packs in the -tier tiers
+func (a *app) cmdList(loader *Loader, args []string) error {
+	fs := a.flagSet("list")
+	offset := fs.Int("offset", 0, "number of packs to skip")
+	pageSize := fs.Int("page-size", 20, "maximum number of packs to show (negative for no limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: list [-offset=n] [-page-size=n]")
+	}
+
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+
+	packs := filterListing(FilterByTier(index.Packs, splitList(*tierFlag)))
+	if err := SortPacks(packs, *sortFlag); err != nil {
+		return err
+	}
+	result := listResult{
+		Offset: max(*offset, 0),
+		Total:  len(packs),
+		Packs:  Paginate(packs, *offset, *pageSize),
+	}
+	return a.output().Result(result)
+}
+
+type filterResult struct {
+	Tag     string `json:"tag"`
+	Matches []Pack `json:"matches"`
+}
+
+func (r filterResult) writeText(w io.Writer) {
+	for _, p := range r.Matches {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+	fmt.Fprintf(w, "%d packs tagged %q.\n", len(r.Matches), r.Tag)
+}
+
+func (r filterResult) ids() []string {
+	return packIDs(r.Matches)
+}
+
+func (r filterResult) packList() []Pack {
+	return r.Matches
+}
+
+// cmdFilter lists the packs in the -tier tiers carrying a tag
+func (a *app) cmdFilter(loader *Loader, args []string) error {
+	fs := a.flagSet("filter")
+	tag := fs.String("tag", "", "tag the packs must carry")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *tag == "" {
+		return fmt.Errorf("usage: filter -tag=<t>")
+	}
+
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+
+	matches := filterListing(FilterByTag(FilterByTier(index.Packs, splitList(*tierFlag)), *tag))
+	if err := SortPacks(matches, *sortFlag); err != nil {
+		return err
+	}
+	return a.output().Result(filterResult{Tag: *tag, Matches: matches})
+}
+
+type reachableResult struct {
+	Starts []string `json:"starts"`
+	Depth  int      `json:"depth"`
+	Type   string   `json:"type,omitempty"`
+	Packs  []Pack   `json:"packs"`
+}
+
+func (r reachableResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Reachable from %s within %d hops (%d):\n", strings.Join(r.Starts, ", "), r.Depth, len(r.Packs))
+	for _, p := range r.Packs {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+}
+
+func (r reachableResult) ids() []string {
+	return packIDs(r.Packs)
+}
+
+func (r reachableResult) packList() []Pack {
+	return r.Packs
+}
+
+// cmdReachable lists every pack within -depth hops of any of the given
+// packs, in breadth-first order
+func (a *app) cmdReachable(loader *Loader, args []string) error {
+	fs := a.flagSet("reachable")
+	depth := fs.Int("depth", 2, "maximum hops from the nearest start")
+	edgeType := fs.String("type", "", "only follow edges of this type")
+	var exclude listFlag
+	fs.Var(&exclude, "exclude-tier", "hide reached packs in this tier, still traversing them (repeatable, or comma-separated)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: reachable [-depth=n] [-type=t] [-exclude-tier=t]... <id> [id...]")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	for _, id := range fs.Args() {
+		if _, err := requirePack(byID, id); err != nil {
+			return err
+		}
+	}
+
+	var types []string
+	if *edgeType != "" {
+		types = []string{*edgeType}
+	}
+	result := reachableResult{Starts: fs.Args(), Depth: *depth, Type: *edgeType, Packs: []Pack{}}
+	for _, id := range FilterPacksExcludingTiers(index, graph.BFSMulti(fs.Args(), *depth, types...), exclude) {
+		p, ok := byID[id]
+		if !ok {
+			p = Pack{ID: id}
+		}
+		result.Packs = append(result.Packs, p)
+	}
+	result.Packs = filterListing(result.Packs)
+	return a.output().Result(result)
+}
+
+type nearestResult struct {
+	Start    string `json:"start"`
+	Tier     string `json:"tier"`
+	Pack     Pack   `json:"pack"`
+	Distance int    `json:"distance"`
+}
+
+func (r nearestResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Nearest %s pack to %s (%d hops):\n", r.Tier, r.Start, r.Distance)
+	fmt.Fprintf(w, "  %s: %s\n", colorID(r.Pack.ID), colorTitle(r.Pack.Title))
+}
+
+func (r nearestResult) ids() []string {
+	return []string{r.Pack.ID}
+}
+
+// cmdNearest prints the closest pack of -tier to the given pack
+func (a *app) cmdNearest(loader *Loader, args []string) error {
+	fs := a.flagSet("nearest")
+	tier := fs.String("tier", "public", "disclosure tier to look for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: nearest [-tier=t] <id>")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	if _, err := requirePack(byID, fs.Arg(0)); err != nil {
+		return err
+	}
+	id, distance, err := graph.NearestOfTier(index, fs.Arg(0), *tier)
+	if err != nil {
+		return err
+	}
+	result := nearestResult{Start: fs.Arg(0), Tier: *tier, Pack: byID[id], Distance: distance}
+	return a.output().Result(result)
+}
+
+type closureResult struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Packs []Pack `json:"packs"`
+}
+
+func (r closureResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "%s depends on %d packs through %s edges:\n", r.ID, len(r.Packs), r.Type)
+	for _, p := range r.Packs {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+}
+
+func (r closureResult) ids() []string {
+	return packIDs(r.Packs)
+}
+
+func (r closureResult) packList() []Pack {
+	return r.Packs
+}
+
+// cmdClosure lists everything a pack transitively depends on
+func (a *app) cmdClosure(loader *Loader, args []string) error {
+	fs := a.flagSet("closure")
+	edgeType := fs.String("type", "depends_on", "edge type pointing from a pack to a dependency")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: closure [-type=depends_on] <id>")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	if _, err := requirePack(byID, fs.Arg(0)); err != nil {
+		return err
+	}
+
+	result := closureResult{ID: fs.Arg(0), Type: *edgeType, Packs: []Pack{}}
+	for _, id := range graph.DependencyClosure(fs.Arg(0), *edgeType) {
+		p, ok := byID[id]
+		if !ok {
+			p = Pack{ID: id}
+		}
+		result.Packs = append(result.Packs, p)
+	}
+	result.Packs = filterListing(result.Packs)
+	return a.output().Result(result)
+}
+
+type recommendResult struct {
+	ID    string `json:"id"`
+	Seed  int64  `json:"seed"`
+	Packs []Pack `json:"packs"`
+}
+
+func (r recommendResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Recommended from %s (seed %d):\n", colorID(r.ID), r.Seed)
+	for _, p := range r.Packs {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+	if len(r.Packs) == 0 {
+		fmt.Fprintln(w, "  (none: the pack has no edges)")
+	}
+}
+
+func (r recommendResult) ids() []string {
+	return packIDs(r.Packs)
+}
+
+func (r recommendResult) packList() []Pack {
+	return r.Packs
+}
+
+// cmdRecommend prints the packs most visited by random walks from a pack.
+// Like random, it prints the seed used so the list can be reproduced.
+func (a *app) cmdRecommend(loader *Loader, args []string) error {
+	fs := a.flagSet("recommend")
+	n := fs.Int("n", 5, "number of packs to recommend")
+	walks := fs.Int("walks", 200, "number of random walks")
+	steps := fs.Int("steps", 4, "steps per walk")
+	seed := fs.Int64("seed", 0, "random seed for reproducible recommendations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *n < 1 {
+		return fmt.Errorf("usage: recommend [-n=k] [-walks=w] [-steps=s] [-seed=s] <id> (k at least 1)")
+	}
+	seeded := false
+	fs.Visit(func(f *flag.Flag) { seeded = seeded || f.Name == "seed" })
+	if !seeded {
+		*seed = time.Now().UnixNano()
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	if _, err := requirePack(byID, fs.Arg(0)); err != nil {
+		return err
+	}
+
+	result := recommendResult{ID: fs.Arg(0), Seed: *seed, Packs: []Pack{}}
+	for _, id := range graph.WalkRecommendations(fs.Arg(0), *walks, *steps, *seed) {
+		p, ok := byID[id]
+		if !ok {
+			p = Pack{ID: id}
+		}
+		result.Packs = append(result.Packs, p)
+	}
+	result.Packs = filterListing(result.Packs)
+	result.Packs = result.Packs[:min(*n, len(result.Packs))]
+	return a.output().Result(result)
+}
+
+type randomResult struct {
+	Seed  int64  `json:"seed"`
+	Packs []Pack `json:"packs"`
+}
+
+func (r randomResult) writeText(w io.Writer) {
+	for _, p := range r.Packs {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+	fmt.Fprintf(w, "%d packs sampled (seed %d).\n", len(r.Packs), r.Seed)
+}
+
+func (r randomResult) ids() []string {
+	return packIDs(r.Packs)
+}
+
+func (r randomResult) packList() []Pack {
+	return r.Packs
+}
+
+// cmdRandom prints a random sample of the -tier packs. Without -seed a
+// fresh seed is used; it is printed so the sample can be reproduced.
+func (a *app) cmdRandom(loader *Loader, args []string) error {
+	fs := a.flagSet("random")
+	n := fs.Int("n", 5, "number of packs to sample")
+	seed := fs.Int64("seed", 0, "random seed for a reproducible sample")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: random [-n=k] [-seed=s]")
+	}
+	seeded := false
+	fs.Visit(func(f *flag.Flag) { seeded = seeded || f.Name == "seed" })
+	if !seeded {
+		*seed = time.Now().UnixNano()
+	}
+
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+
+	packs := filterListing(FilterByTier(index.Packs, splitList(*tierFlag)))
+	result := randomResult{Seed: *seed, Packs: SamplePacks(packs, *n, *seed)}
+	return a.output().Result(result)
+}
+
+type lookupResult struct {
+	Results []LookupResult `json:"results"`
+}
+
+func (r lookupResult) writeText(w io.Writer) {
+	found := 0
+	for _, res := range r.Results {
+		if res.Found {
+			found++
+			fmt.Fprintf(w, "  - %s: %s\n", colorID(res.ID), colorTitle(res.Pack.Title))
+		} else {
+			fmt.Fprintf(w, "  ? %s: not found\n", colorID(res.ID))
+		}
+	}
+	fmt.Fprintf(w, "%d of %d IDs found.\n", found, len(r.Results))
+}
+
+func (r lookupResult) ids() []string {
+	var ids []string
+	for _, res := range r.Results {
+		if res.Found {
+			ids = append(ids, res.ID)
+		}
+	}
+	return ids
+}
+
+// cmdLookup resolves newline-separated pack IDs read from stdin or -file,
+// in input order
+func (a *app) cmdLookup(loader *Loader, args []string) error {
+	fs := a.flagSet("lookup")
+	file := fs.String("file", "", "read IDs from this file instead of stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: lookup [-file=path] < ids.txt")
+	}
+
+	in := a.In
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("reading IDs: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	ids, err := readLines(in)
+	if err != nil {
+		return fmt.Errorf("reading IDs: %w", err)
+	}
+
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+	return a.output().Result(lookupResult{Results: LookupMany(index, ids)})
+}
+
+type searchResult struct {
+	Query   string `json:"query"`
+	Matches []Pack `json:"matches"`
+}
+
+func (r searchResult) writeText(w io.Writer) {
+	for _, p := range r.Matches {
+		fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+	}
+	fmt.Fprintf(w, "%d matches for %q.\n", len(r.Matches), r.Query)
+}
+
+func (r searchResult) ids() []string {
+	return packIDs(r.Matches)
+}
+
+func (r searchResult) packList() []Pack {
+	return r.Matches
+}
+
+// cmdSearch lists packs whose title, or the fields chosen by -fields,
+// match a query
+func (a *app) cmdSearch(loader *Loader, args []string) error {
+	fs := a.flagSet("search")
+	fields := fs.String("fields", SearchTitle, "comma-separated fields to match: id, title")
+	wholeWord := fs.Bool("whole-word", false, "match whole words only")
+	caseSensitive := fs.Bool("case-sensitive", false, "match case exactly")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: search [-fields=id,title] [-whole-word] [-case-sensitive] <query>")
+	}
+	opts := SearchOptions{Query: fs.Arg(0), Fields: splitList(*fields), WholeWord: *wholeWord, CaseSensitive: *caseSensitive}
+	for _, f := range opts.Fields {
+		if f != SearchID && f != SearchTitle {
+			return fmt.Errorf("unknown search field %q (want %s or %s)", f, SearchID, SearchTitle)
+		}
+	}
+
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+
+	result := searchResult{Query: opts.Query, Matches: filterListing(Search(index.Packs, opts))}
+	if result.Matches == nil {
+		result.Matches = []Pack{}
+	}
+	if err := SortPacks(result.Matches, *sortFlag); err != nil {
+		return err
+	}
+	return a.output().Result(result)
+}
+
+type componentsResult struct {
+	Count      int        `json:"count"`
+	Components [][]string `json:"components"`
+}
+
+func (r componentsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Connected components: %d\n", r.Count)
+	for i, c := range r.Components {
+		fmt.Fprintf(w, "  %d. %d packs (e.g. %s)\n", i+1, len(c), c[0])
+	}
+}
+
+type componentDetail struct {
+	ComponentStat
+	HubTitle string `json:"hub_title"`
+}
+
+type componentDetailResult struct {
+	Components []componentDetail `json:"components"`
+}
+
+func (r componentDetailResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Connected components: %d\n", len(r.Components))
+	fmt.Fprintf(w, "  %3s  %5s  %5s  %s\n", "#", "packs", "edges", "hub")
+	for i, c := range r.Components {
+		fmt.Fprintf(w, "  %3d  %5d  %5d  %s: %s (%d edges)\n",
+			i+1, c.Nodes, c.Edges, colorID(c.Hub), colorTitle(c.HubTitle), c.HubDegree)
+	}
+}
+
+// cmdComponents reports the connected components of the graph, with
+// per-component counts and hubs under -detail
+func (a *app) cmdComponents(loader *Loader, args []string) error {
+	fs := a.flagSet("components")
+	detail := fs.Bool("detail", false, "show node and edge counts and the hub of each component")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: components [-detail]")
+	}
+
+	if *detail {
+		index, graph, err := LoadAll(loader)
+		if err != nil {
+			return err
+		}
+		byID := index.ByID()
+		result := componentDetailResult{Components: []componentDetail{}}
+		for _, stat := range graph.ComponentStats() {
+			result.Components = append(result.Components, componentDetail{stat, byID[stat.Hub].Title})
+		}
+		return a.output().Result(result)
+	}
+
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+
+	components := graph.ConnectedComponents()
+	if components == nil {
+		components = [][]string{}
+	}
+	return a.output().Result(componentsResult{Count: len(components), Components: components})
+}
+
+type community struct {
+	ID      int      `json:"id"`
+	Size    int      `json:"size"`
+	Members []string `json:"members"`
+	// Sample is up to -sample members, with their titles
+	Sample []Pack `json:"sample"`
+}
+
+type communitiesResult struct {
+	Seed        int64       `json:"seed"`
+	Communities []community `json:"communities"`
+}
+
+func (r communitiesResult) writeText(w io.Writer) {
+	for _, c := range r.Communities {
+		titles := make([]string, len(c.Sample))
+		for i, p := range c.Sample {
+			titles[i] = colorTitle(cmp.Or(p.Title, p.ID))
+		}
+		more := ""
+		if c.Size > len(c.Sample) {
+			more = ", ..."
+		}
+		fmt.Fprintf(w, "  %d: %d packs: %s%s\n", c.ID, c.Size, strings.Join(titles, ", "), more)
+	}
+	fmt.Fprintf(w, "%d communities (seed %d).\n", len(r.Communities), r.Seed)
+}
+
+// cmdCommunities groups the graph's nodes into communities by label
+// propagation and prints each community's size and sample members. Like
+// random, it prints the seed used so a run can be reproduced.
+func (a *app) cmdCommunities(loader *Loader, args []string) error {
+	fs := a.flagSet("communities")
+	iterations := fs.Int("iterations", 20, "maximum label propagation passes")
+	seed := fs.Int64("seed", 0, "random seed for reproducible communities")
+	sample := fs.Int("sample", 3, "member titles to show per community")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: communities [-iterations=n] [-seed=s] [-sample=k]")
+	}
+	seeded := false
+	fs.Visit(func(f *flag.Flag) { seeded = seeded || f.Name == "seed" })
+	if !seeded {
+		*seed = time.Now().UnixNano()
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+
+	byID := index.ByID()
+	result := communitiesResult{Seed: *seed, Communities: []community{}}
+	for id, members := range CommunityMembers(graph.CommunitiesSeed(*iterations, *seed)) {
+		c := community{ID: id, Size: len(members), Members: members, Sample: []Pack{}}
+		for _, m := range members[:min(*sample, len(members))] {
+			p, ok := byID[m]
+			if !ok {
+				p = Pack{ID: m}
+			}
+			c.Sample = append(c.Sample, p)
+		}
+		result.Communities = append(result.Communities, c)
+	}
+	return a.output().Result(result)
+}
+
+type cyclesResult struct {
+	Type   string     `json:"type,omitempty"`
+	Cycles [][]string `json:"cycles"`
+
+	labels idLabels
+}
+
+func (r cyclesResult) writeText(w io.Writer) {
+	if len(r.Cycles) == 0 {
+		fmt.Fprintln(w, "OK: no cycles found.")
+		return
+	}
+	for _, c := range r.Cycles {
+		ids := make([]string, len(c))
+		for i, id := range c {
+			ids[i] = r.labels.label(id)
+		}
+		fmt.Fprintf(w, "  - %s -> %s\n", strings.Join(ids, " -> "), ids[0])
+	}
+}
+
+// cmdCycles reports directed cycles and fails if any are found
+func (a *app) cmdCycles(loader *Loader, args []string) error {
+	fs := a.flagSet("cycles")
+	edgeType := fs.String("type", "", "only follow edges of this type")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: cycles [-type=t]")
+	}
+
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+
+	labels, err := loadIDLabels(loader)
+	if err != nil {
+		return err
+	}
+	result := cyclesResult{Type: *edgeType, Cycles: graph.FindCycles(*edgeType), labels: labels}
+	if result.Cycles == nil {
+		result.Cycles = [][]string{}
+	}
+	if err := a.output().Result(result); err != nil {
+		return err
+	}
+
+	if len(result.Cycles) > 0 {
+		return fmt.Errorf("found %d cycles", len(result.Cycles))
+	}
+	return nil
+}
+
+type crossTierEdge struct {
+	GraphEdge
+	SourceTier string `json:"source_tier"`
+	TargetTier string `json:"target_tier"`
+}
+
+type crossTierResult struct {
+	Lower string          `json:"lower"`
+	Edges []crossTierEdge `json:"edges"`
+}
+
+func (r crossTierResult) writeText(w io.Writer) {
+	if len(r.Edges) == 0 {
+		fmt.Fprintf(w, "OK: no edges link %s packs to higher tiers.\n", r.Lower)
+		return
+	}
+	fmt.Fprintf(w, "Edges crossing out of %s (%d):\n", r.Lower, len(r.Edges))
+	for _, e := range r.Edges {
+		fmt.Fprintf(w, "  %s (%s) -%s-> %s (%s)\n",
+			colorID(e.Source), e.SourceTier, colorType(e.Type), colorID(e.Target), e.TargetTier)
+	}
+}
+
+// cmdCrossTier lists edges joining -lower packs to packs of a higher tier,
+// for leak checks before publishing. It exits non-zero if any are found.
+func (a *app) cmdCrossTier(loader *Loader, args []string) error {
+	fs := a.flagSet("crosstier")
+	lower := fs.String("lower", "public", "tier whose outward links are checked")
+	higher := fs.String("higher", "", "comma-separated higher tiers (default every tier ranked above -lower)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: crosstier [-lower=tier] [-higher=a,b]")
+	}
+
+	higherTiers := splitList(*higher)
+	if len(higherTiers) == 0 {
+		rank := tierRank(*lower)
+		if rank < 0 {
+			return fmt.Errorf("unknown tier %q; pass -higher or -tier-order", *lower)
+		}
+		higherTiers = Tiers()[rank+1:]
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+
+	byID := index.ByID()
+	result := crossTierResult{Lower: *lower, Edges: []crossTierEdge{}}
+	for _, tier := range higherTiers {
+		for _, edge := range CrossTierEdges(index, graph, *lower, tier) {
+			result.Edges = append(result.Edges, crossTierEdge{
+				GraphEdge:  edge,
+				SourceTier: byID[edge.Source].DisclosureTier,
+				TargetTier: byID[edge.Target].DisclosureTier,
+			})
+		}
+	}
+	if err := a.output().Result(result); err != nil {
+		return err
+	}
+	if len(result.Edges) > 0 {
+		return fmt.Errorf("found %d cross-tier edges", len(result.Edges))
+	}
+	return nil
+}
+
+type tierCount struct {
+	Tier  string `json:"tier"`
+	Count int    `json:"count"`
+}
+
+type tiersResult struct {
+	Total int         `json:"total"`
+	Tiers []tierCount `json:"tiers"`
+}
+
+func (r tiersResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Disclosure tiers (%d packs):\n", r.Total)
+	for _, t := range r.Tiers {
+		fmt.Fprintf(w, "  %-12s %d\n", t.Tier, t.Count)
+	}
+}
+
+// cmdTiers prints the number of packs in each disclosure tier
+func (a *app) cmdTiers(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: tiers")
+	}
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+
+	counts := TierCounts(index.Packs)
+	result := tiersResult{Total: len(index.Packs), Tiers: []tierCount{}}
+	for _, tier := range rankByScore(counts) {
+		result.Tiers = append(result.Tiers, tierCount{Tier: tier, Count: counts[tier]})
+	}
+	return a.output().Result(result)
+}
+
+type tagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type tagsResult struct {
+	Tags []tagCount `json:"tags"`
+}
+
+func (r tagsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Tags (%d):\n", len(r.Tags))
+	for _, t := range r.Tags {
+		fmt.Fprintf(w, "  %-20s %d\n", t.Tag, t.Count)
+	}
+}
+
+// cmdTags prints every distinct tag with the number of packs carrying it
+func (a *app) cmdTags(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: tags")
+	}
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+
+	counts := TagCounts(FilterByTier(index.Packs, splitList(*tierFlag)))
+	result := tagsResult{Tags: []tagCount{}}
+	for _, tag := range rankByScore(counts) {
+		result.Tags = append(result.Tags, tagCount{Tag: tag, Count: counts[tag]})
+	}
+	return a.output().Result(result)
+}
+
+type namespaceCount struct {
+	Namespace string `json:"namespace"`
+	Count     int    `json:"count"`
+}
+
+type namespacesResult struct {
+	Namespaces []namespaceCount `json:"namespaces"`
+}
+
+func (r namespacesResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Namespaces (%d):\n", len(r.Namespaces))
+	for _, ns := range r.Namespaces {
+		name := ns.Namespace
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(w, "  %-20s %d\n", name, ns.Count)
+	}
+}
+
+// cmdNamespaces prints every ID namespace with the number of packs in it
+func (a *app) cmdNamespaces(loader *Loader, args []string) error {
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+
+	packs := FilterByTier(index.Packs, splitList(*tierFlag))
+	counts := make(map[string]int)
+	for _, p := range packs {
+		counts[namespaceOf(p.ID)]++
+	}
+	result := namespacesResult{Namespaces: []namespaceCount{}}
+	for _, ns := range Namespaces(packs) {
+		result.Namespaces = append(result.Namespaces, namespaceCount{Namespace: ns, Count: counts[ns]})
+	}
+	return a.output().Result(result)
+}
+
+type centralResult struct {
+	Packs []HubPack `json:"packs"`
+}
+
+func (r centralResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Most connected packs (%d):\n", len(r.Packs))
+	for _, h := range r.Packs {
+		fmt.Fprintf(w, "  %3d  %s: %s\n", h.Degree, colorID(h.ID), colorTitle(h.Title))
+	}
+}
+
+func (r centralResult) ids() []string {
+	return listIDs(r.Packs, func(x HubPack) string { return x.ID })
+}
+
+// cmdCentral lists the packs with the most incident edges
+func (a *app) cmdCentral(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: central")
+	}
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+
+	result := centralResult{Packs: TopHubs(index, graph, *limitFlag)}
+	return a.output().Result(result)
+}
+
+type typeCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+type edgeTypesResult struct {
+	Types []typeCount `json:"types"`
+}
+
+func (r edgeTypesResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Edge types (%d):\n", len(r.Types))
+	for _, t := range r.Types {
+		fmt.Fprintf(w, "  %s %d\n", colorType(fmt.Sprintf("%-12s", t.Type)), t.Count)
+	}
+}
+
+type edgesResult struct {
+	Type  string      `json:"type"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+func (r edgesResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Edges of type %q (%d):\n", r.Type, len(r.Edges))
+	for _, edge := range r.Edges {
+		fmt.Fprintf(w, "  %s -> %s\n", colorID(edge.Source), colorID(edge.Target))
+	}
+}
+
+func (r edgesResult) edgeList() []GraphEdge {
+	return r.Edges
+}
+
+// cmdEdges lists edges of one type, or the edge types in use
+func (a *app) cmdEdges(loader *Loader, args []string) error {
+	fs := a.flagSet("edges")
+	edgeType := fs.String("type", "", "edge type to list; omit to count edge types")
+	vocab := fs.Bool("vocab", false, "print the edge types in use, one per line, for -edge-vocab")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: edges [-type=t] [-vocab]")
+	}
+
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+
+	if *vocab {
+		for _, t := range EdgeVocabulary(graph) {
+			fmt.Fprintln(a.Out, t)
+		}
+		return nil
+	}
+
+	if *edgeType == "" {
+		counts := graph.EdgeTypeCounts()
+		result := edgeTypesResult{Types: []typeCount{}}
+		for _, t := range rankByScore(counts) {
+			result.Types = append(result.Types, typeCount{Type: t, Count: counts[t]})
+		}
+		return a.output().Result(result)
+	}
+
+	result := edgesResult{Type: *edgeType, Edges: graph.EdgesOfType(*edgeType)}
+	if result.Edges == nil {
+		result.Edges = []GraphEdge{}
+	}
+	return a.output().Result(result)
+}
+
+// cmdTree prints a pack and its relationships as an indented tree
+func (a *app) cmdTree(loader *Loader, args []string) error {
+	fs := a.flagSet("tree")
+	depth := fs.Int("depth", 2, "maximum tree depth")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: tree [-depth=n] <id>")
+	}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	if _, err := requirePack(index.ByID(), fs.Arg(0)); err != nil {
+		return err
+	}
+
+	graph.printTree(a.Out, fs.Arg(0), *depth, newIDLabels(index).label)
+	return nil
+}
+
+func (r Summary) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Packs:      %d\n", r.PackCount)
+	fmt.Fprintf(w, "Edges:      %d\n", r.EdgeCount)
+	fmt.Fprintf(w, "Components: %d\n", r.Components)
+	fmt.Fprintf(w, "Orphans:    %d\n", r.Orphans)
+	fmt.Fprintln(w, "Tiers:")
+	for _, tier := range rankByScore(r.Tiers) {
+		fmt.Fprintf(w, "  %-12s %d\n", tier, r.Tiers[tier])
+	}
+	fmt.Fprintln(w, "Top hubs:")
+	for _, h := range r.Hubs {
+		fmt.Fprintf(w, "  %3d  %s: %s\n", h.Degree, colorID(h.ID), colorTitle(h.Title))
+	}
+}
+
+// cmdStats prints a one-shot overview of the dataset
+func (a *app) cmdStats(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: stats")
+	}
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	return a.output().Result(Summarize(index, graph))
+}
+
+type hashResult struct {
+	Hash string `json:"hash"`
+}
+
+func (r hashResult) writeText(w io.Writer) {
+	fmt.Fprintln(w, r.Hash)
+}
+
+// cmdHash prints the content hash of the dataset
+func (a *app) cmdHash(loader *Loader, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: hash")
+	}
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	return a.output().Result(hashResult{Hash: DatasetHash(index, graph)})
+}
+
+type metricsResult struct {
+	Nodes         int     `json:"nodes"`
+	Edges         int     `json:"edges"`
+	Density       float64 `json:"density"`
+	Diameter      int     `json:"diameter"`
+	AverageDegree float64 `json:"average_degree"`
+}
+
+func (r metricsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Graph metrics (%d nodes, %d edges):\n", r.Nodes, r.Edges)
+	fmt.Fprintf(w, "  density:        %.4f\n", r.Density)
+	fmt.Fprintf(w, "  diameter:       %d\n", r.Diameter)
+	fmt.Fprintf(w, "  average degree: %.2f\n", r.AverageDegree)
+}
+
+// cmdMetrics prints the graph's density, diameter and average degree
+func (a *app) cmdMetrics(loader *Loader, args []string) error {
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+
+	diameter, err := graph.Diameter()
+	if err != nil {
+		return err
+	}
+	nodes := len(graph.BuildAdjacency())
+	return a.output().Result(metricsResult{
+		Nodes:         nodes,
+		Edges:         len(graph.Edges),
+		Density:       graph.Density(),
+		Diameter:      diameter,
+		AverageDegree: 2 * float64(len(graph.Edges)) / float64(nodes),
+	})
+}
+
+type similarPack struct {
+	ID    string  `json:"id"`
+	Title string  `json:"title"`
+	Score float64 `json:"score"`
+}
+
+type similarResult struct {
+	ID      string        `json:"id"`
+	Similar []similarPack `json:"similar"`
+}
+
+func (r similarResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Packs most similar to %s (%d):\n", r.ID, len(r.Similar))
+	for _, s := range r.Similar {
+		fmt.Fprintf(w, "  %.2f  %s: %s\n", s.Score, colorID(s.ID), colorTitle(s.Title))
+	}
+}
+
+func (r similarResult) ids() []string {
+	return listIDs(r.Similar, func(x similarPack) string { return x.ID })
+}
+
+// cmdSimilar ranks other packs by the Jaccard similarity of their
+// neighborhoods to the given pack
+func (a *app) cmdSimilar(loader *Loader, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: similar <id>")
+	}
+	id := args[0]
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	if _, err := requirePack(byID, id); err != nil {
+		return err
+	}
+
+	scores := graph.similarityScores(id)
+	result := similarResult{ID: id, Similar: []similarPack{}}
+	for i, other := range rankByScore(scores) {
+		if i >= *limitFlag {
+			break
+		}
+		result.Similar = append(result.Similar, similarPack{
+			ID: other, Title: byID[other].Title, Score: scores[other],
+		})
+	}
+	return a.output().Result(result)
+}
+
+// sptNode is one pack of a shortest path tree outline
+type sptNode struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Children []sptNode `json:"children"`
+}
+
+type sptResult struct {
+	Root sptNode `json:"root"`
+}
+
+func (r sptResult) writeText(w io.Writer) {
+	var write func(n sptNode, depth int)
+	write = func(n sptNode, depth int) {
+		fmt.Fprintf(w, "%s%s: %s\n", strings.Repeat("  ", depth), colorID(n.ID), colorTitle(n.Title))
+		for _, c := range n.Children {
+			write(c, depth+1)
+		}
+	}
+	write(r.Root, 0)
+}
+
+func (r sptResult) ids() []string {
+	var ids []string
+	var walk func(n sptNode)
+	walk = func(n sptNode) {
+		ids = append(ids, n.ID)
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(r.Root)
+	return ids
+}
+
+// cmdSPT prints the breadth-first tree from a pack as an outline, each
+// pack under the neighbor it is first reached from, siblings by ID
+func (a *app) cmdSPT(loader *Loader, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: spt <id>")
+	}
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	root, err := requirePack(byID, args[0])
+	if err != nil {
+		return err
+	}
+
+	children := make(map[string][]string)
+	for id, parent := range graph.ShortestPathTree(root.ID) {
+		if parent != "" {
+			children[parent] = append(children[parent], id)
+		}
+	}
+	var build func(id string) sptNode
+	build = func(id string) sptNode {
+		n := sptNode{ID: id, Title: byID[id].Title, Children: []sptNode{}}
+		slices.Sort(children[id])
+		for _, c := range children[id] {
+			n.Children = append(n.Children, build(c))
+		}
+		return n
+	}
+	return a.output().Result(sptResult{Root: build(root.ID)})
+}
+
+type staleResult struct {
+	OlderThan string `json:"older_than"`
+	Stale     []Pack `json:"stale"`
+	Unknown   []Pack `json:"unknown_age"`
+}
+
+func (r staleResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Packs not updated in %s (%d):\n", r.OlderThan, len(r.Stale))
+	for _, p := range r.Stale {
+		fmt.Fprintf(w, "  - %s  %s: %s\n", p.UpdatedAt.Format(time.DateOnly), colorID(p.ID), colorTitle(p.Title))
+	}
+	if len(r.Unknown) > 0 {
+		fmt.Fprintf(w, "Unknown age (%d):\n", len(r.Unknown))
+		for _, p := range r.Unknown {
+			fmt.Fprintf(w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
+		}
+	}
+}
+
+func (r staleResult) ids() []string {
+	return packIDs(r.Stale)
+}
+
+func (r staleResult) packList() []Pack {
+	return r.Stale
+}
+
+// parseAge parses a duration such as 90d, 2w or 36h: a whole number of
+// days or weeks, or anything time.ParseDuration accepts
+func parseAge(s string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return d, nil
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// cmdStale lists the -tier packs not updated within -older-than, oldest
+// first, and then those with no updated_at
+func (a *app) cmdStale(loader *Loader, args []string) error {
+	fs := a.flagSet("stale")
+	olderThan := fs.String("older-than", "90d", "list packs last updated longer ago than this, such as 90d, 2w or 36h")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: stale [-older-than=90d]")
+	}
+	age, err := parseAge(*olderThan)
+	if err != nil {
+		return err
+	}
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+
+	packs := filterListing(FilterByTier(index.Packs, splitList(*tierFlag)))
+	return a.output().Result(staleResult{
+		OlderThan: *olderThan,
+		Stale:     StalePacks(packs, age),
+		Unknown:   UnknownAge(packs),
+	})
+}
+
+type suggestion struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Paths int    `json:"paths"`
+}
+
+type suggestResult struct {
+	ID          string       `json:"id"`
+	Suggestions []suggestion `json:"suggestions"`
+}
+
+func (r suggestResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Suggested relations for %s (%d):\n", r.ID, len(r.Suggestions))
+	for _, s := range r.Suggestions {
+		fmt.Fprintf(w, "  %3d  %s: %s\n", s.Paths, colorID(s.ID), colorTitle(s.Title))
+	}
+}
+
+func (r suggestResult) ids() []string {
+	return listIDs(r.Suggestions, func(x suggestion) string { return x.ID })
+}
+
+// cmdSuggest lists packs two hops away that could be linked directly
+func (a *app) cmdSuggest(loader *Loader, args []string) error {
+	fs := a.flagSet("suggest")
+	minShared := fs.Int("min-shared", 0, "only suggest packs sharing at least this many neighbors with the pack")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: suggest [-min-shared=k] <id>")
+	}
+	id := fs.Arg(0)
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		return err
+	}
+	byID := index.ByID()
+	if _, err := requirePack(byID, id); err != nil {
+		return err
+	}
+
+	scores := graph.suggestionScores(id)
+	result := suggestResult{ID: id, Suggestions: []suggestion{}}
+	for _, candidate := range rankByScore(scores) {
+		if len(result.Suggestions) >= *limitFlag {
+			break
+		}
+		if scores[candidate] < *minShared {
+			continue
+		}
+		result.Suggestions = append(result.Suggestions, suggestion{
+			ID: candidate, Title: byID[candidate].Title, Paths: scores[candidate],
+		})
+	}
+	return a.output().Result(result)
+}
+
+type topoResult struct {
+	Type  string   `json:"type,omitempty"`
+	Order []string `json:"order"`
+}
+
+func (r topoResult) writeText(w io.Writer) {
+	for i, id := range r.Order {
+		fmt.Fprintf(w, "%4d. %s\n", i+1, colorID(id))
+	}
+}
+
+func (r topoResult) ids() []string {
+	return r.Order
+}
+
+// cmdTopo prints a dependency-first ordering of the graph
+func (a *app) cmdTopo(loader *Loader, args []string) error {
+	fs := a.flagSet("topo")
+	edgeType := fs.String("type", "", "only order by edges of this type")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: topo [-type=t]")
+	}
+
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		return fmt.Errorf("loading graph: %w", err)
+	}
+
+	order, err := graph.TopoSort(*edgeType)
+	if err != nil {
+		return err
+	}
+	return a.output().Result(topoResult{Type: *edgeType, Order: order})
+}
@@ -0,0 +1,875 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testApp returns an app writing to the returned buffers
+func testApp() (*app, *bytes.Buffer, *bytes.Buffer) {
+	var out, errOut bytes.Buffer
+	return &app{In: strings.NewReader(""), Out: &out, Err: &errOut}, &out, &errOut
+}
+
+func TestCommandOutput(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha","disclosure_tier":"public"},{"id":"B","title":"Beta","disclosure_tier":"public"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"related"}]}`)},
+	}}
+
+	if err := a.runCommand(loader, "path", []string{"A", "B"}); err != nil {
+		t.Fatalf("path: %v", err)
+	}
+	want := "Path from A to B (1 hops):\n  A\n  → related: B\n"
+	if out.String() != want {
+		t.Errorf("path output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	a.In = strings.NewReader("B\n\nX\n")
+	if err := a.runCommand(loader, "lookup", nil); err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	want = "  - B: Beta\n  ? X: not found\n1 of 2 IDs found.\n"
+	if out.String() != want {
+		t.Errorf("lookup output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestIndexOnlyDataset(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha","disclosure_tier":"public"}]}`)},
+	}}
+
+	for _, args := range [][]string{{"search", "alpha"}, {"tiers"}, {"list"}} {
+		out.Reset()
+		if err := a.runCommand(loader, args[0], args[1:]); err != nil {
+			t.Errorf("%v without graph: %v", args, err)
+		}
+		if out.Len() == 0 {
+			t.Errorf("%v without graph printed nothing", args)
+		}
+	}
+
+	err := a.runCommand(loader, "neighbors", []string{"A"})
+	if !errors.Is(err, ErrDistNotFound) {
+		t.Errorf("neighbors without graph: err = %v, want ErrDistNotFound", err)
+	}
+}
+
+func TestCheckDataset(t *testing.T) {
+	a, _, errOut := testApp()
+	dirty := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"metadata":{"pack_count":1},"packs":[{"id":"A","disclosure_tier":"public"}]}`)},
+		GraphFile: {Data: []byte(`{"metadata":{"node_count":2,"edge_count":1},"edges":[{"source":"A","target":"Z","type":"related"}]}`)},
+	}}
+
+	if err := a.checkDataset(dirty, false); err != nil {
+		t.Errorf("non-strict: %v", err)
+	}
+	if !strings.HasPrefix(errOut.String(), `Warning: edge A -> Z (related): unknown target "Z"`) {
+		t.Errorf("non-strict warnings = %q", errOut.String())
+	}
+	if err := a.checkDataset(dirty, true); err == nil || !strings.Contains(err.Error(), "1 problems") {
+		t.Errorf("strict: err = %v, want one problem", err)
+	}
+
+	indexOnly := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"metadata":{"pack_count":1},"packs":[{"id":"A","disclosure_tier":"public"}]}`)},
+	}}
+	if err := a.checkDataset(indexOnly, true); err != nil {
+		t.Errorf("strict without graph: %v", err)
+	}
+	if err := a.checkDataset(&Loader{FS: fstest.MapFS{}}, true); err != nil {
+		t.Errorf("strict without index: %v", err)
+	}
+}
+
+func TestRunValidateReportsOnce(t *testing.T) {
+	a, out, errOut := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"metadata":{"pack_count":1},"packs":[{"id":"A","disclosure_tier":"public"}]}`)},
+		GraphFile: {Data: []byte(`{"metadata":{"node_count":2,"edge_count":1},"edges":[{"source":"A","target":"Z","type":"related"}]}`)},
+	}}
+
+	if err := a.run(loader, []string{"validate"}); err == nil {
+		t.Error("validate succeeded on a dangling edge")
+	}
+	if n := strings.Count(out.String()+errOut.String(), `unknown target "Z"`); n != 1 {
+		t.Errorf("problem printed %d times, want once:\n%s%s", n, out, errOut)
+	}
+}
+
+func TestStrictAllowsRepairs(t *testing.T) {
+	*strictFlag = true
+	defer func() { *strictFlag = false }()
+	a, _, _ := testApp()
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, `{"metadata":{"pack_count":1},"packs":[{"id":"A","disclosure_tier":"public"}]}`)
+	writeFile(t, dir, GraphFile, `{"metadata":{"node_count":9,"edge_count":1},"edges":[{"source":"A","target":"Z","type":"related"}]}`)
+	loader := NewLoader(dir)
+
+	if err := a.run(loader, []string{"tiers"}); err == nil {
+		t.Error("-strict ran a command on a dataset with problems")
+	}
+	if err := a.run(loader, []string{"fix-metadata"}); err != nil {
+		t.Errorf("-strict fix-metadata: %v", err)
+	}
+	if err := a.run(loader, []string{"validate", "-fix", "-yes"}); err != nil {
+		t.Errorf("-strict validate -fix: %v", err)
+	}
+	if err := a.run(loader, []string{"tiers"}); err != nil {
+		t.Errorf("-strict after the repairs: %v", err)
+	}
+}
+
+func TestValidateTitleWarnings(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"metadata":{"pack_count":2},"packs":[{"id":"A","title":"Same","disclosure_tier":"public"},{"id":"B","title":"same","disclosure_tier":"public"}]}`)},
+		GraphFile: {Data: []byte(`{"metadata":{"node_count":2,"edge_count":1},"edges":[{"source":"A","target":"B","type":"related"}]}`)},
+	}}
+
+	if err := a.runCommand(loader, "validate", nil); err != nil {
+		t.Fatalf("duplicate titles should only warn: %v", err)
+	}
+	want := "  - warning: title \"Same\" is shared by A, B\nOK: no problems found.\n"
+	if out.String() != want {
+		t.Errorf("validate output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCrossTierCommand(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","disclosure_tier":"public"},{"id":"B","disclosure_tier":"secret"},{"id":"C","disclosure_tier":"internal"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"related"},{"source":"C","target":"B","type":"related"}]}`)},
+	}}
+
+	if err := a.runCommand(loader, "crosstier", nil); err == nil {
+		t.Error("crosstier found a leak but succeeded")
+	}
+	want := "Edges crossing out of public (1):\n  A (public) -related-> B (secret)\n"
+	if out.String() != want {
+		t.Errorf("crosstier output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := a.runCommand(loader, "crosstier", []string{"-lower=public", "-higher=internal"}); err != nil {
+		t.Errorf("no public-internal edges: %v", err)
+	}
+}
+
+func TestFixMetadataCommand(t *testing.T) {
+	a, out, _ := testApp()
+	dir := t.TempDir()
+	const stale = `{"metadata":{"node_count":9,"edge_count":1},"edges":[{"source":"A","target":"B"},{"source":"B","target":"C"}]}`
+	writeFile(t, dir, GraphFile, stale)
+	loader := NewLoader(dir)
+
+	if err := a.runCommand(loader, "fix-metadata", []string{"-dry-run"}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(out.String(), "node_count: 9 -> 3\nedge_count: 1 -> 2\n") {
+		t.Errorf("dry run output = %q", out.String())
+	}
+	if graph, _ := loader.LoadGraph(); graph.Metadata.NodeCount != 9 {
+		t.Error("dry run rewrote the graph")
+	}
+
+	if err := a.runCommand(loader, "fix-metadata", nil); err != nil {
+		t.Fatalf("fix-metadata: %v", err)
+	}
+	graph, err := loader.LoadGraph()
+	if err != nil || graph.Metadata.NodeCount != 3 || graph.Metadata.EdgeCount != 2 || len(graph.Edges) != 2 {
+		t.Errorf("rewritten graph = %+v, %v", graph, err)
+	}
+}
+
+func TestRewritesRefuseNormalizedGraph(t *testing.T) {
+	a, _, _ := testApp()
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, `{"packs":[{"id":"A"},{"id":"B"}]}`)
+	writeFile(t, dir, GraphFile, `{"metadata":{"node_count":9,"edge_count":2},"edges":[{"source":"A","target":"B","type":"related"},{"source":"B","target":"A","type":"related"}]}`)
+	loader := &Loader{BasePath: dir, SymmetricTypes: []string{"related"}}
+
+	for _, args := range [][]string{{"fix-metadata"}, {"merge-related"}, {"validate", "-fix"}} {
+		if err := a.runCommand(loader, args[0], args[1:]); err == nil || !strings.Contains(err.Error(), "-normalize-symmetric") {
+			t.Errorf("%v with SymmetricTypes: err = %v, want a refusal", args, err)
+		}
+	}
+}
+
+func TestRewritesNeedPlainDirectory(t *testing.T) {
+	a, _, _ := testApp()
+	index := `{"packs":[{"id":"A"},{"id":"B"}]}`
+	graph := `{"metadata":{"node_count":2,"edge_count":1},"edges":[{"source":"A","target":"B","type":"related"}]}`
+
+	gzipped := t.TempDir()
+	writeFile(t, gzipped, IndexFile, index)
+	writeFile(t, gzipped, GraphFile+".gz", string(gzipBytes(t, graph)))
+	bundle := filepath.Join(t.TempDir(), "dist.json")
+	if err := os.WriteFile(bundle, []byte(`{"index":`+index+`,"graph":`+graph+`}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	memory := fstest.MapFS{IndexFile: {Data: []byte(index)}, GraphFile: {Data: []byte(graph)}}
+
+	for name, loader := range map[string]*Loader{
+		"gzipped":   NewLoader(gzipped),
+		"bundle":    NewLoader(bundle),
+		"in-memory": {FS: memory},
+	} {
+		if err := a.runCommand(loader, "fix-metadata", nil); err == nil || !strings.Contains(err.Error(), "fix-metadata ") {
+			t.Errorf("%s: fix-metadata err = %v, want a refusal", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(gzipped, GraphFile)); err == nil {
+		t.Error("fix-metadata wrote a plain graph.json next to graph.json.gz")
+	}
+}
+
+func TestWriteJSONFileKeepsOldOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, GraphFile, "old")
+	path := filepath.Join(dir, GraphFile)
+
+	err := writeJSONFile(path, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errors.New("disk full")
+	})
+	if err == nil {
+		t.Fatal("writeJSONFile: want the write error")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("after a failed write the file holds %q, want the old contents", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary file left behind: %v", entries)
+	}
+}
+
+func TestMergeRelated(t *testing.T) {
+	a, _, errOut := testApp()
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, `{"metadata":{"pack_count":3},"packs":[{"id":"A","related":["B","C"]},{"id":"B","related":["Z"]},{"id":"C"}]}`)
+	writeFile(t, dir, GraphFile, `{"metadata":{"version":"1.0.0","node_count":2,"edge_count":1},"edges":[{"source":"A","target":"B","type":"related"}]}`)
+	loader := NewLoader(dir)
+
+	if err := a.runCommand(loader, "merge-related", nil); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(errOut.String(), "+ A -> C (related)\n1 edges to add, 1 already present.\nDry run;") {
+		t.Errorf("dry run output = %q", errOut.String())
+	}
+	if graph, _ := loader.LoadGraph(); len(graph.Edges) != 1 {
+		t.Fatal("dry run rewrote the graph")
+	}
+
+	if err := a.runCommand(loader, "merge-related", []string{"-apply"}); err != nil {
+		t.Fatalf("merge-related -apply: %v", err)
+	}
+	graph, err := loader.LoadGraph()
+	if err != nil || len(graph.Edges) != 2 || graph.Metadata.NodeCount != 3 || graph.Metadata.EdgeCount != 2 {
+		t.Errorf("merged graph = %+v, %v", graph, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, GraphFile))
+	if !strings.Contains(string(data), `"version": "1.0.0"`) {
+		t.Errorf("rewrite dropped fields it does not model:\n%s", data)
+	}
+}
+
+func TestBackfillApply(t *testing.T) {
+	a, out, errOut := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","related":["B","B"]},{"id":"B"}]}`)},
+	}}
+	path := filepath.Join(t.TempDir(), "graph.json")
+
+	if err := a.runCommand(loader, "backfill", []string{"-out", path}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(errOut.String(), "+ A -> B (related)\n1 edges to add, 1 already present.\nDry run;") {
+		t.Errorf("dry run output = %q", errOut.String())
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("dry run wrote the graph")
+	}
+
+	if err := a.runCommand(loader, "backfill", []string{"-out", path, "-apply"}); err != nil {
+		t.Fatalf("backfill -apply: %v", err)
+	}
+	graph, err := NewLoader(filepath.Dir(path)).LoadGraph()
+	if err != nil || len(graph.Edges) != 1 || graph.Metadata.EdgeCount != 1 {
+		t.Errorf("written graph = %+v, %v", graph, err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("backfill -out wrote to stdout: %q", out.String())
+	}
+}
+
+func TestValidateFailFast(t *testing.T) {
+	a, out, _ := testApp()
+	a.Output = jsonFormatter{out}
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"metadata":{"pack_count":1},"packs":[{"id":"A","disclosure_tier":"draft"}]}`)},
+		GraphFile: {Data: []byte(`{"metadata":{"node_count":2,"edge_count":1},"edges":[{"source":"A","target":"Z","type":"related"}]}`)},
+	}}
+
+	run := func(args ...string) validateResult {
+		t.Helper()
+		out.Reset()
+		if err := a.runCommand(loader, "validate", args); err == nil {
+			t.Fatalf("validate %v: want the problems reported as an error", args)
+		}
+		var result validateResult
+		if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+			t.Fatalf("decoding %q: %v", out.String(), err)
+		}
+		return result
+	}
+	if got := run(); len(got.Problems) != 2 {
+		t.Errorf("default: %v, want the dangling edge and the tier", got.Problems)
+	}
+	if got := run("-fail-fast"); len(got.Problems) != 1 || !strings.Contains(got.Problems[0], "unknown target") {
+		t.Errorf("-fail-fast: %v, want only the dangling edge", got.Problems)
+	}
+	if got := run("-checks=tiers"); len(got.Problems) != 1 || !strings.Contains(got.Problems[0], "disclosure tier") {
+		t.Errorf("-checks=tiers: %v, want only the tier", got.Problems)
+	}
+	if err := a.runCommand(loader, "validate", []string{"-checks=bogus"}); err == nil || !strings.Contains(err.Error(), `unknown check "bogus"`) {
+		t.Errorf("unknown check: err = %v", err)
+	}
+}
+
+func TestValidateTierAsymmetryWarning(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"metadata":{"pack_count":2},"packs":[{"id":"A","disclosure_tier":"public"},{"id":"B","disclosure_tier":"restricted"}]}`)},
+		GraphFile: {Data: []byte(`{"metadata":{"node_count":2,"edge_count":1},"edges":[{"source":"A","target":"B","type":"related"}]}`)},
+	}}
+
+	if err := a.runCommand(loader, "validate", nil); err != nil {
+		t.Fatalf("validate: %v (an asymmetry should only warn)", err)
+	}
+	if !strings.Contains(out.String(), "warning: edge A -> B (related) joins public and restricted packs") {
+		t.Errorf("output = %q, want the asymmetry warning", out.String())
+	}
+}
+
+func TestValidateRequireReciprocal(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"metadata":{"pack_count":2},"packs":[{"id":"A","disclosure_tier":"public","related":["B"]},{"id":"B","disclosure_tier":"public"}]}`)},
+		GraphFile: {Data: []byte(`{"metadata":{"node_count":2,"edge_count":1},"edges":[{"source":"A","target":"B","type":"related"}]}`)},
+	}}
+	const msg = "pack A lists B in related, but B does not list A"
+
+	if err := a.runCommand(loader, "validate", nil); err != nil {
+		t.Fatalf("validate: %v (a one-way related ID should only warn)", err)
+	}
+	if !strings.Contains(out.String(), "warning: "+msg) {
+		t.Errorf("output = %q, want the reciprocity warning", out.String())
+	}
+
+	out.Reset()
+	if err := a.runCommand(loader, "validate", []string{"-require-reciprocal"}); err == nil {
+		t.Error("validate -require-reciprocal passed a one-way related ID")
+	}
+	if !strings.Contains(out.String(), msg) || strings.Contains(out.String(), "warning: "+msg) {
+		t.Errorf("output = %q, want the reciprocity problem", out.String())
+	}
+}
+
+func TestValidateCacheFile(t *testing.T) {
+	a, out, _ := testApp()
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, `{"metadata":{"pack_count":1},"packs":[{"id":"A","disclosure_tier":"public"}]}`)
+	writeFile(t, dir, GraphFile, `{"metadata":{"node_count":0,"edge_count":0},"edges":[]}`)
+	loader := NewLoader(dir)
+	cache := filepath.Join(t.TempDir(), "validate.json")
+	run := func(args ...string) error {
+		out.Reset()
+		return a.runCommand(loader, "validate", append([]string{"-cache-file=" + cache}, args...))
+	}
+
+	if err := run(); err != nil || out.String() != "OK: no problems found.\n" {
+		t.Fatalf("first run: err = %v, output %q", err, out.String())
+	}
+	if err := run(); err != nil || out.String() != "unchanged, OK\n" {
+		t.Errorf("unchanged run: err = %v, output %q, want the cached result", err, out.String())
+	}
+	if err := run("-force"); err != nil || out.String() != "OK: no problems found.\n" {
+		t.Errorf("-force: err = %v, output %q, want a full run", err, out.String())
+	}
+	if err := run("-checks=tiers"); err != nil || out.String() != "OK: no problems found.\n" {
+		t.Errorf("changed options: output %q, want a full run", out.String())
+	}
+
+	// A changed count is a problem, and the failure is cached too
+	writeFile(t, dir, IndexFile, `{"metadata":{"pack_count":2},"packs":[{"id":"A","disclosure_tier":"public"}]}`)
+	if err := run(); err == nil {
+		t.Fatal("changed dataset: want the pack-count problem")
+	}
+	if err := run(); err == nil || !strings.HasPrefix(out.String(), "unchanged since the last validation:\n  - ") {
+		t.Errorf("cached failure: err = %v, output %q", err, out.String())
+	}
+
+	if err := a.runCommand(loader, "validate", []string{"-cache-file=" + cache, "-fix"}); err == nil {
+		t.Error("-cache-file with -fix: want a usage error")
+	}
+}
+
+func TestValidateCacheHitSkipsValidate(t *testing.T) {
+	a, _, _ := testApp()
+	var logs bytes.Buffer
+	logger, _ := newLogger(&logs, 1, LogFormatText)
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, `{"metadata":{"pack_count":1},"packs":[{"id":"A","disclosure_tier":"public"}]}`)
+	writeFile(t, dir, GraphFile, `{"metadata":{"node_count":0,"edge_count":0},"edges":[]}`)
+	loader := &Loader{BasePath: dir, Logger: logger}
+	args := []string{"validate", "-cache-file=" + filepath.Join(t.TempDir(), "validate.json")}
+
+	if err := a.run(loader, args); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if n := strings.Count(logs.String(), "validated dataset"); n != 1 {
+		t.Errorf("first run validated %d times, want once:\n%s", n, logs.String())
+	}
+	logs.Reset()
+	if err := a.run(loader, args); err != nil {
+		t.Fatalf("cached run: %v", err)
+	}
+	if strings.Contains(logs.String(), "validated dataset") || !strings.Contains(logs.String(), "skipped validation") {
+		t.Errorf("cache hit ran Validate:\n%s", logs.String())
+	}
+}
+
+func TestValidateFix(t *testing.T) {
+	a, _, errOut := testApp()
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, `{"metadata":{"pack_count":2},"packs":[{"id":"A","disclosure_tier":"public"},{"id":"B","disclosure_tier":"public"}]}`)
+	writeFile(t, dir, GraphFile, `{"metadata":{"version":"1.0.0","node_count":3,"edge_count":2},"nodes":[{"id":"A"}],`+
+		`"edges":[{"source":"A","target":"B","type":"related"},{"source":"A","target":"Z","type":"related"}]}`)
+	loader := NewLoader(dir)
+
+	if err := a.runCommand(loader, "validate", []string{"-fix"}); err == nil {
+		t.Error("dry run should still report the dangling edge")
+	}
+	if !strings.Contains(errOut.String(), "Removing dangling edge A -> Z (related)\nDry run;") {
+		t.Errorf("dry run output = %q", errOut.String())
+	}
+	if graph, _ := loader.LoadGraph(); len(graph.Edges) != 2 {
+		t.Fatal("dry run rewrote the graph")
+	}
+
+	if err := a.runCommand(loader, "validate", []string{"-fix", "-yes"}); err != nil {
+		t.Errorf("validate after fixing: %v", err)
+	}
+	graph, err := loader.LoadGraph()
+	if err != nil || len(graph.Edges) != 1 || graph.Metadata.NodeCount != 2 || graph.Metadata.EdgeCount != 1 {
+		t.Errorf("fixed graph = %+v, %v", graph, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, GraphFile))
+	if !strings.Contains(string(data), `"version": "1.0.0"`) || !strings.Contains(string(data), `"nodes": [`) {
+		t.Errorf("rewrite dropped fields it does not model:\n%s", data)
+	}
+}
+
+func TestNeighborsFilters(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{IncludeRelated: true, FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha","disclosure_tier":"public","related":["D"]},` +
+			`{"id":"B","title":"Beta","disclosure_tier":"internal"},{"id":"C","title":"Gamma","disclosure_tier":"public"},` +
+			`{"id":"D","title":"Delta","disclosure_tier":"public"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"related"},{"source":"A","target":"C","type":"child"}]}`)},
+	}}
+
+	run := func(args ...string) neighborsResult {
+		t.Helper()
+		out.Reset()
+		a.Output = jsonFormatter{out}
+		if err := a.runCommand(loader, "neighbors", append(args, "A")); err != nil {
+			t.Fatalf("neighbors %v: %v", args, err)
+		}
+		var result neighborsResult
+		if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	ids := func(r neighborsResult) []string {
+		var ids []string
+		for _, n := range r.Neighbors {
+			ids = append(ids, n.ID)
+		}
+		return ids
+	}
+
+	// D is a neighbor only through A's related list
+	if got := run("-exclude-tier=internal"); !reflect.DeepEqual(ids(got), []string{"C", "D"}) || got.Total != 2 {
+		t.Errorf("-exclude-tier=internal = %v (total %d), want [C D]", ids(got), got.Total)
+	}
+	if got := ids(run("-type=related", "-exclude-tier", "internal")); !reflect.DeepEqual(got, []string{"D"}) {
+		t.Errorf("-type=related -exclude-tier=internal = %v, want [D]", got)
+	}
+	if got := ids(run("-exclude-tier=internal", "-exclude-tier=public")); got != nil {
+		t.Errorf("excluding both tiers = %v, want none", got)
+	}
+
+	defer func(limit int) { neighborLimit = limit }(neighborLimit)
+	neighborLimit = 1
+	if got := run(); len(got.Neighbors) != 1 || got.Total != 3 {
+		t.Errorf("limit 1 = %v (total %d), want one of 3", ids(got), got.Total)
+	}
+}
+
+func TestBatchPath(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"related"},{"source":"B","target":"C","type":"related"}]}`)},
+	}}
+
+	a.In = strings.NewReader("from,target\nA,C\n C , A\nA,Z\n")
+	if err := a.runCommand(loader, "batch-path", nil); err != nil {
+		t.Fatalf("batch-path: %v", err)
+	}
+	want := "  A -> C: 2 hops: A, B, C\n  C -> A: 2 hops: C, B, A\n  A -> Z: no path\n2 of 3 pairs connected.\n"
+	if out.String() != want {
+		t.Errorf("batch-path output = %q, want %q", out.String(), want)
+	}
+
+	a.In = strings.NewReader("A,B,C\n")
+	if err := a.runCommand(loader, "batch-path", nil); err == nil {
+		t.Error("batch-path with three columns: want error")
+	}
+}
+
+func TestFilterFlag(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha","disclosure_tier":"public"},{"id":"B","title":"Beta","disclosure_tier":"public"}]}`)},
+	}}
+	pred, err := ParseFilter("title~bet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	packFilter = pred
+	defer func() { packFilter = nil }()
+
+	if err := a.runCommand(loader, "search", []string{"a"}); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if want := "  - B: Beta\n1 matches for \"a\".\n"; out.String() != want {
+		t.Errorf("search output = %q, want %q", out.String(), want)
+	}
+
+	a.Output = jsonFormatter{out}
+	incomplete := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha"},{"id":"B","title":"Beta"}]}`)},
+	}}
+	out.Reset()
+	if err := a.runCommand(incomplete, "incomplete", nil); err != nil {
+		t.Fatalf("incomplete: %v", err)
+	}
+	var result incompleteResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil || len(result.Packs) != 1 || result.Packs[0].Position != 2 {
+		t.Errorf("incomplete = %s, want only B at position 2", out.String())
+	}
+
+	if err := a.run(loader, []string{"health"}); err == nil || !strings.Contains(err.Error(), "-filter does not apply to health") {
+		t.Errorf("health with -filter: err = %v, want a refusal", err)
+	}
+}
+
+func TestStale(t *testing.T) {
+	recent := time.Now().Add(-24 * time.Hour).Format(time.RFC3339)
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[` +
+			`{"id":"A","title":"Alpha","disclosure_tier":"public","updated_at":"2025-06-01T00:00:00Z"},` +
+			`{"id":"B","title":"Beta","disclosure_tier":"public","updated_at":"` + recent + `"},` +
+			`{"id":"C","title":"Gamma","disclosure_tier":"public","updated_at":"2024-01-15T00:00:00Z"},` +
+			`{"id":"D","title":"Delta","disclosure_tier":"public"}]}`)},
+	}}
+
+	a, out, _ := testApp()
+	if err := a.runCommand(loader, "stale", []string{"-older-than=30d"}); err != nil {
+		t.Fatalf("stale: %v", err)
+	}
+	want := "Packs not updated in 30d (2):\n" +
+		"  - 2024-01-15  C: Gamma\n" +
+		"  - 2025-06-01  A: Alpha\n" +
+		"Unknown age (1):\n" +
+		"  - D: Delta\n"
+	if out.String() != want {
+		t.Errorf("stale output = %q, want %q", out.String(), want)
+	}
+
+	a, out, _ = testApp()
+	if err := a.runCommand(loader, "stale", []string{"-older-than=1h"}); err != nil {
+		t.Fatalf("stale -older-than=1h: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Packs not updated in 1h (3):\n") {
+		t.Errorf("stale -older-than=1h output = %q", out.String())
+	}
+
+	for _, age := range []string{"soon", "-2d", "1.5w"} {
+		a, _, _ = testApp()
+		if err := a.runCommand(loader, "stale", []string{"-older-than=" + age}); err == nil {
+			t.Errorf("-older-than=%s: got no error", age)
+		}
+	}
+}
+
+func TestImpact(t *testing.T) {
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha","related":["B"]},{"id":"B","title":"Beta"},{"id":"C","title":"Gamma"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"related"},{"source":"B","target":"C","type":"child"}]}`)},
+	}}
+
+	a, out, _ := testApp()
+	if err := a.runCommand(loader, "impact", []string{"B"}); err != nil {
+		t.Fatalf("impact: %v", err)
+	}
+	want := "Deleting B: Beta\n" +
+		"Edges dropped (2):\n" +
+		"  - A -> B (related)\n" +
+		"  - B -> C (child)\n" +
+		"New orphans (2):\n" +
+		"  - A\n" +
+		"  - C\n" +
+		"Broken related references (1):\n" +
+		"  - A\n" +
+		"Components: 1 before, 2 after (splits a component)\n"
+	if out.String() != want {
+		t.Errorf("impact output = %q, want %q", out.String(), want)
+	}
+
+	a, _, _ = testApp()
+	if err := a.runCommand(loader, "impact", []string{"Z"}); !errors.Is(err, ErrPackNotFound) {
+		t.Errorf("impact Z: got %v, want ErrPackNotFound", err)
+	}
+}
+
+func TestReach(t *testing.T) {
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha"},{"id":"B","title":"Beta"},{"id":"C","title":"Gamma"},{"id":"D","title":"Delta"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"child"},{"source":"B","target":"C","type":"child"},` +
+			`{"source":"B","target":"D","type":"child"},{"source":"D","target":"A","type":"related"}]}`)},
+	}}
+
+	a, out, _ := testApp()
+	if err := a.runCommand(loader, "reach", nil); err != nil {
+		t.Fatalf("reach: %v", err)
+	}
+	// -limit defaults to 3, leaving out C, which reaches nothing
+	want := "Top packs by outgoing reach (3):\n" +
+		"     3  (1 out)  A: Alpha\n" +
+		"     3  (2 out)  B: Beta\n" +
+		"     3  (1 out)  D: Delta\n"
+	if out.String() != want {
+		t.Errorf("reach output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestReachableTypeAndExcludeTier(t *testing.T) {
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha","disclosure_tier":"public"},{"id":"B","title":"Beta","disclosure_tier":"internal"},` +
+			`{"id":"C","title":"Gamma","disclosure_tier":"public"},{"id":"D","title":"Delta","disclosure_tier":"public"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"child"},{"source":"B","target":"C","type":"child"},` +
+			`{"source":"A","target":"D","type":"related"}]}`)},
+	}}
+
+	a, out, _ := testApp()
+	a.Output = jsonFormatter{out}
+	if err := a.runCommand(loader, "reachable", []string{"-type=child", "-exclude-tier=internal", "A"}); err != nil {
+		t.Fatalf("reachable: %v", err)
+	}
+	var result reachableResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	// B is hidden but still traversed to reach C; D is only related
+	if got := packIDs(result.Packs); !reflect.DeepEqual(got, []string{"A", "C"}) || result.Type != "child" {
+		t.Errorf("reachable = %v (type %q), want [A C] via child", got, result.Type)
+	}
+}
+
+func TestSPT(t *testing.T) {
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha"},{"id":"B","title":"Beta"},{"id":"C","title":"Gamma"},{"id":"D","title":"Delta"},{"id":"E","title":"Epsilon"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"C","type":"child"},{"source":"A","target":"B","type":"child"},` +
+			`{"source":"C","target":"D","type":"child"},{"source":"B","target":"D","type":"related"}]}`)},
+	}}
+
+	a, out, _ := testApp()
+	if err := a.runCommand(loader, "spt", []string{"A"}); err != nil {
+		t.Fatalf("spt: %v", err)
+	}
+	// D is reached from C first, in edge order; E is unreachable
+	want := "A: Alpha\n" +
+		"  B: Beta\n" +
+		"  C: Gamma\n" +
+		"    D: Delta\n"
+	if out.String() != want {
+		t.Errorf("spt output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha","disclosure_tier":"public"},` +
+			`{"id":"B","title":"","disclosure_tier":"restricted"},{"title":"Nameless"}]}`)},
+	}}
+
+	a, out, _ := testApp()
+	if err := a.runCommand(loader, "incomplete", nil); err != nil {
+		t.Fatalf("incomplete: %v", err)
+	}
+	want := "Incomplete packs (2):\n" +
+		"  - B: missing title\n" +
+		"  - pack #3 (no id): missing id, disclosure_tier\n"
+	if out.String() != want {
+		t.Errorf("incomplete output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestWithTitles(t *testing.T) {
+	withTitles = true
+	defer func() { withTitles = false }()
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha","disclosure_tier":"public"},{"id":"B","title":"Beta","disclosure_tier":"public"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"depends_on"},{"source":"B","target":"A","type":"depends_on"}]}`)},
+	}}
+
+	if err := a.runCommand(loader, "path", []string{"A", "B"}); err != nil {
+		t.Fatalf("path: %v", err)
+	}
+	if want := "Path from A to B (1 hops):\n  A (Alpha)\n  → depends_on: B (Beta)\n"; out.String() != want {
+		t.Errorf("path output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := a.runCommand(loader, "cycles", []string{"-type=depends_on"}); err == nil {
+		t.Fatal("cycles succeeded, want the cycle reported as a failure")
+	}
+	if want := "  - A (Alpha) -> B (Beta) -> A (Alpha)\n"; out.String() != want {
+		t.Errorf("cycles output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestRewritesKeepAliasedIDs(t *testing.T) {
+	a, _, _ := testApp()
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, `{"metadata":{"pack_count":3},"packs":[{"id":"A","disclosure_tier":"public","related":["C"]},`+
+		`{"id":"B","disclosure_tier":"public"},{"id":"C","disclosure_tier":"public"}]}`)
+	writeFile(t, dir, GraphFile, `{"metadata":{"node_count":9,"edge_count":3},"edges":[{"source":"old","target":"A","type":"child"},`+
+		`{"source":"A","target":"Z","type":"child"},{"source":"B","target":"old","type":"child"}]}`)
+	writeFile(t, dir, AliasesFile, `{"old":"B"}`)
+	loader := NewLoader(dir)
+	source := func() []GraphEdge {
+		t.Helper()
+		graph, err := LoadGraphFS(os.DirFS(dir), GraphFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return graph.Edges
+	}
+
+	if err := a.runCommand(loader, "fix-metadata", nil); err != nil {
+		t.Fatalf("fix-metadata: %v", err)
+	}
+	graph, _ := LoadGraphFS(os.DirFS(dir), GraphFile)
+	if graph.Metadata.NodeCount != 4 || graph.Edges[0].Source != "old" {
+		t.Errorf("fix-metadata wrote %+v, want the file's IDs and their count", graph)
+	}
+
+	a.runCommand(loader, "validate", []string{"-fix", "-yes"})
+	want := []GraphEdge{{Source: "old", Target: "A", Type: "child"}, {Source: "B", Target: "old", Type: "child"}}
+	if got := source(); !reflect.DeepEqual(got, want) {
+		t.Errorf("validate -fix wrote %v, want %v", got, want)
+	}
+
+	if err := a.runCommand(loader, "merge-related", []string{"-apply"}); err != nil {
+		t.Fatalf("merge-related: %v", err)
+	}
+	want = append(want, GraphEdge{Source: "A", Target: "C", Type: "related"})
+	if got := source(); !reflect.DeepEqual(got, want) {
+		t.Errorf("merge-related wrote %v, want %v", got, want)
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha"},{"id":"B","title":"Beta"},{"id":"C","title":"Gamma"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"related"},{"source":"B","target":"C","type":"related"}]}`)},
+	}}
+
+	for _, n := range []string{"-n=0", "-n=-1"} {
+		if err := a.runCommand(loader, "recommend", []string{n, "A"}); err == nil || !strings.HasPrefix(err.Error(), "usage:") {
+			t.Errorf("recommend %s: err = %v, want a usage error", n, err)
+		}
+	}
+	if err := a.runCommand(loader, "recommend", []string{"-n=1", "-seed=1", "A"}); err != nil {
+		t.Fatalf("recommend -n=1: %v", err)
+	}
+	if !strings.Contains(out.String(), "  - B: Beta\n") {
+		t.Errorf("recommend output = %q, want B", out.String())
+	}
+}
+
+func TestCommandsRejectExtraArgs(t *testing.T) {
+	a, _, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","disclosure_tier":"public"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[]}`)},
+	}}
+	for _, name := range []string{"orphans", "tiers", "tags", "central", "stats", "hash", "leaves", "components", "cycles", "topo", "rank", "edges"} {
+		err := a.runCommand(loader, name, []string{"extra-junk"})
+		if err == nil || !strings.HasPrefix(err.Error(), "usage: "+name) {
+			t.Errorf("%s extra-junk: err = %v, want a usage error", name, err)
+		}
+	}
+}
+
+func TestDemoRejectsEmptyTier(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{IndexFile: {Data: []byte(`{"packs":[{"id":"A","disclosure_tier":"public"}]}`)}}}
+	defer func(tier string) { *tierFlag = tier }(*tierFlag)
+
+	*tierFlag = " , "
+	if err := a.runCommand(loader, "demo", nil); err == nil || !strings.HasPrefix(err.Error(), "usage:") {
+		t.Errorf("empty -tier: err = %v, want a usage error", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("empty -tier printed %q", out.String())
+	}
+	*tierFlag = "public"
+	if err := a.runCommand(loader, "demo", nil); err != nil || !strings.Contains(out.String(), "Public tier packs (1):") {
+		t.Errorf("demo = %v, output %q", err, out.String())
+	}
+}
+
+func TestDemoRejectsUnknownSort(t *testing.T) {
+	a, _, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{IndexFile: {Data: []byte(`{"packs":[{"id":"A","disclosure_tier":"public"}]}`)}}}
+	defer func(tier, sort string) { *tierFlag, *sortFlag = tier, sort }(*tierFlag, *sortFlag)
+
+	*tierFlag, *sortFlag = "public", "bogus"
+	if err := a.runCommand(loader, "demo", nil); err == nil || !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("err = %v, want the unknown sort key", err)
+	}
+}
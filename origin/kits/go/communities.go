@@ -0,0 +1,126 @@
+// ORIGIN Go Kit - community detection
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+)
+
+// Communities assigns each node a community ID by label propagation, with
+// seed 0; see CommunitiesSeed
+func (g Graph) Communities(iterations int) map[string]int {
+	return g.CommunitiesSeed(iterations, 0)
+}
+
+// CommunitiesSeed assigns each node a community ID by label propagation.
+// Every node starts in its own community; each pass visits the nodes in an
+// order shuffled by seed and moves each to the community most common among
+// its neighbors, ignoring edge direction and type, with ties broken at
+// random unless the node's own community is among them. It stops after
+// iterations passes or once a pass changes nothing. The same seed always
+// gives the same result. IDs are numbered from 0 by community size,
+// largest first, then by smallest member ID.
+func (g Graph) CommunitiesSeed(iterations int, seed int64) map[string]int {
+	ids, pos := sortedNodes(g.BuildAdjacency())
+	neighbors := make([][]int, len(ids))
+	for _, edge := range g.Edges {
+		s, t := pos[edge.Source], pos[edge.Target]
+		if s != t {
+			neighbors[s] = append(neighbors[s], t)
+			neighbors[t] = append(neighbors[t], s)
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	label := make([]int, len(ids))
+	order := make([]int, len(ids))
+	for i := range ids {
+		label[i], order[i] = i, i
+	}
+	counts := make(map[int]int)
+	var best []int
+	for range iterations {
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		changed := false
+		for _, node := range order {
+			if len(neighbors[node]) == 0 {
+				continue
+			}
+			clear(counts)
+			top := 0
+			for _, n := range neighbors[node] {
+				counts[label[n]]++
+				top = max(top, counts[label[n]])
+			}
+			if counts[label[node]] == top {
+				continue
+			}
+			best = best[:0]
+			for l, c := range counts {
+				if c == top {
+					best = append(best, l)
+				}
+			}
+			// Map order is random, so sort before drawing from the seed
+			slices.Sort(best)
+			label[node] = best[rng.IntN(len(best))]
+			changed = true
+		}
+		if !changed {
+			break
+		}
+	}
+	return numberCommunities(ids, label)
+}
+
+// numberCommunities renumbers labels, indexed by position in the sorted
+// ids, from 0 by community size, largest first, then by smallest member
+func numberCommunities(ids []string, label []int) map[string]int {
+	type group struct{ first, size int }
+	groups := make(map[int]*group)
+	for i, l := range label {
+		if g, ok := groups[l]; ok {
+			g.size++
+		} else {
+			// ids are sorted, so the first member seen is the smallest
+			groups[l] = &group{first: i, size: 1}
+		}
+	}
+	labels := make([]int, 0, len(groups))
+	for l := range groups {
+		labels = append(labels, l)
+	}
+	slices.SortFunc(labels, func(a, b int) int {
+		return cmp.Or(cmp.Compare(groups[b].size, groups[a].size), cmp.Compare(groups[a].first, groups[b].first))
+	})
+	number := make(map[int]int, len(labels))
+	for n, l := range labels {
+		number[l] = n
+	}
+
+	communities := make(map[string]int, len(ids))
+	for i, id := range ids {
+		communities[id] = number[label[i]]
+	}
+	return communities
+}
+
+// CommunityMembers groups the nodes of communities by community ID, each
+// group sorted
+func CommunityMembers(communities map[string]int) [][]string {
+	groups := [][]string{}
+	for id, c := range communities {
+		for len(groups) <= c {
+			groups = append(groups, nil)
+		}
+		groups[c] = append(groups[c], id)
+	}
+	for _, members := range groups {
+		slices.Sort(members)
+	}
+	return groups
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// twoCliques returns two four-node cliques, a1..a4 and b1..b4, joined by
+// the edge a1-b1, and a self-loop on z
+func twoCliques() Graph {
+	var g Graph
+	for _, side := range []string{"a", "b"} {
+		for i := 1; i <= 4; i++ {
+			for j := i + 1; j <= 4; j++ {
+				g.Edges = append(g.Edges, GraphEdge{Source: fmt.Sprint(side, i), Target: fmt.Sprint(side, j), Type: "related"})
+			}
+		}
+	}
+	g.Edges = append(g.Edges,
+		GraphEdge{Source: "a1", Target: "b1", Type: "child"},
+		GraphEdge{Source: "z", Target: "z", Type: "related"})
+	return g
+}
+
+func TestCommunities(t *testing.T) {
+	g := twoCliques()
+	// Label propagation can also merge the cliques across the bridge; these
+	// seeds split them
+	for _, seed := range []int64{0, 2, 4} {
+		got := CommunityMembers(g.CommunitiesSeed(20, seed))
+		want := [][]string{{"a1", "a2", "a3", "a4"}, {"b1", "b2", "b3", "b4"}, {"z"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("seed %d: communities = %v, want %v", seed, got, want)
+		}
+	}
+
+	if !maps.Equal(g.Communities(20), g.CommunitiesSeed(20, 0)) {
+		t.Error("Communities should use seed 0")
+	}
+	rng := randomGraph(rand.New(rand.NewPCG(7, 8)), 60, 150)
+	if !maps.Equal(rng.CommunitiesSeed(20, 3), rng.CommunitiesSeed(20, 3)) {
+		t.Error("the same seed gave different communities")
+	}
+
+	zero := g.CommunitiesSeed(0, 1)
+	if len(zero) != 9 || zero["a1"] == zero["a2"] {
+		t.Errorf("no passes: %v, want every node alone", zero)
+	}
+	if got := (Graph{}).Communities(10); len(got) != 0 {
+		t.Errorf("empty graph: %v", got)
+	}
+}
+
+func TestCommunitiesCommand(t *testing.T) {
+	a, out, _ := testApp()
+	index := `{"packs":[{"id":"a1","title":"First"},{"id":"a2","title":"Second"}]}`
+	var edges []string
+	for _, e := range twoCliques().Edges {
+		edges = append(edges, fmt.Sprintf(`{"source":%q,"target":%q,"type":%q}`, e.Source, e.Target, e.Type))
+	}
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(index)},
+		GraphFile: {Data: []byte(`{"edges":[` + strings.Join(edges, ",") + `]}`)},
+	}}
+
+	if err := a.runCommand(loader, "communities", []string{"-seed", "4", "-sample", "2"}); err != nil {
+		t.Fatalf("communities: %v", err)
+	}
+	want := "  0: 4 packs: First, Second, ...\n  1: 4 packs: b1, b2, ...\n  2: 1 packs: z\n3 communities (seed 4).\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
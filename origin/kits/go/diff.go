@@ -0,0 +1,101 @@
+// ORIGIN Go Kit - dist snapshot comparison
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"cmp"
+	"slices"
+)
+
+// PackChange is a pack whose title or tier differs between snapshots
+type PackChange struct {
+	ID  string `json:"id"`
+	Old Pack   `json:"old"`
+	New Pack   `json:"new"`
+}
+
+// IndexDiff lists the pack differences between two indexes, each sorted
+// by ID
+type IndexDiff struct {
+	Added   []Pack       `json:"added"`
+	Removed []Pack       `json:"removed"`
+	Changed []PackChange `json:"changed"`
+}
+
+// GraphDiff lists the edges present in only one of two graphs, sorted by
+// source, target and type
+type GraphDiff struct {
+	Added   []GraphEdge `json:"added"`
+	Removed []GraphEdge `json:"removed"`
+}
+
+// DiffIndexes compares packs by ID, reporting packs only in new as added,
+// packs only in old as removed, and packs whose title or disclosure tier
+// changed
+func DiffIndexes(old, new PacksIndex) IndexDiff {
+	oldByID, newByID := old.ByID(), new.ByID()
+	diff := IndexDiff{Added: []Pack{}, Removed: []Pack{}, Changed: []PackChange{}}
+
+	for id, p := range newByID {
+		before, ok := oldByID[id]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, p)
+		case before.Title != p.Title || before.DisclosureTier != p.DisclosureTier:
+			diff.Changed = append(diff.Changed, PackChange{ID: id, Old: before, New: p})
+		}
+	}
+	for id, p := range oldByID {
+		if _, ok := newByID[id]; !ok {
+			diff.Removed = append(diff.Removed, p)
+		}
+	}
+
+	byPackID := func(a, b Pack) int { return cmp.Compare(a.ID, b.ID) }
+	slices.SortFunc(diff.Added, byPackID)
+	slices.SortFunc(diff.Removed, byPackID)
+	slices.SortFunc(diff.Changed, func(a, b PackChange) int { return cmp.Compare(a.ID, b.ID) })
+	return diff
+}
+
+// compareEdges orders edges by source, then target, then type
+func compareEdges(a, b GraphEdge) int {
+	return cmp.Or(cmp.Compare(a.Source, b.Source), cmp.Compare(a.Target, b.Target), cmp.Compare(a.Type, b.Type))
+}
+
+// compareEdgeValues extends compareEdges with weight, then attributes, so
+// edges that differ in any field have a fixed order
+func compareEdgeValues(a, b GraphEdge) int {
+	return cmp.Or(compareEdges(a, b), cmp.Compare(a.Weight, b.Weight), cmp.Compare(a.Attributes.encoded, b.Attributes.encoded))
+}
+
+// DiffGraphs reports edges only in new as added and edges only in old as
+// removed. Edges are compared by value, so a changed type, weight or
+// attribute shows as one removal and one addition.
+func DiffGraphs(old, new Graph) GraphDiff {
+	inOld := make(map[GraphEdge]bool, len(old.Edges))
+	for _, edge := range old.Edges {
+		inOld[edge] = true
+	}
+	inNew := make(map[GraphEdge]bool, len(new.Edges))
+	for _, edge := range new.Edges {
+		inNew[edge] = true
+	}
+
+	diff := GraphDiff{Added: []GraphEdge{}, Removed: []GraphEdge{}}
+	for edge := range inNew {
+		if !inOld[edge] {
+			diff.Added = append(diff.Added, edge)
+		}
+	}
+	for edge := range inOld {
+		if !inNew[edge] {
+			diff.Removed = append(diff.Removed, edge)
+		}
+	}
+	slices.SortFunc(diff.Added, compareEdgeValues)
+	slices.SortFunc(diff.Removed, compareEdgeValues)
+	return diff
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffIndexes(t *testing.T) {
+	old := PacksIndex{Packs: samplePacks()}
+	new := PacksIndex{Packs: []Pack{
+		{ID: "A", Title: "Alpha", DisclosureTier: "public", Related: []string{"C"}},
+		{ID: "C", Title: "Gamma", DisclosureTier: "internal"},
+		{ID: "E", Title: "Epsilon"},
+		{ID: "D", Title: "Delta"},
+	}}
+
+	got := DiffIndexes(old, new)
+	want := IndexDiff{
+		Added:   []Pack{{ID: "D", Title: "Delta"}, {ID: "E", Title: "Epsilon"}},
+		Removed: []Pack{{ID: "B", Title: "Beta", DisclosureTier: "internal"}},
+		Changed: []PackChange{{
+			ID:  "C",
+			Old: Pack{ID: "C", Title: "Gamma", DisclosureTier: "public"},
+			New: Pack{ID: "C", Title: "Gamma", DisclosureTier: "internal"},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffIndexes =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if d := DiffIndexes(old, old); len(d.Added)+len(d.Removed)+len(d.Changed) != 0 {
+		t.Errorf("identical indexes: %+v", d)
+	}
+}
+
+func TestDiffGraphs(t *testing.T) {
+	old := cycleGraph()
+	new := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "C", Type: "child"},
+		{Source: "C", Target: "A", Type: "related"},
+		{Source: "C", Target: "D", Type: "child"},
+		{Source: "A", Target: "E", Type: "related"},
+	}}
+
+	got := DiffGraphs(old, new)
+	want := GraphDiff{
+		Added: []GraphEdge{
+			{Source: "A", Target: "E", Type: "related"},
+			{Source: "B", Target: "C", Type: "child"},
+		},
+		Removed: []GraphEdge{{Source: "B", Target: "C", Type: "related"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffGraphs =\n%+v\nwant\n%+v", got, want)
+	}
+
+	new.Edges[0].SetAttribute("confidence", "high")
+	got = DiffGraphs(old, new)
+	if len(got.Added) != 3 || len(got.Removed) != 2 || got.Added[0].Attributes.Map()["confidence"] != "high" {
+		t.Errorf("changed attribute: DiffGraphs =\n%+v\nwant A -> B added with it and removed without", got)
+	}
+
+	weighted := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related", Weight: 3},
+		{Source: "A", Target: "B", Type: "related", Weight: 1},
+		{Source: "A", Target: "B", Type: "related", Weight: 2},
+	}}
+	for range 5 {
+		got = DiffGraphs(Graph{}, weighted)
+		if len(got.Added) != 3 || got.Added[0].Weight != 1 || got.Added[1].Weight != 2 || got.Added[2].Weight != 3 {
+			t.Fatalf("weights only: Added = %+v, want ordered by weight", got.Added)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// ORIGIN Go Kit - embedded dist
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// embeddedFiles holds whatever was copied into dist/ at build time
+//
+//go:embed dist
+var embeddedFiles embed.FS
+
+// embeddedDist returns the dist directory compiled into the binary
+func embeddedDist() fs.FS {
+	sub, err := fs.Sub(embeddedFiles, "dist")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,238 @@
+// ORIGIN Go Kit - graph and pack exporters
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// dotEscaper escapes text for a double-quoted DOT ID
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// dotQuote returns s as a double-quoted DOT ID
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+// ToDOT writes the graph as a Graphviz digraph
+func (g Graph) ToDOT(w io.Writer) error {
+	return g.ToDOTWithAttributes(w, nil)
+}
+
+// ToDOTWithAttributes is ToDOT adding a "key=value" line to each edge
+// label for every attribute in keys the edge has, in keys order
+func (g Graph) ToDOTWithAttributes(w io.Writer, keys []string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph origin {")
+	for _, edge := range g.Edges {
+		label := edge.Type
+		for _, k := range keys {
+			if v, ok := edge.Attribute(k); ok {
+				label += "\n" + k + "=" + v
+			}
+		}
+		fmt.Fprintf(bw, "  %s -> %s [label=%s];\n",
+			dotQuote(edge.Source), dotQuote(edge.Target), dotQuote(label))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// xmlEscape returns s escaped for XML text and attribute values
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// exportNodes returns the IDs of every pack in index and every edge
+// endpoint, sorted
+func (g Graph) exportNodes(index PacksIndex) []string {
+	seen := make(map[string]bool, len(index.Packs))
+	var ids []string
+	addNode := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, p := range index.Packs {
+		addNode(p.ID)
+	}
+	for _, edge := range g.Edges {
+		addNode(edge.Source)
+		addNode(edge.Target)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ToGraphML writes the graph as GraphML for Gephi and similar tools.
+// Nodes are every pack in index plus any other edge endpoint, sorted by
+// ID, with title and tier data; edges carry their type.
+func (g Graph) ToGraphML(w io.Writer, index PacksIndex) error {
+	return g.ToGraphMLWithAttributes(w, index, nil)
+}
+
+// ToGraphMLWithAttributes is ToGraphML declaring an edge key
+// "attr.<key>" for every attribute in keys and giving each edge a data
+// element for those it has
+func (g Graph) ToGraphMLWithAttributes(w io.Writer, index PacksIndex, keys []string) error {
+	byID := index.ByID()
+	ids := g.exportNodes(index)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(bw, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(bw, `  <key id="title" for="node" attr.name="title" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="tier" for="node" attr.name="tier" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="type" for="edge" attr.name="type" attr.type="string"/>`)
+	for _, k := range keys {
+		fmt.Fprintf(bw, "  <key id=\"attr.%s\" for=\"edge\" attr.name=\"%s\" attr.type=\"string\"/>\n", xmlEscape(k), xmlEscape(k))
+	}
+	fmt.Fprintln(bw, `  <graph id="origin" edgedefault="directed">`)
+	for _, id := range ids {
+		p := byID[id]
+		fmt.Fprintf(bw, "    <node id=\"%s\"><data key=\"title\">%s</data><data key=\"tier\">%s</data></node>\n",
+			xmlEscape(id), xmlEscape(p.Title), xmlEscape(p.DisclosureTier))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(bw, "    <edge source=\"%s\" target=\"%s\"><data key=\"type\">%s</data>",
+			xmlEscape(edge.Source), xmlEscape(edge.Target), xmlEscape(edge.Type))
+		for _, k := range keys {
+			if v, ok := edge.Attribute(k); ok {
+				fmt.Fprintf(bw, "<data key=\"attr.%s\">%s</data>", xmlEscape(k), xmlEscape(v))
+			}
+		}
+		fmt.Fprintln(bw, "</edge>")
+	}
+	fmt.Fprintln(bw, "  </graph>")
+	fmt.Fprintln(bw, "</graphml>")
+	return bw.Flush()
+}
+
+// d3Node and d3Link are the node and link objects of a D3 force layout
+type d3Node struct {
+	ID    string `json:"id"`
+	Group int    `json:"group"`
+}
+
+type d3Link struct {
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Value  float64 `json:"value"`
+}
+
+// ToD3JSON writes the graph as {"nodes": [...], "links": [...]} for a D3
+// force-directed layout. Nodes are every pack in index plus any other edge
+// endpoint, sorted by ID, each grouped by the tierRank of its tier (-1 for
+// unknown tiers and packs missing from index). Links follow g.Edges, with
+// the edge Cost as their value.
+func (g Graph) ToD3JSON(w io.Writer, index PacksIndex) error {
+	byID := index.ByID()
+	doc := struct {
+		Nodes []d3Node `json:"nodes"`
+		Links []d3Link `json:"links"`
+	}{Nodes: []d3Node{}, Links: []d3Link{}}
+	for _, id := range g.exportNodes(index) {
+		doc.Nodes = append(doc.Nodes, d3Node{ID: id, Group: tierRank(byID[id].DisclosureTier)})
+	}
+	for _, edge := range g.Edges {
+		doc.Links = append(doc.Links, d3Link{Source: edge.Source, Target: edge.Target, Value: edge.Cost()})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(doc)
+}
+
+// WriteJSON writes the graph in graph.json format
+func (g Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// WriteJSON writes the index in packs.index.json format. HTML characters
+// are not escaped, so text is written as it was read.
+func (idx PacksIndex) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(idx)
+}
+
+// WritePacksCSV writes one row per pack with a header row. Related IDs are
+// joined with ";".
+func WritePacksCSV(w io.Writer, packs []Pack) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "title", "disclosure_tier", "related"}); err != nil {
+		return err
+	}
+	for _, p := range packs {
+		row := []string{p.ID, p.Title, p.DisclosureTier, strings.Join(p.Related, ";")}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteEdgesCSV writes the graph as a CSV edge list with a
+// source,target,type header. Rows are sorted by source, target and type so
+// regenerated files diff cleanly; g is not modified.
+func (g Graph) WriteEdgesCSV(w io.Writer) error {
+	edges := slices.Clone(g.Edges)
+	slices.SortStableFunc(edges, compareEdges)
+	return writeEdgesCSV(w, edges)
+}
+
+// writeEdgesCSV writes edges in order with a source,target,type header
+func writeEdgesCSV(w io.Writer, edges []GraphEdge) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"source", "target", "type"}); err != nil {
+		return err
+	}
+	for _, edge := range edges {
+		if err := cw.Write([]string{edge.Source, edge.Target, edge.Type}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// adjacencyEntry is one outgoing edge in the adjacency-list export
+type adjacencyEntry struct {
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// ToAdjacencyJSON writes the graph as a JSON object mapping each node ID to
+// its outgoing edges. Keys are sorted; nodes with no outgoing edges map to
+// an empty list.
+func (g Graph) ToAdjacencyJSON(w io.Writer) error {
+	adj := make(map[string][]adjacencyEntry)
+	for _, edge := range g.Edges {
+		adj[edge.Source] = append(adj[edge.Source], adjacencyEntry{Target: edge.Target, Type: edge.Type})
+		if _, ok := adj[edge.Target]; !ok {
+			adj[edge.Target] = []adjacencyEntry{}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(adj)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// dotEdgeLine matches one edge statement written by ToDOT
+var dotEdgeLine = regexp.MustCompile(`^\s*"(?:[^"\\]|\\.)*" -> "(?:[^"\\]|\\.)*" \[label="(?:[^"\\]|\\.)*"\];$`)
+
+func TestToDOT(t *testing.T) {
+	g := cycleGraph()
+	g.Edges = append(g.Edges, GraphEdge{Source: `say "hi"`, Target: `back\slash`, Type: "odd type"})
+
+	var buf bytes.Buffer
+	if err := g.ToDOT(&buf); err != nil {
+		t.Fatalf("ToDOT: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != "digraph origin {" || lines[len(lines)-1] != "}" {
+		t.Fatalf("missing digraph wrapper:\n%s", buf.String())
+	}
+
+	edges := 0
+	for _, line := range lines[1 : len(lines)-1] {
+		if !dotEdgeLine.MatchString(line) {
+			t.Errorf("malformed edge line: %s", line)
+			continue
+		}
+		edges++
+	}
+	if edges != len(g.Edges) {
+		t.Errorf("parsed %d edges, want %d", edges, len(g.Edges))
+	}
+	if !strings.Contains(buf.String(), `"say \"hi\"" -> "back\\slash"`) {
+		t.Errorf("special characters not escaped:\n%s", buf.String())
+	}
+}
+
+func TestToDOTWithAttributes(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related", Attributes: NewEdgeAttributes(map[string]string{"confidence": "high", "source": "review"})},
+		{Source: "B", Target: "C", Type: "child"},
+	}}
+
+	var buf bytes.Buffer
+	if err := g.ToDOTWithAttributes(&buf, []string{"source", "confidence", "missing"}); err != nil {
+		t.Fatalf("ToDOTWithAttributes: %v", err)
+	}
+	want := "digraph origin {\n" +
+		`  "A" -> "B" [label="related\nsource=review\nconfidence=high"];` + "\n" +
+		`  "B" -> "C" [label="child"];` + "\n}\n"
+	if buf.String() != want {
+		t.Errorf("DOT =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWritePacksCSV(t *testing.T) {
+	packs := []Pack{
+		{ID: "A", Title: `Alpha, "the first"`, DisclosureTier: "public", Related: []string{"B", "C"}},
+		{ID: "B", Title: "Beta"},
+	}
+
+	var buf bytes.Buffer
+	if err := WritePacksCSV(&buf, packs); err != nil {
+		t.Fatalf("WritePacksCSV: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV back: %v", err)
+	}
+	want := [][]string{
+		{"id", "title", "disclosure_tier", "related"},
+		{"A", `Alpha, "the first"`, "public", "B;C"},
+		{"B", "Beta", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestWriteEdgesCSV(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "B", Target: "A", Type: "has, comma"},
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "A", Target: "B", Type: "child"},
+	}}
+
+	var buf bytes.Buffer
+	if err := g.WriteEdgesCSV(&buf); err != nil {
+		t.Fatalf("WriteEdgesCSV: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV back: %v", err)
+	}
+	want := [][]string{
+		{"source", "target", "type"},
+		{"A", "B", "child"},
+		{"A", "C", "related"},
+		{"B", "A", "has, comma"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+	if g.Edges[0].Source != "B" {
+		t.Error("WriteEdgesCSV reordered the graph's edges")
+	}
+}
+
+func TestToAdjacencyJSON(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "B", Target: "A", Type: "child"},
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "A", Target: "B", Type: "related"},
+	}}
+
+	var first, second bytes.Buffer
+	if err := g.ToAdjacencyJSON(&first); err != nil {
+		t.Fatalf("ToAdjacencyJSON: %v", err)
+	}
+	if err := g.ToAdjacencyJSON(&second); err != nil {
+		t.Fatalf("ToAdjacencyJSON: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Error("output is not deterministic")
+	}
+
+	got := strings.Join(strings.Fields(first.String()), "")
+	want := `{"A":[{"target":"C","type":"related"},{"target":"B","type":"related"}],"B":[{"target":"A","type":"child"}],"C":[]}`
+	if got != want {
+		t.Errorf("adjacency = %s, want %s", got, want)
+	}
+}
+
+func TestGraphWriteJSONRoundTrip(t *testing.T) {
+	sub := cycleGraph().Subgraph([]string{"A", "B", "C"})
+
+	var buf bytes.Buffer
+	if err := sub.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+
+	var back Graph
+	if err := decodeJSON(GraphFile, buf.Bytes(), &back); err != nil {
+		t.Fatalf("reloading: %v", err)
+	}
+	if !reflect.DeepEqual(back, sub) {
+		t.Errorf("round trip = %+v, want %+v", back, sub)
+	}
+}
+
+func TestToGraphML(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", Title: `Fish & "Chips" <deluxe>`, DisclosureTier: "public"},
+		{ID: "B", Title: "Beta", DisclosureTier: "internal"},
+		{ID: "E", Title: "Lonely"},
+	}}
+	graph := cycleGraph()
+
+	var buf bytes.Buffer
+	if err := graph.ToGraphML(&buf, index); err != nil {
+		t.Fatalf("ToGraphML: %v", err)
+	}
+
+	var doc struct {
+		Graph struct {
+			Nodes []struct {
+				ID   string `xml:"id,attr"`
+				Data []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Data   struct {
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+
+	if len(doc.Graph.Nodes) != 5 || len(doc.Graph.Edges) != len(graph.Edges) {
+		t.Fatalf("got %d nodes and %d edges, want 5 and %d", len(doc.Graph.Nodes), len(doc.Graph.Edges), len(graph.Edges))
+	}
+	a := doc.Graph.Nodes[0]
+	if a.ID != "A" || a.Data[0].Value != index.Packs[0].Title || a.Data[1].Value != "public" {
+		t.Errorf("node A = %+v", a)
+	}
+	if doc.Graph.Edges[3].Data.Value != "child" {
+		t.Errorf("edge type = %q, want child", doc.Graph.Edges[3].Data.Value)
+	}
+}
+
+func TestToGraphMLWithAttributes(t *testing.T) {
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related", Attributes: NewEdgeAttributes(map[string]string{"confidence": "<high>", "source": "review"})},
+		{Source: "B", Target: "C", Type: "child"},
+	}}
+
+	var buf bytes.Buffer
+	if err := graph.ToGraphMLWithAttributes(&buf, PacksIndex{}, []string{"confidence"}); err != nil {
+		t.Fatalf("ToGraphMLWithAttributes: %v", err)
+	}
+	for _, want := range []string{
+		`<key id="attr.confidence" for="edge" attr.name="confidence" attr.type="string"/>`,
+		`<edge source="A" target="B"><data key="type">related</data><data key="attr.confidence">&lt;high&gt;</data></edge>`,
+		`<edge source="B" target="C"><data key="type">child</data></edge>`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("GraphML missing %s:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "review") {
+		t.Errorf("GraphML includes an attribute that was not selected:\n%s", buf.String())
+	}
+	if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+		t.Errorf("GraphML is not well-formed: %v", err)
+	}
+}
+
+func TestToD3JSON(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "C", DisclosureTier: "restricted"},
+		{ID: "A", DisclosureTier: "public"},
+		{ID: "B", DisclosureTier: "internal"},
+		{ID: "E", DisclosureTier: "draft"},
+	}}
+	graph := cycleGraph()
+	graph.Edges[0].Weight = 2.5
+
+	var buf bytes.Buffer
+	if err := graph.ToD3JSON(&buf, index); err != nil {
+		t.Fatalf("ToD3JSON: %v", err)
+	}
+	var doc struct {
+		Nodes []d3Node `json:"nodes"`
+		Links []d3Link `json:"links"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+
+	wantNodes := []d3Node{{"A", 0}, {"B", 1}, {"C", 2}, {"D", -1}, {"E", -1}}
+	if !reflect.DeepEqual(doc.Nodes, wantNodes) {
+		t.Errorf("nodes = %+v, want %+v", doc.Nodes, wantNodes)
+	}
+	wantLinks := []d3Link{{"A", "B", 2.5}, {"B", "C", 1}, {"C", "A", 1}, {"C", "D", 1}}
+	if !reflect.DeepEqual(doc.Links, wantLinks) {
+		t.Errorf("links = %+v, want %+v", doc.Links, wantLinks)
+	}
+
+	buf.Reset()
+	if err := (Graph{}).ToD3JSON(&buf, PacksIndex{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(strings.Fields(buf.String()), ""); got != `{"nodes":[],"links":[]}` {
+		t.Errorf("empty graph = %s", got)
+	}
+}
@@ -0,0 +1,284 @@
+// ORIGIN Go Kit - pack filter expressions
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrBadFilter is wrapped by ParseFilter errors
+var ErrBadFilter = errors.New("invalid filter")
+
+// Predicate reports whether a pack matches
+type Predicate func(Pack) bool
+
+// And returns a Predicate matching packs that match both p and q
+func (p Predicate) And(q Predicate) Predicate {
+	return func(pack Pack) bool { return p(pack) && q(pack) }
+}
+
+// Or returns a Predicate matching packs that match p or q
+func (p Predicate) Or(q Predicate) Predicate {
+	return func(pack Pack) bool { return p(pack) || q(pack) }
+}
+
+// Filter returns the packs that match p, in order
+func (p Predicate) Filter(packs []Pack) []Pack {
+	out := []Pack{}
+	for _, pack := range packs {
+		if p(pack) {
+			out = append(out, pack)
+		}
+	}
+	return out
+}
+
+// filterFields maps each field a filter can test to its values in a pack
+var filterFields = map[string]func(Pack) []string{
+	"id":      func(p Pack) []string { return []string{p.ID} },
+	"title":   func(p Pack) []string { return []string{p.Title} },
+	"tier":    func(p Pack) []string { return []string{p.DisclosureTier} },
+	"tag":     func(p Pack) []string { return p.Tags },
+	"related": func(p Pack) []string { return p.Related },
+}
+
+// ParseFilter parses a filter expression such as
+//
+//	tier=public AND (tag=core OR title~network)
+//
+// into a Predicate. A comparison is a field (id, title, tier, tag or
+// related), then = for an exact match or ~ for a case-insensitive
+// substring, then a value, quoted with double quotes if it has spaces,
+// parentheses or operators. Tag and related match when any of the pack's
+// values does. AND binds tighter than OR; both are case-insensitive.
+// Errors wrap ErrBadFilter and give the column of the offending token.
+func ParseFilter(expr string) (Predicate, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	pred, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("unexpected %s", p.tokens[p.pos].text)
+	}
+	return pred, nil
+}
+
+// filterToken is one token of a filter expression: a word (field, keyword
+// or value), a quoted value, an operator or a parenthesis
+type filterToken struct {
+	text   string
+	value  string // unquoted text of a quoted value
+	quoted bool
+	col    int // 1-based column of the first character
+}
+
+// lexFilter splits expr into tokens
+func lexFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.IndexByte("()=~", c) >= 0:
+			tokens = append(tokens, filterToken{text: expr[i : i+1], col: i + 1})
+			i++
+		case c == '"':
+			n, err := quotedLen(expr[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w: unterminated string at column %d", ErrBadFilter, i+1)
+			}
+			value, _ := strconv.Unquote(expr[i : i+n])
+			tokens = append(tokens, filterToken{text: expr[i : i+n], value: value, quoted: true, col: i + 1})
+			i += n
+		default:
+			j := i
+			for j < len(expr) && !strings.ContainsRune(" \t()=~\"", rune(expr[j])) {
+				j++
+			}
+			tokens = append(tokens, filterToken{text: expr[i:j], col: i + 1})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// quotedLen returns the length of the double-quoted string s starts with
+func quotedLen(s string) (int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			if _, err := strconv.Unquote(s[:i+1]); err != nil {
+				return 0, err
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, errors.New("unterminated")
+}
+
+// filterParser is a recursive-descent parser over filter tokens
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// errorf returns an ErrBadFilter error at the current token
+func (p *filterParser) errorf(format string, args ...any) error {
+	if p.pos >= len(p.tokens) {
+		return fmt.Errorf("%w: %s at end of filter", ErrBadFilter, fmt.Sprintf(format, args...))
+	}
+	return fmt.Errorf("%w: %s at column %d", ErrBadFilter, fmt.Sprintf(format, args...), p.tokens[p.pos].col)
+}
+
+// keyword reports whether the current token is the keyword kw, consuming
+// it if so
+func (p *filterParser) keyword(kw string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// or parses and-expressions joined by OR
+func (p *filterParser) or() (Predicate, error) {
+	pred, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		next, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		pred = pred.Or(next)
+	}
+	return pred, nil
+}
+
+// and parses terms joined by AND
+func (p *filterParser) and() (Predicate, error) {
+	pred, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		next, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		pred = pred.And(next)
+	}
+	return pred, nil
+}
+
+// term parses a parenthesized expression or a comparison
+func (p *filterParser) term() (Predicate, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, p.errorf("expected a comparison")
+	}
+	if tok := p.tokens[p.pos]; tok.text == "(" && !tok.quoted {
+		p.pos++
+		pred, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].text != ")" {
+			return nil, p.errorf("expected )")
+		}
+		p.pos++
+		return pred, nil
+	}
+	return p.comparison()
+}
+
+// comparison parses field, operator and value
+func (p *filterParser) comparison() (Predicate, error) {
+	field := p.tokens[p.pos]
+	values, ok := filterFields[strings.ToLower(field.text)]
+	if field.quoted || !ok {
+		return nil, p.errorf("unknown field %s (want one of %s)", field.text, strings.Join(slices.Sorted(maps.Keys(filterFields)), ", "))
+	}
+	p.pos++
+
+	if p.pos >= len(p.tokens) || (p.tokens[p.pos].text != "=" && p.tokens[p.pos].text != "~") {
+		return nil, p.errorf("expected = or ~ after %s", field.text)
+	}
+	op := p.tokens[p.pos].text
+	p.pos++
+
+	if p.pos >= len(p.tokens) || (!p.tokens[p.pos].quoted && strings.ContainsAny(p.tokens[p.pos].text, "()=~")) {
+		return nil, p.errorf("expected a value after %s%s", field.text, op)
+	}
+	tok := p.tokens[p.pos]
+	want := tok.text
+	if tok.quoted {
+		want = tok.value
+	}
+	p.pos++
+
+	if op == "=" {
+		return func(pack Pack) bool { return slices.Contains(values(pack), want) }, nil
+	}
+	want = strings.ToLower(want)
+	return func(pack Pack) bool {
+		return slices.ContainsFunc(values(pack), func(v string) bool {
+			return strings.Contains(strings.ToLower(v), want)
+		})
+	}, nil
+}
+
+// packFilter is the -filter predicate; like colorOutput it is set by main,
+// and nil lists every pack
+var packFilter Predicate
+
+// filterCommands are the commands whose pack listings -filter narrows.
+// repl is among them so that -filter reaches the commands run inside it.
+var filterCommands = []string{
+	"closure", "cutpoints", "demo", "filter", "incomplete", "leaves", "list",
+	"orphans", "random", "reachable", "recommend", "repl", "search", "stale",
+}
+
+// checkFilterCommand rejects -filter for a command that would ignore it
+func checkFilterCommand(name string) error {
+	if packFilter != nil && !slices.Contains(filterCommands, name) {
+		return fmt.Errorf("-filter does not apply to %s; it narrows %s", name, strings.Join(filterCommands, ", "))
+	}
+	return nil
+}
+
+// filterListing returns the packs of a command's listing that match
+// -filter
+func filterListing(packs []Pack) []Pack {
+	return filterListingBy(packs, func(p Pack) Pack { return p })
+}
+
+// filterListingBy is filterListing for a listing of other items, each
+// tested as the pack that pack returns for it
+func filterListingBy[T any](items []T, pack func(T) Pack) []T {
+	if packFilter == nil {
+		return items
+	}
+	out := []T{}
+	for _, item := range items {
+		if packFilter(pack(item)) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	packs := []Pack{
+		{ID: "A", Title: "Alpha Network", DisclosureTier: "public", Tags: []string{"core", "net"}},
+		{ID: "B", Title: "Beta", DisclosureTier: "internal", Tags: []string{"core"}},
+		{ID: "C", Title: "Gamma (draft)", DisclosureTier: "public", Related: []string{"A"}},
+	}
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"tier=public", []string{"A", "C"}},
+		{"title~NETWORK", []string{"A"}},
+		{"tag=core AND tier=public", []string{"A"}},
+		{"tier=internal OR related=A", []string{"B", "C"}},
+		{"tag=core and tier=public or id=C", []string{"A", "C"}},
+		{"tag=core AND (tier=public OR id=C)", []string{"A"}},
+		{`title="Gamma (draft)"`, []string{"C"}},
+		{"tag~ne", []string{"A"}},
+		{"tag=co", []string{}},
+	}
+	for _, tt := range tests {
+		pred, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := packIDs(pred.Filter(packs)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q matched %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "expected a comparison at end of filter"},
+		{"tier public", "expected = or ~ after tier at column 6"},
+		{"colour=red", "unknown field colour (want one of id, related, tag, tier, title) at column 1"},
+		{"tier=public AND", "expected a comparison at end of filter"},
+		{"(tier=public", "expected ) at end of filter"},
+		{"tier=public) OR id=A", "unexpected ) at column 12"},
+		{"tier=(", "expected a value after tier= at column 6"},
+		{`title~"open`, "unterminated string at column 7"},
+	}
+	for _, tt := range tests {
+		_, err := ParseFilter(tt.expr)
+		if !errors.Is(err, ErrBadFilter) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseFilter(%q) err = %v, want ErrBadFilter mentioning %q", tt.expr, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,1681 @@
+// ORIGIN Go Kit - graph queries
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNoPath is returned when two packs are not connected
+var ErrNoPath = errors.New("no path")
+
+// ErrCycleDetected is returned when an ordering is impossible because the
+// edges form a cycle
+var ErrCycleDetected = errors.New("cycle detected")
+
+// ErrDuplicateEdge is returned when adding an edge that is already present
+var ErrDuplicateEdge = errors.New("duplicate edge")
+
+// ErrEmptyGraph is returned by metrics that are undefined without edges
+var ErrEmptyGraph = errors.New("graph has no edges")
+
+// ErrAmbiguousParent is returned when a pack has more than one parent in
+// a hierarchy that should be single-parent
+var ErrAmbiguousParent = errors.New("ambiguous parent")
+
+// BuildAdjacency maps each pack ID to its incident edges. Every edge is
+// listed under both its source and its target; a self-loop is listed once.
+func (g Graph) BuildAdjacency() map[string][]GraphEdge {
+	adj := make(map[string][]GraphEdge)
+	for _, edge := range g.Edges {
+		addIncident(adj, edge)
+	}
+	return adj
+}
+
+// addIncident lists edge under its endpoints in adj
+func addIncident(adj map[string][]GraphEdge, edge GraphEdge) {
+	adj[edge.Source] = append(adj[edge.Source], edge)
+	if edge.Target != edge.Source {
+		adj[edge.Target] = append(adj[edge.Target], edge)
+	}
+}
+
+// SelfLoops returns the edges whose source is their target, in graph
+// order. They are almost always data errors; Validate reports them.
+func (g Graph) SelfLoops() []GraphEdge {
+	var loops []GraphEdge
+	for _, edge := range g.Edges {
+		if edge.Source == edge.Target {
+			loops = append(loops, edge)
+		}
+	}
+	return loops
+}
+
+// BuildTypedAdjacency maps each edge type to an adjacency of the edges of
+// that type, as BuildAdjacency builds it, so a traversal restricted to one
+// type visits only those edges. Concatenating every type's list for a node
+// gives the node's BuildAdjacency edges, though not in graph order.
+func (g Graph) BuildTypedAdjacency() map[string]map[string][]GraphEdge {
+	typed := make(map[string]map[string][]GraphEdge)
+	for _, edge := range g.Edges {
+		adj := typed[edge.Type]
+		if adj == nil {
+			adj = make(map[string][]GraphEdge)
+			typed[edge.Type] = adj
+		}
+		addIncident(adj, edge)
+	}
+	return typed
+}
+
+// Direction selects which incident edges a traversal follows
+type Direction int
+
+const (
+	// Both follows edges regardless of direction; it is the zero value
+	Both Direction = iota
+	// Outgoing follows edges whose source is the current node
+	Outgoing
+	// Incoming follows edges whose target is the current node
+	Incoming
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Outgoing:
+		return "out"
+	case Incoming:
+		return "in"
+	default:
+		return "both"
+	}
+}
+
+// filterDirection returns the edges incident to id that match dir
+func filterDirection(edges []GraphEdge, id string, dir Direction) []GraphEdge {
+	if dir == Both {
+		return edges
+	}
+	var out []GraphEdge
+	for _, edge := range edges {
+		if (dir == Outgoing && edge.Source == id) || (dir == Incoming && edge.Target == id) {
+			out = append(out, edge)
+		}
+	}
+	return out
+}
+
+// filterTypes returns the edges whose type is one of types; an empty set
+// keeps every edge
+func filterTypes(edges []GraphEdge, types []string) []GraphEdge {
+	if len(types) == 0 {
+		return edges
+	}
+	var out []GraphEdge
+	for _, edge := range edges {
+		if slices.Contains(types, edge.Type) {
+			out = append(out, edge)
+		}
+	}
+	return out
+}
+
+// Neighbors returns the edges incident to id in the given direction,
+// restricted to edgeTypes when any are given. It scans g.Edges once
+// rather than building an adjacency.
+func (g Graph) Neighbors(id string, dir Direction, edgeTypes ...string) []GraphEdge {
+	var incident []GraphEdge
+	for _, edge := range g.Edges {
+		if edge.Source == id || edge.Target == id {
+			incident = append(incident, edge)
+		}
+	}
+	return filterTypes(filterDirection(incident, id, dir), edgeTypes)
+}
+
+// Referrers returns the distinct sources of edges targeting id, sorted,
+// excluding id itself
+func (g Graph) Referrers(id string) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, edge := range g.Edges {
+		if edge.Target == id && edge.Source != id && !seen[edge.Source] {
+			seen[edge.Source] = true
+			refs = append(refs, edge.Source)
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// otherEnd returns the endpoint of edge opposite id
+func otherEnd(edge GraphEdge, id string) string {
+	if edge.Source == id {
+		return edge.Target
+	}
+	return edge.Source
+}
+
+// BFS returns pack IDs reachable from startID within maxDepth hops,
+// in breadth-first order, following edges in direction dir. When edgeTypes
+// is non-empty only edges of those types are expanded.
+func (g Graph) BFS(startID string, maxDepth int, dir Direction, edgeTypes ...string) []string {
+	adj := g.BuildAdjacency()
+	if _, ok := adj[startID]; !ok {
+		return []string{}
+	}
+	return bfs(adj, startID, maxDepth, dir, edgeTypes)
+}
+
+// bfs is BFS over a prebuilt adjacency, for a start known to be in the
+// graph
+func bfs(adj map[string][]GraphEdge, startID string, maxDepth int, dir Direction, edgeTypes []string) []string {
+	visited := map[string]bool{startID: true}
+	order := []string{startID}
+	frontier := []string{startID}
+
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		var next []string
+		for _, id := range frontier {
+			for _, edge := range filterTypes(filterDirection(adj[id], id, dir), edgeTypes) {
+				otherID := otherEnd(edge, id)
+				if visited[otherID] {
+					continue
+				}
+				visited[otherID] = true
+				order = append(order, otherID)
+				next = append(next, otherID)
+			}
+		}
+		frontier = next
+	}
+
+	return order
+}
+
+// BFSMulti returns the pack IDs reachable from any of starts within
+// maxDepth hops, following edges in either direction. All starts are at
+// depth 0, so a pack near several starts is explored once. The result is
+// in breadth-first order, starting with the starts that are in the graph.
+// When edgeTypes is non-empty only edges of those types are expanded.
+func (g Graph) BFSMulti(starts []string, maxDepth int, edgeTypes ...string) []string {
+	adj := g.BuildAdjacency()
+	visited := make(map[string]bool)
+	order := []string{}
+	for _, id := range starts {
+		if _, ok := adj[id]; ok && !visited[id] {
+			visited[id] = true
+			order = append(order, id)
+		}
+	}
+
+	frontier := slices.Clone(order)
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		var next []string
+		for _, id := range frontier {
+			for _, edge := range filterTypes(adj[id], edgeTypes) {
+				otherID := otherEnd(edge, id)
+				if !visited[otherID] {
+					visited[otherID] = true
+					order = append(order, otherID)
+					next = append(next, otherID)
+				}
+			}
+		}
+		frontier = next
+	}
+	return order
+}
+
+// ReachSize returns the number of distinct nodes reachable from id by
+// following edges in direction dir, not counting id itself. An id that is
+// not in the graph reaches nothing.
+func (g Graph) ReachSize(id string, dir Direction) int {
+	adj := g.BuildAdjacency()
+	if _, ok := adj[id]; !ok {
+		return 0
+	}
+	return len(bfs(adj, id, len(adj), dir, nil)) - 1
+}
+
+// ReachSizes returns ReachSize in direction dir for every node
+func (g Graph) ReachSizes(dir Direction) map[string]int {
+	return g.ReachSizesWithProgress(dir, nil)
+}
+
+// ReachSizesWithProgress is ReachSizes reporting each finished node to
+// progress, which may be nil
+func (g Graph) ReachSizesWithProgress(dir Direction, progress Progress) map[string]int {
+	adj := g.BuildAdjacency()
+	ids, _ := sortedNodes(adj)
+	sizes := make(map[string]int, len(ids))
+	for i, id := range ids {
+		sizes[id] = len(bfs(adj, id, len(adj), dir, nil)) - 1
+		progress.report(i+1, len(ids))
+	}
+	return sizes
+}
+
+// edgeBetween returns the first edge in adj joining a and b in either
+// direction
+func edgeBetween(adj map[string][]GraphEdge, a, b string) (GraphEdge, bool) {
+	for _, edge := range adj[a] {
+		if otherEnd(edge, a) == b {
+			return edge, true
+		}
+	}
+	return GraphEdge{}, false
+}
+
+// ExplainPath describes path as prose for authors, naming each pack by
+// title (or ID when it has none) and each hop by the type of the edge it
+// crosses: "Alpha — depends_on → Beta — mentions → Gamma". A hop that
+// follows an edge backwards is written with the arrow reversed, as in
+// "Beta ← child — Alpha", so the sentence stays true to the edge's
+// direction. A single-pack path is just that pack's name and an empty
+// path is "".
+func (g Graph) ExplainPath(index PacksIndex, path []string) string {
+	return explainPath(g.BuildAdjacency(), index, path)
+}
+
+// explainPath is ExplainPath over a prebuilt adjacency
+func explainPath(adj map[string][]GraphEdge, index PacksIndex, path []string) string {
+	byID := index.ByID()
+	name := func(id string) string {
+		if p, ok := byID[id]; ok && p.Title != "" {
+			return p.Title
+		}
+		return id
+	}
+
+	var b strings.Builder
+	for i, id := range path {
+		if i > 0 {
+			b.WriteString(" " + describeHop(adj, path[i-1], id) + " ")
+		}
+		b.WriteString(name(id))
+	}
+	return b.String()
+}
+
+// describeHop returns the connector ExplainPath writes between from and
+// to, preferring an edge from from to to over one in reverse
+func describeHop(adj map[string][]GraphEdge, from, to string) string {
+	typeName := func(edge GraphEdge) string {
+		if edge.Type == "" {
+			return "linked"
+		}
+		return edge.Type
+	}
+	for _, edge := range adj[from] {
+		if edge.Source == from && edge.Target == to {
+			return "— " + typeName(edge) + " →"
+		}
+	}
+	if edge, ok := edgeBetween(adj, from, to); ok {
+		return "← " + typeName(edge) + " —"
+	}
+	return "— (no edge) →"
+}
+
+// ShortestPath returns the pack IDs on a shortest undirected path from
+// from to to, inclusive of both ends.
+func (g Graph) ShortestPath(from, to string) ([]string, error) {
+	if path := shortestPath(g.BuildAdjacency(), from, to); path != nil {
+		return path, nil
+	}
+	return nil, fmt.Errorf("%w from %s to %s", ErrNoPath, from, to)
+}
+
+// shortestPath is ShortestPath over adj, returning nil when there is no
+// path
+func shortestPath(adj map[string][]GraphEdge, from, to string) []string {
+	if from == to {
+		return []string{from}
+	}
+
+	parent := map[string]string{from: ""}
+	queue := []string{from}
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		for _, edge := range adj[id] {
+			otherID := otherEnd(edge, id)
+			if _, seen := parent[otherID]; seen {
+				continue
+			}
+			parent[otherID] = id
+
+			if otherID == to {
+				var path []string
+				for n := to; n != ""; n = parent[n] {
+					path = append([]string{n}, path...)
+				}
+				return path
+			}
+			queue = append(queue, otherID)
+		}
+	}
+	return nil
+}
+
+// PathResult is the answer to one BatchShortestPath query. Path lists the
+// pack IDs from From to To inclusive and Hops is its length in edges; when
+// there is no path Found is false, Path is empty and Hops is -1.
+type PathResult struct {
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Found bool     `json:"found"`
+	Hops  int      `json:"hops"`
+	Path  []string `json:"path"`
+}
+
+// BatchShortestPath answers ShortestPath for each (from, to) pair, in
+// order, building the adjacency index once for all of them
+func (g Graph) BatchShortestPath(pairs [][2]string) []PathResult {
+	return g.BatchShortestPathWithProgress(pairs, nil)
+}
+
+// BatchShortestPathWithProgress is BatchShortestPath reporting each
+// answered pair to progress, which may be nil
+func (g Graph) BatchShortestPathWithProgress(pairs [][2]string, progress Progress) []PathResult {
+	adj := g.BuildAdjacency()
+	results := make([]PathResult, len(pairs))
+	for i, pair := range pairs {
+		r := PathResult{From: pair[0], To: pair[1], Hops: -1, Path: []string{}}
+		if path := shortestPath(adj, pair[0], pair[1]); path != nil {
+			r.Found, r.Hops, r.Path = true, len(path)-1, path
+		}
+		results[i] = r
+		progress.report(i+1, len(pairs))
+	}
+	return results
+}
+
+// ShortestPathTree returns the breadth-first tree from root over the
+// undirected graph, as each reachable node's parent; root maps to "". Of
+// several parents at the same distance, the first reached in edge order
+// wins. Unreachable nodes are absent, and a root not in the graph gives an
+// empty map.
+func (g Graph) ShortestPathTree(root string) map[string]string {
+	adj := g.BuildAdjacency()
+	parent := make(map[string]string)
+	if _, ok := adj[root]; !ok {
+		return parent
+	}
+	parent[root] = ""
+	for queue := []string{root}; len(queue) > 0; queue = queue[1:] {
+		id := queue[0]
+		for _, edge := range adj[id] {
+			other := otherEnd(edge, id)
+			if _, seen := parent[other]; !seen {
+				parent[other] = id
+				queue = append(queue, other)
+			}
+		}
+	}
+	return parent
+}
+
+// ShortestPathBidirectional returns the same length path as ShortestPath,
+// but searches from both ends at once, expanding the smaller frontier one
+// level at a time until they meet. It explores far fewer nodes on large
+// graphs.
+func (g Graph) ShortestPathBidirectional(from, to string) ([]string, error) {
+	if from == to {
+		return []string{from}, nil
+	}
+
+	adj := g.BuildAdjacency()
+	distF, distB := map[string]int{from: 0}, map[string]int{to: 0}
+	parentF, parentB := map[string]string{}, map[string]string{}
+	frontierF, frontierB := []string{from}, []string{to}
+
+	for len(frontierF) > 0 && len(frontierB) > 0 {
+		// Expand one full level of the smaller side, keeping the best
+		// meeting point found in that level
+		dist, other, parent, frontier := distF, distB, parentF, &frontierF
+		if len(frontierB) < len(frontierF) {
+			dist, other, parent, frontier = distB, distF, parentB, &frontierB
+		}
+
+		meet, best := "", -1
+		var next []string
+		for _, id := range *frontier {
+			for _, edge := range adj[id] {
+				otherID := otherEnd(edge, id)
+				if _, seen := dist[otherID]; seen {
+					continue
+				}
+				dist[otherID] = dist[id] + 1
+				parent[otherID] = id
+				next = append(next, otherID)
+				if d, ok := other[otherID]; ok && (best < 0 || dist[otherID]+d < best) {
+					meet, best = otherID, dist[otherID]+d
+				}
+			}
+		}
+		*frontier = next
+
+		if best >= 0 {
+			path := []string{meet}
+			for n := meet; n != from; {
+				n = parentF[n]
+				path = append([]string{n}, path...)
+			}
+			for n := meet; n != to; {
+				n = parentB[n]
+				path = append(path, n)
+			}
+			return path, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w from %s to %s", ErrNoPath, from, to)
+}
+
+// ErrLimitExceeded is returned when a traversal is stopped by a cap or by
+// its context
+var ErrLimitExceeded = errors.New("traversal exceeded limits")
+
+// checkInterval is how many steps a long traversal takes between context
+// checks
+const checkInterval = 1024
+
+// MaxPathDepth caps the maxDepth of AllPaths; the number of simple paths
+// can grow exponentially with depth
+const MaxPathDepth = 8
+
+// AllPaths returns every simple undirected path from from to to with at
+// most maxDepth hops (capped at MaxPathDepth), shortest first and then in
+// ID order
+func (g Graph) AllPaths(from, to string, maxDepth int) [][]string {
+	paths, _ := g.AllPathsContext(context.Background(), from, to, maxDepth, 0)
+	return paths
+}
+
+// AllPathsContext is AllPaths with guards for dense graphs: it fails with
+// ErrLimitExceeded once more than maxPaths paths are found (0 means no
+// cap) or when ctx is done
+func (g Graph) AllPathsContext(ctx context.Context, from, to string, maxDepth, maxPaths int) ([][]string, error) {
+	maxDepth = min(maxDepth, MaxPathDepth)
+	if from == to {
+		return [][]string{{from}}, nil
+	}
+
+	adj := g.BuildAdjacency()
+	var paths [][]string
+	var err error
+	steps := 0
+	onPath := map[string]bool{from: true}
+	path := []string{from}
+
+	var walk func(id string)
+	walk = func(id string) {
+		if len(path)-1 >= maxDepth || err != nil {
+			return
+		}
+		if steps%checkInterval == 0 && ctx.Err() != nil {
+			err = fmt.Errorf("%w: %w after %d steps", ErrLimitExceeded, ctx.Err(), steps)
+			return
+		}
+		steps++
+		// Parallel edges lead to the same next node; following each once
+		// keeps duplicate paths out of paths and of the maxPaths count
+		tried := make(map[string]bool, len(adj[id]))
+		for _, edge := range adj[id] {
+			next := otherEnd(edge, id)
+			if onPath[next] || tried[next] {
+				continue
+			}
+			tried[next] = true
+			path = append(path, next)
+			if next == to {
+				paths = append(paths, slices.Clone(path))
+				if maxPaths > 0 && len(paths) > maxPaths {
+					err = fmt.Errorf("%w: more than %d paths from %s to %s", ErrLimitExceeded, maxPaths, from, to)
+					return
+				}
+			} else {
+				onPath[next] = true
+				walk(next)
+				onPath[next] = false
+			}
+			path = path[:len(path)-1]
+			if err != nil {
+				return
+			}
+		}
+	}
+	walk(from)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(paths, func(a, b []string) int {
+		return cmp.Or(cmp.Compare(len(a), len(b)), slices.Compare(a, b))
+	})
+	return paths, nil
+}
+
+// ConnectedComponents groups node IDs by undirected connected component.
+// Components are sorted largest-first and IDs within each are sorted.
+func (g Graph) ConnectedComponents() [][]string {
+	adj := g.BuildAdjacency()
+
+	ids := make([]string, 0, len(adj))
+	for id := range adj {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	seen := make(map[string]bool, len(ids))
+	var components [][]string
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		component := []string{id}
+		for queue := []string{id}; len(queue) > 0; queue = queue[1:] {
+			for _, edge := range adj[queue[0]] {
+				otherID := otherEnd(edge, queue[0])
+				if !seen[otherID] {
+					seen[otherID] = true
+					component = append(component, otherID)
+					queue = append(queue, otherID)
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool {
+		return len(components[i]) > len(components[j])
+	})
+	return components
+}
+
+// ArticulationPoints returns the nodes whose removal, with their edges,
+// would split their connected component, ignoring edge direction, by
+// Tarjan's low-link depth-first search. IDs are sorted; self-loops and
+// parallel edges do not change the result.
+func (g Graph) ArticulationPoints() []string {
+	ids, pos := sortedNodes(g.BuildAdjacency())
+	neighbors := make([][]int, len(ids))
+	for _, edge := range g.Edges {
+		s, t := pos[edge.Source], pos[edge.Target]
+		if s != t {
+			neighbors[s] = append(neighbors[s], t)
+			neighbors[t] = append(neighbors[t], s)
+		}
+	}
+
+	// disc is the 1-based discovery time, 0 while unvisited; low is the
+	// earliest discovery time reachable from a node's subtree by one back
+	// edge. The explicit stack keeps deep chains from overflowing.
+	disc, low, parent := make([]int, len(ids)), make([]int, len(ids)), make([]int, len(ids))
+	next := make([]int, len(ids))
+	cut := make([]bool, len(ids))
+	clock := 0
+	for root := range ids {
+		if disc[root] != 0 {
+			continue
+		}
+		clock++
+		disc[root], low[root], parent[root] = clock, clock, -1
+		children := 0
+		stack := []int{root}
+		for len(stack) > 0 {
+			u := stack[len(stack)-1]
+			if next[u] < len(neighbors[u]) {
+				v := neighbors[u][next[u]]
+				next[u]++
+				switch {
+				case disc[v] == 0:
+					clock++
+					disc[v], low[v], parent[v] = clock, clock, u
+					if u == root {
+						children++
+					}
+					stack = append(stack, v)
+				case v != parent[u]:
+					low[u] = min(low[u], disc[v])
+				}
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			if p := parent[u]; p >= 0 {
+				low[p] = min(low[p], low[u])
+				if p != root && low[u] >= disc[p] {
+					cut[p] = true
+				}
+			}
+		}
+		cut[root] = children > 1
+	}
+
+	points := []string{}
+	for i, id := range ids {
+		if cut[i] {
+			points = append(points, id)
+		}
+	}
+	return points
+}
+
+// ComponentStat summarizes one connected component. Hub is its
+// highest-degree node, ties broken by ID.
+type ComponentStat struct {
+	Nodes     int    `json:"nodes"`
+	Edges     int    `json:"edges"`
+	Hub       string `json:"hub"`
+	HubDegree int    `json:"hub_degree"`
+}
+
+// ComponentStats returns node and edge counts and the hub of each
+// connected component, in ConnectedComponents order
+func (g Graph) ComponentStats() []ComponentStat {
+	components := g.ConnectedComponents()
+	member := make(map[string]int)
+	stats := make([]ComponentStat, len(components))
+	for i, component := range components {
+		stats[i].Nodes = len(component)
+		for _, id := range component {
+			member[id] = i
+		}
+	}
+	for _, edge := range g.Edges {
+		stats[member[edge.Source]].Edges++
+	}
+
+	degree := g.DegreeCentrality()
+	for i, component := range components {
+		// Components are sorted by ID, so the first maximum wins ties
+		for _, id := range component {
+			if degree[id] > stats[i].HubDegree {
+				stats[i].Hub, stats[i].HubDegree = id, degree[id]
+			}
+		}
+	}
+	return stats
+}
+
+// Density returns the number of edges over the number of possible directed
+// edges between distinct nodes, n(n-1). Nodes are the distinct edge
+// endpoints; graphs with fewer than two have density 0.
+func (g Graph) Density() float64 {
+	n := len(g.BuildAdjacency())
+	if n < 2 {
+		return 0
+	}
+	return float64(len(g.Edges)) / float64(n*(n-1))
+}
+
+// Diameter returns the longest shortest undirected path, in edges, within
+// any connected component; disconnected pairs are ignored. It runs a
+// breadth-first search from every node, so it costs O(V·E) and is slow on
+// large graphs.
+func (g Graph) Diameter() (int, error) {
+	if len(g.Edges) == 0 {
+		return 0, ErrEmptyGraph
+	}
+	adj := g.BuildAdjacency()
+	diameter := 0
+	for src := range adj {
+		dist := map[string]int{src: 0}
+		queue := []string{src}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, edge := range adj[id] {
+				next := otherEnd(edge, id)
+				if _, seen := dist[next]; !seen {
+					dist[next] = dist[id] + 1
+					diameter = max(diameter, dist[next])
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return diameter, nil
+}
+
+// AncestorChain returns the lineage of id up to its root, starting with id,
+// following edgeType edges from source to target. Parallel edges to the
+// same parent are allowed; distinct parents are ErrAmbiguousParent and a
+// loop back into the chain is ErrCycleDetected.
+func (g Graph) AncestorChain(id, edgeType string) ([]string, error) {
+	parents := make(map[string][]string)
+	for _, edge := range g.Edges {
+		if edge.Type == edgeType && !slices.Contains(parents[edge.Source], edge.Target) {
+			parents[edge.Source] = append(parents[edge.Source], edge.Target)
+		}
+	}
+
+	chain := []string{id}
+	seen := map[string]bool{id: true}
+	for cur := id; ; {
+		switch ps := parents[cur]; len(ps) {
+		case 0:
+			return chain, nil
+		case 1:
+			cur = ps[0]
+		default:
+			slices.Sort(ps)
+			return nil, fmt.Errorf("%w: %s has %d parents via %s edges: %s",
+				ErrAmbiguousParent, cur, len(ps), edgeType, strings.Join(ps, ", "))
+		}
+		if seen[cur] {
+			return nil, fmt.Errorf("%w: %s -> %s", ErrCycleDetected, strings.Join(chain, " -> "), cur)
+		}
+		seen[cur] = true
+		chain = append(chain, cur)
+	}
+}
+
+// FindCycles returns directed cycles over edges of edgeType, or over all
+// edges when edgeType is empty. Each cycle lists its nodes in edge order
+// without repeating the first node. One cycle is reported per back edge
+// found by a depth-first search, so this is not every elementary cycle.
+func (g Graph) FindCycles(edgeType string) [][]string {
+	out := make(map[string][]string)
+	var ids []string
+	addNode := func(id string) {
+		if _, ok := out[id]; !ok {
+			out[id] = nil
+			ids = append(ids, id)
+		}
+	}
+	for _, edge := range g.Edges {
+		if edgeType != "" && edge.Type != edgeType {
+			continue
+		}
+		addNode(edge.Source)
+		addNode(edge.Target)
+		out[edge.Source] = append(out[edge.Source], edge.Target)
+	}
+	sort.Strings(ids)
+
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make(map[string]int, len(ids))
+	var stack []string
+	var cycles [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = onStack
+		stack = append(stack, id)
+		for _, next := range out[id] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case onStack:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == next {
+						cycles = append(cycles, append([]string(nil), stack[i:]...))
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+
+	for _, id := range ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return cycles
+}
+
+// DegreeCentrality returns the number of incident edges for each node, as
+// BuildAdjacency lists them, so a self-loop counts once
+func (g Graph) DegreeCentrality() map[string]int {
+	degree := make(map[string]int)
+	for _, edge := range g.Edges {
+		degree[edge.Source]++
+		if edge.Target != edge.Source {
+			degree[edge.Target]++
+		}
+	}
+	return degree
+}
+
+// Leaves returns the nodes with exactly one incident edge, sorted. They
+// are often stub packs worth expanding; packs with no edges at all are
+// FindOrphans' concern.
+func (g Graph) Leaves() []string {
+	var leaves []string
+	for id, d := range g.DegreeCentrality() {
+		if d == 1 {
+			leaves = append(leaves, id)
+		}
+	}
+	slices.Sort(leaves)
+	return leaves
+}
+
+// rankByScore returns the keys of scores ordered by score descending,
+// breaking ties by key
+func rankByScore[V cmp.Ordered](scores map[string]V) []string {
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// PageRank defaults
+const (
+	DefaultDamping    = 0.85
+	DefaultIterations = 50
+)
+
+// PageRank scores each node by random-walk influence over the undirected
+// graph: each iteration, a node keeps (1-damping)/N and receives damping
+// times its neighbors' scores split evenly over their edges. Scores sum
+// to 1.
+func (g Graph) PageRank(damping float64, iterations int) map[string]float64 {
+	return g.PageRankWithProgress(damping, iterations, nil)
+}
+
+// PageRankWithProgress is PageRank reporting each finished iteration to
+// progress, which may be nil
+func (g Graph) PageRankWithProgress(damping float64, iterations int, progress Progress) map[string]float64 {
+	adj := g.BuildAdjacency()
+	ids, pos := sortedNodes(adj)
+	rank := make(map[string]float64, len(ids))
+	if len(ids) == 0 {
+		return rank
+	}
+
+	// Each node pulls its neighbors' shares in the order a serial pass
+	// pushing from every node in ID order would add them, so the sums
+	// are the same however the nodes are split among workers
+	degree := make([]float64, len(ids))
+	senders := make([][]int, len(ids))
+	for u, id := range ids {
+		degree[u] = float64(len(adj[id]))
+		for _, edge := range adj[id] {
+			v := pos[otherEnd(edge, id)]
+			senders[v] = append(senders[v], u)
+		}
+	}
+
+	n := float64(len(ids))
+	cur, next, share := make([]float64, len(ids)), make([]float64, len(ids)), make([]float64, len(ids))
+	for u := range cur {
+		cur[u] = 1 / n
+	}
+	workers := workerCount(len(ids))
+	for i := 0; i < iterations; i++ {
+		parallelRange(len(ids), workers, func(lo, hi int) {
+			for u := lo; u < hi; u++ {
+				share[u] = damping * cur[u] / degree[u]
+			}
+		})
+		parallelRange(len(ids), workers, func(lo, hi int) {
+			for v := lo; v < hi; v++ {
+				sum := (1 - damping) / n
+				for _, u := range senders[v] {
+					sum += share[u]
+				}
+				next[v] = sum
+			}
+		})
+		cur, next = next, cur
+		progress.report(i+1, iterations)
+	}
+
+	for u, id := range ids {
+		rank[id] = cur[u]
+	}
+	return rank
+}
+
+// BetweennessCentrality scores each node by the number of shortest paths
+// between other pairs of nodes that pass through it, using Brandes'
+// algorithm over the undirected graph. Each unordered pair counts once;
+// parallel edges and self-loops do not add paths.
+func (g Graph) BetweennessCentrality() map[string]float64 {
+	return g.BetweennessCentralityWithProgress(nil)
+}
+
+// BetweennessCentralityWithProgress is BetweennessCentrality reporting
+// each finished source node to progress, which may be nil
+func (g Graph) BetweennessCentralityWithProgress(progress Progress) map[string]float64 {
+	adj := g.BuildAdjacency()
+	ids, pos := sortedNodes(adj)
+	neighbors := make([][]int, len(ids))
+	for u, id := range ids {
+		var vs []int
+		for _, edge := range adj[id] {
+			if other := otherEnd(edge, id); other != id {
+				vs = append(vs, pos[other])
+			}
+		}
+		slices.Sort(vs)
+		neighbors[u] = slices.Compact(vs)
+	}
+
+	// Sources are searched in parallel, but their dependencies are added
+	// in source order, as a serial pass would
+	score := make([]float64, len(ids))
+	add := func(deps []nodeScore) {
+		for _, d := range deps {
+			score[d.node] += d.score
+		}
+	}
+	if workers := workerCount(len(ids)); workers == 1 {
+		b := newBrandes(neighbors)
+		for src := range ids {
+			add(b.dependencies(src))
+			progress.report(src+1, len(ids))
+		}
+	} else {
+		type sourceDeps struct {
+			src  int
+			deps []nodeScore
+		}
+		jobs := make(chan int)
+		results := make(chan sourceDeps, workers)
+		window := make(chan struct{}, 2*workers) // bounds results held out of order
+		go func() {
+			for src := range ids {
+				window <- struct{}{}
+				jobs <- src
+			}
+			close(jobs)
+		}()
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				b := newBrandes(neighbors)
+				for src := range jobs {
+					results <- sourceDeps{src, b.dependencies(src)}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		pending := make(map[int][]nodeScore)
+		done := 0
+		for r := range results {
+			pending[r.src] = r.deps
+			for deps, ok := pending[done]; ok; deps, ok = pending[done] {
+				add(deps)
+				delete(pending, done)
+				done++
+				<-window
+				progress.report(done, len(ids))
+			}
+		}
+	}
+
+	// Every pair was counted from both ends
+	result := make(map[string]float64, len(ids))
+	for u, id := range ids {
+		result[id] = score[u] / 2
+	}
+	return result
+}
+
+// Workers is how many goroutines PageRank and BetweennessCentrality run
+// on; zero or less means runtime.NumCPU(). Their results do not depend on
+// it.
+var Workers int
+
+// workerCount returns the number of workers to use for n units of work
+func workerCount(n int) int {
+	w := Workers
+	if w <= 0 {
+		w = runtime.NumCPU()
+	}
+	return max(1, min(w, n))
+}
+
+// parallelRange splits [0, n) into one contiguous chunk per worker and
+// calls fn on each, returning when all are done
+func parallelRange(n, workers int, fn func(lo, hi int)) {
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+	chunk := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for lo := 0; lo < n; lo += chunk {
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			fn(lo, hi)
+		}(lo, min(lo+chunk, n))
+	}
+	wg.Wait()
+}
+
+// sortedNodes returns the nodes of adj in ID order and each one's position
+func sortedNodes(adj map[string][]GraphEdge) ([]string, map[string]int) {
+	ids := slices.Sorted(maps.Keys(adj))
+	pos := make(map[string]int, len(ids))
+	for i, id := range ids {
+		pos[id] = i
+	}
+	return ids, pos
+}
+
+// nodeScore is one node's share of a computation, by node position
+type nodeScore struct {
+	node  int
+	score float64
+}
+
+// brandes holds one worker's buffers for Brandes' single-source search
+// over nodes numbered by position
+type brandes struct {
+	neighbors [][]int
+	dist      []int
+	paths     []float64
+	delta     []float64
+	preds     [][]int
+	order     []int
+}
+
+func newBrandes(neighbors [][]int) *brandes {
+	n := len(neighbors)
+	b := &brandes{neighbors: neighbors, dist: make([]int, n), paths: make([]float64, n),
+		delta: make([]float64, n), preds: make([][]int, n)}
+	for i := range b.dist {
+		b.dist[i] = -1
+	}
+	return b
+}
+
+// dependencies returns the dependency of src on each other node it
+// reaches, leaving the buffers clean for the next source
+func (b *brandes) dependencies(src int) []nodeScore {
+	// Count shortest paths from src breadth-first
+	order := append(b.order[:0], src)
+	b.dist[src], b.paths[src] = 0, 1
+	for i := 0; i < len(order); i++ {
+		id := order[i]
+		for _, next := range b.neighbors[id] {
+			if b.dist[next] < 0 {
+				b.dist[next] = b.dist[id] + 1
+				order = append(order, next)
+			}
+			if b.dist[next] == b.dist[id]+1 {
+				b.paths[next] += b.paths[id]
+				b.preds[next] = append(b.preds[next], id)
+			}
+		}
+	}
+
+	// Accumulate dependencies from the farthest nodes back
+	deps := make([]nodeScore, 0, len(order)-1)
+	for i := len(order) - 1; i > 0; i-- {
+		id := order[i]
+		for _, pred := range b.preds[id] {
+			b.delta[pred] += b.paths[pred] / b.paths[id] * (1 + b.delta[id])
+		}
+		deps = append(deps, nodeScore{id, b.delta[id]})
+	}
+
+	for _, id := range order {
+		b.dist[id], b.paths[id], b.delta[id], b.preds[id] = -1, 0, 0, b.preds[id][:0]
+	}
+	b.order = order
+	return deps
+}
+
+// EdgesOfType returns the edges whose Type is t
+func (g Graph) EdgesOfType(t string) []GraphEdge {
+	var edges []GraphEdge
+	for _, edge := range g.Edges {
+		if edge.Type == t {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// EdgeTypeCounts returns the number of edges of each type
+func (g Graph) EdgeTypeCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, edge := range g.Edges {
+		counts[edge.Type]++
+	}
+	return counts
+}
+
+// PrintTree writes root and its neighbors to w as an indented tree, up to
+// maxDepth levels deep. Nodes already printed are marked "(seen)" and not
+// expanded again, so cycles terminate.
+func (g Graph) PrintTree(w io.Writer, root string, maxDepth int) {
+	g.printTree(w, root, maxDepth, colorID)
+}
+
+// printTree is PrintTree with each pack ID rendered by label
+func (g Graph) printTree(w io.Writer, root string, maxDepth int, label func(string) string) {
+	adj := g.BuildAdjacency()
+	visited := map[string]bool{root: true}
+	fmt.Fprintln(w, label(root))
+
+	var walk func(id string, via GraphEdge, depth int)
+	walk = func(id string, via GraphEdge, depth int) {
+		if depth >= maxDepth {
+			return
+		}
+		indent := strings.Repeat("  ", depth+1)
+		for _, edge := range adj[id] {
+			if depth > 0 && edge == via {
+				continue
+			}
+			otherID := otherEnd(edge, id)
+			if visited[otherID] {
+				fmt.Fprintf(w, "%s→ %s: %s (seen)\n", indent, colorType(edge.Type), label(otherID))
+				continue
+			}
+			visited[otherID] = true
+			fmt.Fprintf(w, "%s→ %s: %s\n", indent, colorType(edge.Type), label(otherID))
+			walk(otherID, edge, depth+1)
+		}
+	}
+	walk(root, GraphEdge{}, 0)
+}
+
+// neighborSet returns the distinct undirected neighbors of id
+func neighborSet(adj map[string][]GraphEdge, id string) map[string]bool {
+	set := make(map[string]bool, len(adj[id]))
+	for _, edge := range adj[id] {
+		if otherID := otherEnd(edge, id); otherID != id {
+			set[otherID] = true
+		}
+	}
+	return set
+}
+
+// jaccard returns |a ∩ b| / |a ∪ b|, or 0 when both sets are empty
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for id := range a {
+		if b[id] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// Similarity returns the Jaccard index of the neighbor sets of a and b,
+// or 0 when neither has neighbors
+func (g Graph) Similarity(a, b string) float64 {
+	adj := g.BuildAdjacency()
+	return jaccard(neighborSet(adj, a), neighborSet(adj, b))
+}
+
+// similarityScores returns the nonzero Similarity of id to every other
+// node in the graph
+func (g Graph) similarityScores(id string) map[string]float64 {
+	adj := g.BuildAdjacency()
+	self := neighborSet(adj, id)
+	scores := make(map[string]float64)
+	for other := range adj {
+		if other == id {
+			continue
+		}
+		if score := jaccard(self, neighborSet(adj, other)); score > 0 {
+			scores[other] = score
+		}
+	}
+	return scores
+}
+
+// StrongNeighbors returns the direct neighbors of id, sorted, that share
+// at least k neighbors with it. With k <= 0 every neighbor is returned.
+func (g Graph) StrongNeighbors(id string, k int) []string {
+	adj := g.BuildAdjacency()
+	direct := neighborSet(adj, id)
+	strong := []string{}
+	for other := range direct {
+		shared := 0
+		for mid := range neighborSet(adj, other) {
+			if direct[mid] {
+				shared++
+			}
+		}
+		if shared >= k {
+			strong = append(strong, other)
+		}
+	}
+	sort.Strings(strong)
+	return strong
+}
+
+// suggestionScores counts, for each pack two hops from id and not
+// directly connected to it, the distinct intermediate packs linking them
+func (g Graph) suggestionScores(id string) map[string]int {
+	adj := g.BuildAdjacency()
+	direct := neighborSet(adj, id)
+
+	scores := make(map[string]int)
+	for mid := range direct {
+		for candidate := range neighborSet(adj, mid) {
+			if candidate != id && !direct[candidate] {
+				scores[candidate]++
+			}
+		}
+	}
+	return scores
+}
+
+// Suggestions returns packs two hops from id that are not directly
+// connected to it, ranked by how many intermediate packs lead to them
+func (g Graph) Suggestions(id string) []string {
+	return rankByScore(g.suggestionScores(id))
+}
+
+// TopoSort orders every graph node so that, for each edge of edgeType (or
+// every edge when edgeType is empty), the target comes before the source.
+// For depends_on edges this puts dependencies first. Nodes without such
+// edges are included; ties are broken by ID. A cycle makes ordering
+// impossible and returns an error wrapping ErrCycleDetected.
+func (g Graph) TopoSort(edgeType string) ([]string, error) {
+	if cycles := g.FindCycles(edgeType); len(cycles) > 0 {
+		c := cycles[0]
+		return nil, fmt.Errorf("%w: %s -> %s", ErrCycleDetected, strings.Join(c, " -> "), c[0])
+	}
+
+	pending := make(map[string]int)
+	dependents := make(map[string][]string)
+	for id := range g.BuildAdjacency() {
+		pending[id] = 0
+	}
+	for _, edge := range g.Edges {
+		if edgeType != "" && edge.Type != edgeType {
+			continue
+		}
+		pending[edge.Source]++
+		dependents[edge.Target] = append(dependents[edge.Target], edge.Source)
+	}
+
+	var ready []string
+	for id, n := range pending {
+		if n == 0 {
+			ready = append(ready, id)
+		}
+	}
+	sort.Strings(ready)
+
+	order := make([]string, 0, len(pending))
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+
+		var freed []string
+		for _, dep := range dependents[id] {
+			if pending[dep]--; pending[dep] == 0 {
+				freed = append(freed, dep)
+			}
+		}
+		ready = append(ready, freed...)
+		sort.Strings(ready)
+	}
+	return order, nil
+}
+
+// costItem is a node and its tentative distance in a Dijkstra queue
+type costItem struct {
+	id   string
+	cost float64
+}
+
+// costQueue is a min-heap of costItems, ties broken by ID
+type costQueue []costItem
+
+func (q costQueue) Len() int { return len(q) }
+func (q costQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].id < q[j].id
+}
+func (q costQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *costQueue) Push(x any)   { *q = append(*q, x.(costItem)) }
+func (q *costQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// dijkstra finds a cheapest undirected path from from to to, where
+// stepCost gives the cost of crossing edge into node next
+func (g Graph) dijkstra(from, to string, stepCost func(edge GraphEdge, next string) float64) ([]string, float64, error) {
+	if from == to {
+		return []string{from}, 0, nil
+	}
+
+	adj := g.BuildAdjacency()
+	dist := map[string]float64{from: 0}
+	parent := make(map[string]string)
+	done := make(map[string]bool)
+	queue := &costQueue{{id: from}}
+
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(costItem)
+		if done[item.id] {
+			continue
+		}
+		done[item.id] = true
+
+		if item.id == to {
+			path := []string{to}
+			for n := to; n != from; {
+				n = parent[n]
+				path = append([]string{n}, path...)
+			}
+			return path, item.cost, nil
+		}
+
+		for _, edge := range adj[item.id] {
+			next := otherEnd(edge, item.id)
+			if done[next] {
+				continue
+			}
+			cost := item.cost + stepCost(edge, next)
+			if d, seen := dist[next]; !seen || cost < d {
+				dist[next] = cost
+				parent[next] = item.id
+				heap.Push(queue, costItem{id: next, cost: cost})
+			}
+		}
+	}
+
+	return nil, 0, fmt.Errorf("%w from %s to %s", ErrNoPath, from, to)
+}
+
+// WeightedShortestPath returns a cheapest undirected path from from to to
+// and its total cost, using Dijkstra's algorithm over edge weights (unset
+// weights count as 1). Negative weights are rejected with an error.
+func (g Graph) WeightedShortestPath(from, to string) ([]string, float64, error) {
+	if err := g.checkWeights(); err != nil {
+		return nil, 0, err
+	}
+	return g.dijkstra(from, to, func(edge GraphEdge, _ string) float64 {
+		return edge.Cost()
+	})
+}
+
+// checkWeights rejects negative edge weights, which Dijkstra's algorithm
+// cannot handle
+func (g Graph) checkWeights() error {
+	for _, edge := range g.Edges {
+		if edge.Weight < 0 {
+			return fmt.Errorf("edge %s -> %s (%s) has negative weight %g",
+				edge.Source, edge.Target, edge.Type, edge.Weight)
+		}
+	}
+	return nil
+}
+
+// sameEdge reports whether a and b join the same source and target with
+// the same type
+func sameEdge(a, b GraphEdge) bool {
+	return a.Source == b.Source && a.Target == b.Target && a.Type == b.Type
+}
+
+// AddEdge appends e and updates the metadata counts. An edge with the same
+// source, target and type is left alone and reported as ErrDuplicateEdge.
+// Edges is replaced rather than grown in place, so copies of g taken
+// earlier are unaffected; adjacency is derived from Edges on demand and so
+// stays current.
+func (g *Graph) AddEdge(e GraphEdge) error {
+	if e.Source == "" || e.Target == "" {
+		return fmt.Errorf("edge %s -> %s: source and target are required", e.Source, e.Target)
+	}
+	for _, edge := range g.Edges {
+		if sameEdge(edge, e) {
+			return fmt.Errorf("%w: %s -> %s (%s)", ErrDuplicateEdge, e.Source, e.Target, e.Type)
+		}
+	}
+	g.Edges = append(slices.Clip(g.Edges), e)
+	g.recount()
+	return nil
+}
+
+// RemoveEdge removes every edge with e's source, target and type and
+// reports whether there were any. Like AddEdge it replaces Edges.
+func (g *Graph) RemoveEdge(e GraphEdge) bool {
+	kept := slices.DeleteFunc(slices.Clone(g.Edges), func(edge GraphEdge) bool { return sameEdge(edge, e) })
+	if len(kept) == len(g.Edges) {
+		return false
+	}
+	g.Edges = kept
+	g.recount()
+	return true
+}
+
+// MinimumSpanningTree returns a minimum spanning forest of g, one tree per
+// connected component, ignoring edge direction, with metadata recomputed.
+// Edges cost their weight, 1 when unset, and equal costs are broken by
+// source, target and type, so the result is stable. Self-loops are never
+// part of a tree, so a node whose only edge is a self-loop is dropped.
+// Edges are returned in the order chosen: cheapest first.
+func (g Graph) MinimumSpanningTree() Graph {
+	edges := slices.Clone(g.Edges)
+	slices.SortStableFunc(edges, func(a, b GraphEdge) int {
+		return cmp.Or(cmp.Compare(a.Cost(), b.Cost()), compareEdges(a, b))
+	})
+
+	// parent links each merged node towards its tree's root, which has no
+	// entry
+	parent := make(map[string]string)
+	root := func(id string) string {
+		r := id
+		for p, ok := parent[r]; ok; p, ok = parent[r] {
+			r = p
+		}
+		for id != r {
+			id, parent[id] = parent[id], r
+		}
+		return r
+	}
+
+	tree := Graph{Edges: []GraphEdge{}}
+	for _, edge := range edges {
+		a, b := root(edge.Source), root(edge.Target)
+		if a == b {
+			continue
+		}
+		parent[a] = b
+		tree.Edges = append(tree.Edges, edge)
+	}
+	tree.recount()
+	return tree
+}
+
+// RecomputeMetadata returns g with NodeCount set to the number of distinct
+// edge endpoints and EdgeCount to the number of edges
+func (g Graph) RecomputeMetadata() Graph {
+	g.recount()
+	return g
+}
+
+// recount sets the metadata counts from the edges
+func (g *Graph) recount() {
+	g.Metadata.NodeCount = len(g.BuildAdjacency())
+	g.Metadata.EdgeCount = len(g.Edges)
+}
+
+// ExtractNeighborhood returns the packs within k hops of center and the
+// subgraph they induce, as a self-contained dataset: counts are recomputed
+// and related lists keep only included packs. To leave packs out, filter
+// index and graph before extracting.
+func ExtractNeighborhood(index PacksIndex, graph Graph, center string, k int) (PacksIndex, Graph) {
+	ids := graph.BFS(center, k, Both)
+	if len(ids) == 0 {
+		// A pack with no edges is its own neighborhood
+		ids = []string{center}
+	}
+	keep := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+	}
+
+	var sub PacksIndex
+	sub.Packs = []Pack{}
+	for _, p := range index.Packs {
+		if !keep[p.ID] {
+			continue
+		}
+		p.Related = slices.DeleteFunc(slices.Clone(p.Related), func(id string) bool { return !keep[id] })
+		sub.Packs = append(sub.Packs, p)
+	}
+	sub.Metadata.PackCount = len(sub.Packs)
+	return sub, graph.Subgraph(ids)
+}
+
+// Dedupe returns a copy of g without repeated edges, keeping the first
+// edge for each source, target and type in its original order, with
+// metadata recomputed
+func (g Graph) Dedupe() Graph {
+	type key struct{ source, target, typ string }
+	seen := make(map[key]bool, len(g.Edges))
+	out := g
+	out.Edges = []GraphEdge{}
+	for _, edge := range g.Edges {
+		k := key{edge.Source, edge.Target, edge.Type}
+		if !seen[k] {
+			seen[k] = true
+			out.Edges = append(out.Edges, edge)
+		}
+	}
+	return out.RecomputeMetadata()
+}
+
+// NormalizeSymmetric returns a copy of g in which each edge of the
+// symmetric types points from the lower ID to the higher, keeping the
+// first edge for each pair and type in its original order, so A -> B and
+// B -> A become one edge. Edges of other types are kept as they are,
+// repeats included. Metadata is recomputed.
+func NormalizeSymmetric(g Graph, symmetricTypes []string) Graph {
+	type key struct{ source, target, typ string }
+	seen := make(map[key]bool)
+	out := g
+	out.Edges = []GraphEdge{}
+	for _, edge := range g.Edges {
+		if slices.Contains(symmetricTypes, edge.Type) {
+			if edge.Source > edge.Target {
+				edge.Source, edge.Target = edge.Target, edge.Source
+			}
+			k := key{edge.Source, edge.Target, edge.Type}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		}
+		out.Edges = append(out.Edges, edge)
+	}
+	return out.RecomputeMetadata()
+}
+
+// PlanEdgeChanges splits proposed into the edges that adding them to
+// existing would create and the ones it would skip, both in proposed order.
+// An edge is skipped when existing, or an earlier proposed edge, already
+// has its source, target and type, as Dedupe keys them; weights are not
+// compared.
+func PlanEdgeChanges(existing Graph, proposed []GraphEdge) (added []GraphEdge, skipped []GraphEdge) {
+	type key struct{ source, target, typ string }
+	seen := make(map[key]bool, len(existing.Edges)+len(proposed))
+	for _, edge := range existing.Edges {
+		seen[key{edge.Source, edge.Target, edge.Type}] = true
+	}
+	added, skipped = []GraphEdge{}, []GraphEdge{}
+	for _, edge := range proposed {
+		k := key{edge.Source, edge.Target, edge.Type}
+		if seen[k] {
+			skipped = append(skipped, edge)
+			continue
+		}
+		seen[k] = true
+		added = append(added, edge)
+	}
+	return added, skipped
+}
+
+// DropDangling returns a copy of g without the edges whose source or
+// target is not in index, with metadata recomputed, and the removed edges
+// in their original order
+func (g Graph) DropDangling(index PacksIndex) (Graph, []GraphEdge) {
+	known := index.ByID()
+	kept := []GraphEdge{}
+	removed := []GraphEdge{}
+	for _, edge := range g.Edges {
+		_, src := known[edge.Source]
+		_, dst := known[edge.Target]
+		if src && dst {
+			kept = append(kept, edge)
+		} else {
+			removed = append(removed, edge)
+		}
+	}
+	g.Edges = kept
+	return g.RecomputeMetadata(), removed
+}
+
+// Subgraph returns a copy of g with only the edges whose endpoints are
+// both in nodeIDs, with node and edge counts recomputed for the result
+func (g Graph) Subgraph(nodeIDs []string) Graph {
+	keep := make(map[string]bool, len(nodeIDs))
+	for _, id := range nodeIDs {
+		keep[id] = true
+	}
+
+	sub := g
+	sub.Edges = []GraphEdge{}
+	for _, edge := range g.Edges {
+		if keep[edge.Source] && keep[edge.Target] {
+			sub.Edges = append(sub.Edges, edge)
+		}
+	}
+	return sub.RecomputeMetadata()
+}
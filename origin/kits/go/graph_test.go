@@ -0,0 +1,1194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// cycleGraph is A-B-C-A with a tail C-D
+func cycleGraph() Graph {
+	return Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "C", Type: "related"},
+		{Source: "C", Target: "A", Type: "related"},
+		{Source: "C", Target: "D", Type: "child"},
+	}}
+}
+
+func TestBFS(t *testing.T) {
+	g := cycleGraph()
+
+	tests := []struct {
+		name  string
+		start string
+		depth int
+		want  []string
+	}{
+		{"depth zero", "A", 0, []string{"A"}},
+		{"one hop", "A", 1, []string{"A", "B", "C"}},
+		{"cycle not revisited", "A", 5, []string{"A", "B", "C", "D"}},
+		{"reverse edge", "D", 1, []string{"D", "C"}},
+		{"unknown start", "Z", 3, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.BFS(tt.start, tt.depth, Both); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BFS(%q, %d) = %v, want %v", tt.start, tt.depth, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	g := cycleGraph()
+	g.Edges = append(g.Edges, GraphEdge{Source: "X", Target: "Y", Type: "related"})
+
+	path, err := g.ShortestPath("A", "D")
+	if err != nil {
+		t.Fatalf("ShortestPath: %v", err)
+	}
+	if want := []string{"A", "C", "D"}; !reflect.DeepEqual(path, want) {
+		t.Errorf("path = %v, want %v", path, want)
+	}
+
+	if path, _ := g.ShortestPath("B", "B"); !reflect.DeepEqual(path, []string{"B"}) {
+		t.Errorf("same endpoints: got %v", path)
+	}
+
+	if _, err := g.ShortestPath("A", "X"); !errors.Is(err, ErrNoPath) {
+		t.Errorf("disconnected: err = %v, want ErrNoPath", err)
+	}
+}
+
+func TestBatchShortestPath(t *testing.T) {
+	g := cycleGraph()
+	g.Edges = append(g.Edges, GraphEdge{Source: "X", Target: "Y", Type: "related"})
+
+	var reports int
+	got := g.BatchShortestPathWithProgress([][2]string{{"A", "D"}, {"B", "B"}, {"A", "X"}, {"Q", "A"}}, func(done, total int) {
+		reports++
+	})
+	want := []PathResult{
+		{From: "A", To: "D", Found: true, Hops: 2, Path: []string{"A", "C", "D"}},
+		{From: "B", To: "B", Found: true, Hops: 0, Path: []string{"B"}},
+		{From: "A", To: "X", Hops: -1, Path: []string{}},
+		{From: "Q", To: "A", Hops: -1, Path: []string{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BatchShortestPath = %+v, want %+v", got, want)
+	}
+	if reports != len(want) {
+		t.Errorf("progress reported %d times, want %d", reports, len(want))
+	}
+}
+
+func TestExplainPath(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "depends_on"},
+		{Source: "C", Target: "B", Type: "child"},
+		{Source: "D", Target: "C"},
+		{Source: "C", Target: "D", Type: "mentions"},
+	}}
+	index := PacksIndex{Packs: []Pack{{ID: "A", Title: "Alpha"}, {ID: "B", Title: "Beta"}, {ID: "C"}}}
+
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{[]string{"A", "B", "C", "D"}, "Alpha — depends_on → Beta ← child — C — mentions → D"},
+		{[]string{"D", "C"}, "D — linked → C"},
+		{[]string{"A", "C"}, "Alpha — (no edge) → C"},
+		{[]string{"B"}, "Beta"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := g.ExplainPath(index, tt.path); got != tt.want {
+			t.Errorf("ExplainPath(%v) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSelfLoops(t *testing.T) {
+	loop := GraphEdge{Source: "A", Target: "A", Type: "related"}
+	g := Graph{Edges: []GraphEdge{loop, {Source: "A", Target: "B", Type: "related"}}}
+
+	if got := g.SelfLoops(); !reflect.DeepEqual(got, []GraphEdge{loop}) {
+		t.Errorf("SelfLoops = %v, want [%v]", got, loop)
+	}
+	if got := len(g.BuildAdjacency()["A"]); got != 2 {
+		t.Errorf("A has %d incident edges, want 2 (the loop once)", got)
+	}
+	if got := g.DegreeCentrality()["A"]; got != 2 {
+		t.Errorf("degree of A = %d, want 2", got)
+	}
+	if got := g.BFS("A", 10, Both); !reflect.DeepEqual(got, []string{"A", "B"}) {
+		t.Errorf("BFS from a self-looped node = %v, want [A B]", got)
+	}
+	only := Graph{Edges: []GraphEdge{loop}}
+	if got := only.BFS("A", 10, Outgoing, "related"); !reflect.DeepEqual(got, []string{"A"}) {
+		t.Errorf("BFS around a lone self-loop = %v, want [A]", got)
+	}
+}
+
+func TestLeaves(t *testing.T) {
+	g := cycleGraph()
+	g.Edges = append(g.Edges, GraphEdge{Source: "X", Target: "Y", Type: "related"}, GraphEdge{Source: "Y", Target: "Z", Type: "related"})
+
+	if got, want := g.Leaves(), []string{"D", "X", "Z"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Leaves = %v, want %v", got, want)
+	}
+	if got := (Graph{}).Leaves(); len(got) != 0 {
+		t.Errorf("empty graph leaves = %v", got)
+	}
+}
+
+func TestBuildAdjacency(t *testing.T) {
+	adj := cycleGraph().BuildAdjacency()
+
+	if got := len(adj["C"]); got != 3 {
+		t.Errorf("C has %d incident edges, want 3", got)
+	}
+	if got := len(adj["D"]); got != 1 {
+		t.Errorf("D has %d incident edges, want 1", got)
+	}
+}
+
+// syntheticGraph builds a ring of n nodes with a chord every tenth node
+func syntheticGraph(n int) Graph {
+	var g Graph
+	for i := 0; i < n; i++ {
+		src := fmt.Sprintf("N%05d", i)
+		g.Edges = append(g.Edges, GraphEdge{Source: src, Target: fmt.Sprintf("N%05d", (i+1)%n), Type: "related"})
+		if i%10 == 0 {
+			g.Edges = append(g.Edges, GraphEdge{Source: src, Target: fmt.Sprintf("N%05d", (i+n/2)%n), Type: "related"})
+		}
+	}
+	return g
+}
+
+// linearNeighbors is the pre-adjacency O(E) neighbor scan
+func linearNeighbors(g Graph, id string) []GraphEdge {
+	var out []GraphEdge
+	for _, edge := range g.Edges {
+		if edge.Source == id || edge.Target == id {
+			out = append(out, edge)
+		}
+	}
+	return out
+}
+
+func BenchmarkNeighborsLinearScan(b *testing.B) {
+	g := syntheticGraph(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearNeighbors(g, fmt.Sprintf("N%05d", i%10000))
+	}
+}
+
+func BenchmarkNeighborsAdjacency(b *testing.B) {
+	g := syntheticGraph(10000)
+	adj := g.BuildAdjacency()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = adj[fmt.Sprintf("N%05d", i%10000)]
+	}
+}
+
+func TestBuildTypedAdjacency(t *testing.T) {
+	g := cycleGraph()
+	typed := g.BuildTypedAdjacency()
+
+	if got := len(typed["related"]["C"]); got != 2 {
+		t.Errorf("C has %d related edges, want 2", got)
+	}
+	if _, ok := typed["related"]["D"]; ok {
+		t.Error("D has no related edges but is in the related adjacency")
+	}
+
+	// Flattening the types gives BuildAdjacency, up to order
+	flat := make(map[string][]GraphEdge)
+	for _, adj := range typed {
+		for id, edges := range adj {
+			flat[id] = append(flat[id], edges...)
+		}
+	}
+	want := g.BuildAdjacency()
+	for id := range want {
+		slices.SortFunc(flat[id], compareEdges)
+		slices.SortFunc(want[id], compareEdges)
+	}
+	if !reflect.DeepEqual(flat, want) {
+		t.Errorf("flattened typed adjacency = %v, want %v", flat, want)
+	}
+
+	if got := g.BFS("A", 5, Both, "child"); len(got) != 1 {
+		t.Errorf("BFS over child edges from A = %v, want just A", got)
+	}
+	if got := g.BFS("C", 5, Outgoing, "child"); !reflect.DeepEqual(got, []string{"C", "D"}) {
+		t.Errorf("BFS over child edges from C = %v, want [C D]", got)
+	}
+}
+
+// multiTypeGraph builds a ring of n nodes joined by t0 edges, with each
+// node also linked to others by one edge of each type t1 to t(types-1)
+func multiTypeGraph(n, types int) Graph {
+	var g Graph
+	for i := 0; i < n; i++ {
+		src := fmt.Sprintf("N%05d", i)
+		g.Edges = append(g.Edges, GraphEdge{Source: src, Target: fmt.Sprintf("N%05d", (i+1)%n), Type: "t0"})
+		for t := 1; t < types; t++ {
+			g.Edges = append(g.Edges, GraphEdge{Source: src, Target: fmt.Sprintf("N%05d", (i+37*t)%n), Type: fmt.Sprintf("t%d", t)})
+		}
+	}
+	return g
+}
+
+func BenchmarkTypedBFSFiltered(b *testing.B) {
+	g := multiTypeGraph(10000, 8)
+	adj := g.BuildAdjacency()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bfs(adj, fmt.Sprintf("N%05d", i%10000), 200, Both, []string{"t0"})
+	}
+}
+
+func BenchmarkTypedBFSIndexed(b *testing.B) {
+	g := multiTypeGraph(10000, 8)
+	adj := g.BuildTypedAdjacency()["t0"]
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bfs(adj, fmt.Sprintf("N%05d", i%10000), 200, Both, nil)
+	}
+}
+
+func TestConnectedComponents(t *testing.T) {
+	g := cycleGraph()
+	g.Edges = append(g.Edges,
+		GraphEdge{Source: "X", Target: "Y", Type: "related"},
+		GraphEdge{Source: "P", Target: "Q", Type: "related"},
+	)
+
+	want := [][]string{{"A", "B", "C", "D"}, {"P", "Q"}, {"X", "Y"}}
+	if got := g.ConnectedComponents(); !reflect.DeepEqual(got, want) {
+		t.Errorf("components = %v, want %v", got, want)
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "depends_on"},
+		{Source: "B", Target: "C", Type: "depends_on"},
+		{Source: "C", Target: "A", Type: "depends_on"},
+		{Source: "C", Target: "D", Type: "depends_on"},
+		{Source: "D", Target: "E", Type: "mentions"},
+		{Source: "E", Target: "D", Type: "mentions"},
+	}}
+
+	if got, want := g.FindCycles("depends_on"), [][]string{{"A", "B", "C"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("depends_on cycles = %v, want %v", got, want)
+	}
+	if got := g.FindCycles(""); len(got) != 2 {
+		t.Errorf("all-edge cycles = %v, want 2", got)
+	}
+	if got := g.FindCycles("parent"); len(got) != 0 {
+		t.Errorf("parent cycles = %v, want none", got)
+	}
+}
+
+func TestNeighborsDirection(t *testing.T) {
+	g := cycleGraph()
+
+	if got := g.Neighbors("C", Outgoing); len(got) != 2 {
+		t.Errorf("outgoing from C = %v, want 2 edges", got)
+	}
+	if got := g.Neighbors("C", Incoming); len(got) != 1 || got[0].Source != "B" {
+		t.Errorf("incoming to C = %v, want B -> C", got)
+	}
+	if got := g.Neighbors("C", Both); len(got) != 3 {
+		t.Errorf("both for C = %v, want 3 edges", got)
+	}
+}
+
+func TestBFSDirected(t *testing.T) {
+	g := cycleGraph()
+
+	if got, want := g.BFS("D", 3, Outgoing), []string{"D"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("outgoing from D = %v, want %v", got, want)
+	}
+	if got, want := g.BFS("D", 3, Incoming), []string{"D", "C", "B", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("incoming to D = %v, want %v", got, want)
+	}
+}
+
+func TestBFSEdgeTypes(t *testing.T) {
+	g := cycleGraph()
+
+	if got := g.Neighbors("C", Both, "child"); len(got) != 1 || got[0].Target != "D" {
+		t.Errorf("child edges of C = %v, want C -> D", got)
+	}
+	if got, want := g.BFS("D", 3, Both, "related"), []string{"D"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("related from D = %v, want %v", got, want)
+	}
+	if got, want := g.BFS("D", 3, Both, "child"), []string{"D", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("child from D = %v, want %v", got, want)
+	}
+	// The depth limit still applies under a type filter
+	if got := g.BFS("D", 1, Both, "child", "related"); len(got) != 2 {
+		t.Errorf("depth 1 from D = %v, want D and C", got)
+	}
+	if got := g.BFS("D", 2, Both, "child", "related"); len(got) != 4 {
+		t.Errorf("depth 2 from D = %v, want all four packs", got)
+	}
+}
+
+func TestDensityAndDiameter(t *testing.T) {
+	g := cycleGraph()
+	if got := g.Density(); math.Abs(got-1.0/3) > 1e-9 {
+		t.Errorf("Density = %v, want 1/3", got)
+	}
+	if d, err := g.Diameter(); err != nil || d != 2 {
+		t.Errorf("Diameter = %d, %v, want 2", d, err)
+	}
+
+	// A separate chain is longer than anything in the first component
+	g.Edges = append(g.Edges,
+		GraphEdge{Source: "W", Target: "X"}, GraphEdge{Source: "X", Target: "Y"}, GraphEdge{Source: "Y", Target: "Z"})
+	if d, _ := g.Diameter(); d != 3 {
+		t.Errorf("Diameter with a 3-edge chain = %d, want 3", d)
+	}
+
+	if _, err := (Graph{}).Diameter(); !errors.Is(err, ErrEmptyGraph) {
+		t.Errorf("empty graph: err = %v, want ErrEmptyGraph", err)
+	}
+	if got := (Graph{}).Density(); got != 0 {
+		t.Errorf("empty graph density = %v", got)
+	}
+}
+
+func TestComponentStats(t *testing.T) {
+	g := cycleGraph()
+	g.Edges = append(g.Edges,
+		GraphEdge{Source: "X", Target: "Y", Type: "related"},
+		GraphEdge{Source: "Y", Target: "X", Type: "related"},
+	)
+
+	got := g.ComponentStats()
+	want := []ComponentStat{
+		{Nodes: 4, Edges: 4, Hub: "C", HubDegree: 3},
+		{Nodes: 2, Edges: 2, Hub: "X", HubDegree: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ComponentStats = %+v, want %+v", got, want)
+	}
+}
+
+func TestAncestorChain(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "leaf", Target: "mid", Type: "child_of"},
+		{Source: "mid", Target: "root", Type: "child_of"},
+		{Source: "mid", Target: "root", Type: "child_of"},
+		{Source: "leaf", Target: "other", Type: "related"},
+		{Source: "twin", Target: "mid", Type: "child_of"},
+		{Source: "twin", Target: "root", Type: "child_of"},
+		{Source: "x", Target: "y", Type: "child_of"},
+		{Source: "y", Target: "x", Type: "child_of"},
+	}}
+
+	got, err := g.AncestorChain("leaf", "child_of")
+	if want := []string{"leaf", "mid", "root"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("AncestorChain(leaf) = %v, %v; want %v", got, err, want)
+	}
+	if got, err := g.AncestorChain("root", "child_of"); err != nil || !reflect.DeepEqual(got, []string{"root"}) {
+		t.Errorf("AncestorChain(root) = %v, %v; want just root", got, err)
+	}
+	if _, err := g.AncestorChain("twin", "child_of"); !errors.Is(err, ErrAmbiguousParent) {
+		t.Errorf("two parents: err = %v, want ErrAmbiguousParent", err)
+	}
+	if _, err := g.AncestorChain("x", "child_of"); !errors.Is(err, ErrCycleDetected) {
+		t.Errorf("loop: err = %v, want ErrCycleDetected", err)
+	}
+}
+
+func TestDegreeCentrality(t *testing.T) {
+	degree := cycleGraph().DegreeCentrality()
+
+	want := map[string]int{"A": 2, "B": 2, "C": 3, "D": 1}
+	if !reflect.DeepEqual(degree, want) {
+		t.Errorf("degree = %v, want %v", degree, want)
+	}
+	if got := rankByScore(degree); !reflect.DeepEqual(got, []string{"C", "A", "B", "D"}) {
+		t.Errorf("ranking = %v", got)
+	}
+}
+
+func TestBetweennessCentrality(t *testing.T) {
+	// Two triangles joined through D
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B"}, {Source: "B", Target: "C"}, {Source: "C", Target: "A"},
+		{Source: "C", Target: "D"}, {Source: "D", Target: "E"},
+		{Source: "E", Target: "F"}, {Source: "F", Target: "G"}, {Source: "G", Target: "E"},
+		{Source: "D", Target: "E"}, // parallel edges add no paths
+	}}
+
+	got := g.BetweennessCentrality()
+	want := map[string]float64{"A": 0, "B": 0, "C": 8, "D": 9, "E": 8, "F": 0, "G": 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("betweenness = %v, want %v", got, want)
+	}
+	if top := rankByScore(got)[0]; top != "D" {
+		t.Errorf("top bridge = %s, want D", top)
+	}
+
+	// Two equal shortest paths split the credit
+	square := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B"}, {Source: "B", Target: "C"},
+		{Source: "C", Target: "D"}, {Source: "D", Target: "A"},
+	}}
+	for id, score := range square.BetweennessCentrality() {
+		if score != 0.5 {
+			t.Errorf("square betweenness of %s = %v, want 0.5", id, score)
+		}
+	}
+}
+
+func TestEdgesOfType(t *testing.T) {
+	g := cycleGraph()
+
+	if got := g.EdgesOfType("child"); len(got) != 1 || got[0].Target != "D" {
+		t.Errorf("child edges = %v", got)
+	}
+	if got := g.EdgesOfType("nope"); len(got) != 0 {
+		t.Errorf("unknown type = %v, want none", got)
+	}
+	if got, want := g.EdgeTypeCounts(), map[string]int{"related": 3, "child": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("counts = %v, want %v", got, want)
+	}
+}
+
+func TestPrintTree(t *testing.T) {
+	var buf bytes.Buffer
+	cycleGraph().PrintTree(&buf, "A", 3)
+
+	want := `A
+  → related: B
+    → related: C
+      → related: A (seen)
+      → child: D
+  → related: C (seen)
+`
+	if buf.String() != want {
+		t.Errorf("tree =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	// A links to B and C; both link to D; C also links to E
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "B", Target: "D", Type: "related"},
+		{Source: "C", Target: "D", Type: "related"},
+		{Source: "C", Target: "E", Type: "related"},
+		{Source: "B", Target: "C", Type: "related"},
+	}}
+
+	if got, want := g.Suggestions("A"), []string{"D", "E"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suggestions(A) = %v, want %v", got, want)
+	}
+	if got := g.Suggestions("Z"); len(got) != 0 {
+		t.Errorf("Suggestions(Z) = %v, want none", got)
+	}
+}
+
+func TestBFSMulti(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B"}, {Source: "B", Target: "C"}, {Source: "C", Target: "D"},
+		{Source: "X", Target: "Y"}, {Source: "Y", Target: "Z"},
+	}}
+
+	got := g.BFSMulti([]string{"A", "X", "A", "Q"}, 1)
+	if want := []string{"A", "X", "B", "Y"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("BFSMulti depth 1 = %v, want %v", got, want)
+	}
+	got = g.BFSMulti([]string{"A", "D"}, 1)
+	if want := []string{"A", "D", "B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("overlapping frontiers = %v, want %v", got, want)
+	}
+	if got := g.BFSMulti(nil, 3); len(got) != 0 {
+		t.Errorf("no starts = %v", got)
+	}
+
+	g.Edges[1].Type = "child"
+	if got, want := g.BFSMulti([]string{"A"}, 3, "child"), []string{"A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("child edges only = %v, want %v", got, want)
+	}
+	if got, want := g.BFSMulti([]string{"B"}, 3, "child"), []string{"B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("child edges from B = %v, want %v", got, want)
+	}
+}
+
+func TestStrongNeighbors(t *testing.T) {
+	g := cycleGraph()
+	for _, tt := range []struct {
+		k    int
+		want []string
+	}{
+		{0, []string{"A", "B", "D"}},
+		{1, []string{"A", "B"}},
+		{2, []string{}},
+	} {
+		if got := g.StrongNeighbors("C", tt.k); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("StrongNeighbors(C, %d) = %v, want %v", tt.k, got, tt.want)
+		}
+	}
+}
+
+func TestTopoSort(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "app", Target: "lib", Type: "depends_on"},
+		{Source: "lib", Target: "core", Type: "depends_on"},
+		{Source: "app", Target: "core", Type: "depends_on"},
+		{Source: "docs", Target: "app", Type: "mentions"},
+	}}
+
+	order, err := g.TopoSort("depends_on")
+	if err != nil {
+		t.Fatalf("TopoSort: %v", err)
+	}
+	if want := []string{"core", "docs", "lib", "app"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+
+	g.Edges = append(g.Edges, GraphEdge{Source: "core", Target: "app", Type: "depends_on"})
+	if _, err := g.TopoSort("depends_on"); !errors.Is(err, ErrCycleDetected) {
+		t.Errorf("err = %v, want ErrCycleDetected", err)
+	}
+}
+
+func TestWeightedShortestPath(t *testing.T) {
+	// A-B-C-D costs 3 hops of 0.5; the direct A-D "mentions" edge costs 5
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "strongly_related", Weight: 0.5},
+		{Source: "B", Target: "C", Type: "strongly_related", Weight: 0.5},
+		{Source: "C", Target: "D", Type: "strongly_related", Weight: 0.5},
+		{Source: "A", Target: "D", Type: "mentions", Weight: 5},
+		{Source: "D", Target: "E", Type: "related"},
+	}}
+
+	path, cost, err := g.WeightedShortestPath("A", "E")
+	if err != nil {
+		t.Fatalf("WeightedShortestPath: %v", err)
+	}
+	if want := []string{"A", "B", "C", "D", "E"}; !reflect.DeepEqual(path, want) || cost != 2.5 {
+		t.Errorf("got %v (cost %g), want %v (cost 2.5)", path, cost, want)
+	}
+
+	if hops, _ := g.ShortestPath("A", "E"); len(hops) != 3 {
+		t.Errorf("unweighted path = %v, want 2 hops", hops)
+	}
+
+	g.Edges = append(g.Edges, GraphEdge{Source: "X", Target: "Y", Weight: -1})
+	if _, _, err := g.WeightedShortestPath("A", "E"); err == nil {
+		t.Error("expected error for negative weight")
+	}
+}
+
+func TestEdgeWeightDefault(t *testing.T) {
+	var g Graph
+	if err := json.Unmarshal([]byte(`{"edges":[{"source":"A","target":"B"},{"source":"B","target":"C","weight":2.5}]}`), &g); err != nil {
+		t.Fatal(err)
+	}
+	if g.Edges[0].Cost() != 1 || g.Edges[1].Cost() != 2.5 {
+		t.Errorf("costs = %g, %g; want 1, 2.5", g.Edges[0].Cost(), g.Edges[1].Cost())
+	}
+}
+
+func TestAddRemoveEdge(t *testing.T) {
+	g := cycleGraph()
+	snapshot := g
+
+	if err := g.AddEdge(GraphEdge{Source: "D", Target: "E", Type: "child"}); err != nil {
+		t.Fatalf("AddEdge: %v", err)
+	}
+	if err := g.AddEdge(GraphEdge{Source: "A", Target: "B", Type: "related"}); !errors.Is(err, ErrDuplicateEdge) {
+		t.Errorf("duplicate AddEdge: err = %v, want ErrDuplicateEdge", err)
+	}
+	if g.Metadata.NodeCount != 5 || g.Metadata.EdgeCount != 5 {
+		t.Errorf("after add: metadata = %+v, want 5 nodes and 5 edges", g.Metadata)
+	}
+	if got := g.Neighbors("E", Both); len(got) != 1 {
+		t.Errorf("neighbors of new node E = %v", got)
+	}
+
+	if !g.RemoveEdge(GraphEdge{Source: "C", Target: "D", Type: "child"}) {
+		t.Error("RemoveEdge(C -> D) = false, want true")
+	}
+	if g.RemoveEdge(GraphEdge{Source: "C", Target: "D", Type: "child"}) {
+		t.Error("removing a missing edge reported true")
+	}
+	if g.Metadata.NodeCount != 5 || g.Metadata.EdgeCount != 4 {
+		t.Errorf("after remove: metadata = %+v, want 5 nodes and 4 edges", g.Metadata)
+	}
+	if len(snapshot.Edges) != 4 || snapshot.Edges[3].Target != "D" {
+		t.Errorf("edits changed an earlier copy: %v", snapshot.Edges)
+	}
+}
+
+func TestExtractNeighborhood(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", Related: []string{"B", "D"}},
+		{ID: "B", Related: []string{"A", "C"}},
+		{ID: "C"},
+		{ID: "D"},
+		{ID: "E"},
+	}}
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "C", Type: "related"},
+		{Source: "C", Target: "D", Type: "child"},
+	}}
+
+	subIndex, subGraph := ExtractNeighborhood(index, graph, "A", 1)
+	want := []Pack{{ID: "A", Related: []string{"B"}}, {ID: "B", Related: []string{"A"}}}
+	if !reflect.DeepEqual(subIndex.Packs, want) || subIndex.Metadata.PackCount != 2 {
+		t.Errorf("index = %+v, want %+v with count 2", subIndex, want)
+	}
+	if len(subGraph.Edges) != 1 || subGraph.Metadata.NodeCount != 2 || subGraph.Metadata.EdgeCount != 1 {
+		t.Errorf("graph = %+v, want the A-B edge", subGraph)
+	}
+	if !reflect.DeepEqual(index.Packs[0].Related, []string{"B", "D"}) {
+		t.Errorf("ExtractNeighborhood modified the input related list: %v", index.Packs[0].Related)
+	}
+
+	if sub, _ := ExtractNeighborhood(index, graph, "E", 2); len(sub.Packs) != 1 || sub.Packs[0].ID != "E" {
+		t.Errorf("isolated pack neighborhood = %+v, want just E", sub.Packs)
+	}
+}
+
+func TestMinimumSpanningTree(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related", Weight: 4},
+		{Source: "B", Target: "C", Type: "related"},
+		{Source: "C", Target: "A", Type: "related", Weight: 2},
+		{Source: "C", Target: "D", Type: "child", Weight: 3},
+		{Source: "D", Target: "B", Type: "child", Weight: 3},
+		{Source: "X", Target: "Y", Type: "related"},
+		{Source: "Y", Target: "X", Type: "child"},
+		{Source: "Z", Target: "Z", Type: "related"},
+	}}
+
+	tree := g.MinimumSpanningTree()
+	want := []GraphEdge{
+		{Source: "B", Target: "C", Type: "related"},
+		{Source: "X", Target: "Y", Type: "related"},
+		{Source: "C", Target: "A", Type: "related", Weight: 2},
+		{Source: "C", Target: "D", Type: "child", Weight: 3},
+	}
+	if !reflect.DeepEqual(tree.Edges, want) {
+		t.Errorf("MST edges = %v, want %v", tree.Edges, want)
+	}
+	if tree.Metadata.NodeCount != 6 || tree.Metadata.EdgeCount != 4 {
+		t.Errorf("metadata = %+v, want 6 nodes and 4 edges", tree.Metadata)
+	}
+
+	rng := randomGraph(rand.New(rand.NewPCG(3, 4)), 80, 300)
+	forest := rng.MinimumSpanningTree()
+	if got, want := len(forest.Edges), forest.Metadata.NodeCount-len(forest.ConnectedComponents()); got != want {
+		t.Errorf("random forest has %d edges, want nodes minus components = %d", got, want)
+	}
+}
+
+func TestPlanEdgeChanges(t *testing.T) {
+	existing := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related", Weight: 2},
+		{Source: "B", Target: "C", Type: "child"},
+	}}
+	proposed := []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "C", Type: "related"},
+		{Source: "C", Target: "A", Type: "related"},
+		{Source: "B", Target: "C", Type: "related"},
+	}
+
+	added, skipped := PlanEdgeChanges(existing, proposed)
+	wantAdded := []GraphEdge{proposed[1], proposed[2]}
+	wantSkipped := []GraphEdge{proposed[0], proposed[3]}
+	if !reflect.DeepEqual(added, wantAdded) {
+		t.Errorf("added = %v, want %v", added, wantAdded)
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped = %v, want %v", skipped, wantSkipped)
+	}
+
+	added, skipped = PlanEdgeChanges(existing, nil)
+	if len(added) != 0 || len(skipped) != 0 || added == nil {
+		t.Errorf("empty proposal: added %v, skipped %v", added, skipped)
+	}
+}
+
+func TestDropDangling(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "A", Target: "Z", Type: "related"},
+		{Source: "Y", Target: "C", Type: "child"},
+	}}
+
+	cleaned, removed := g.DropDangling(PacksIndex{Packs: samplePacks()})
+	if len(cleaned.Edges) != 1 || cleaned.Metadata.NodeCount != 2 || cleaned.Metadata.EdgeCount != 1 {
+		t.Errorf("cleaned = %+v", cleaned)
+	}
+	if want := g.Edges[1:]; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+	if len(g.Edges) != 3 {
+		t.Error("DropDangling modified its receiver")
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "B", Target: "C", Type: "related"},
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "C", Type: "related", Weight: 2},
+		{Source: "B", Target: "C", Type: "child"},
+		{Source: "C", Target: "B", Type: "related"},
+		{Source: "A", Target: "B", Type: "related"},
+	}}
+
+	got := g.Dedupe()
+	want := []GraphEdge{
+		{Source: "B", Target: "C", Type: "related"},
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "C", Type: "child"},
+		{Source: "C", Target: "B", Type: "related"},
+	}
+	if !reflect.DeepEqual(got.Edges, want) {
+		t.Errorf("Dedupe edges = %v, want %v", got.Edges, want)
+	}
+	if got.Metadata.NodeCount != 3 || got.Metadata.EdgeCount != 4 {
+		t.Errorf("metadata = %+v, want 3 nodes and 4 edges", got.Metadata)
+	}
+	if len(g.Edges) != 6 {
+		t.Errorf("Dedupe modified the original graph")
+	}
+	g.SchemaVersion = SupportedSchemaVersion
+	if got := g.Dedupe(); got.SchemaVersion != SupportedSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", got.SchemaVersion, SupportedSchemaVersion)
+	}
+}
+
+func TestSubgraph(t *testing.T) {
+	sub := cycleGraph().Subgraph([]string{"A", "B", "D"})
+	if len(sub.Edges) != 1 || sub.Edges[0] != (GraphEdge{Source: "A", Target: "B", Type: "related"}) {
+		t.Errorf("edges = %+v, want only A-B", sub.Edges)
+	}
+	if sub.Metadata.NodeCount != 2 || sub.Metadata.EdgeCount != 1 {
+		t.Errorf("metadata = %+v, want 2 nodes, 1 edge", sub.Metadata)
+	}
+	if errs := CheckGraphCounts(sub); len(errs) != 0 {
+		t.Errorf("subgraph counts inconsistent: %v", errs)
+	}
+
+	if empty := cycleGraph().Subgraph(nil); empty.Edges == nil || len(empty.Edges) != 0 {
+		t.Errorf("empty subgraph edges = %#v, want []", empty.Edges)
+	}
+	versioned := cycleGraph()
+	versioned.SchemaVersion = SupportedSchemaVersion
+	if got := versioned.Subgraph([]string{"A", "B"}); got.SchemaVersion != SupportedSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", got.SchemaVersion, SupportedSchemaVersion)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	// A and B share neighbors X and Y; B also links Z
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "X"}, {Source: "A", Target: "Y"},
+		{Source: "B", Target: "X"}, {Source: "B", Target: "Y"}, {Source: "B", Target: "Z"},
+	}}
+
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"A", "B", 2.0 / 3},
+		{"B", "A", 2.0 / 3},
+		{"A", "A", 1},
+		{"X", "Y", 1},
+		{"A", "Z", 0},
+		{"Q", "R", 0},
+	}
+	for _, tt := range tests {
+		if got := g.Similarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("Similarity(%s, %s) = %g, want %g", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	if got := rankByScore(g.similarityScores("A")); !reflect.DeepEqual(got, []string{"B"}) {
+		t.Errorf("ranked similar to A = %v, want [B]", got)
+	}
+}
+
+func TestReferrers(t *testing.T) {
+	g := cycleGraph()
+	g.Edges = append(g.Edges,
+		GraphEdge{Source: "D", Target: "D", Type: "related"},
+		GraphEdge{Source: "A", Target: "D", Type: "related"},
+	)
+	if got := g.Referrers("D"); !reflect.DeepEqual(got, []string{"A", "C"}) {
+		t.Errorf("Referrers(D) = %v, want [A C]", got)
+	}
+	if got := g.Referrers("Z"); len(got) != 0 {
+		t.Errorf("Referrers(Z) = %v, want none", got)
+	}
+}
+
+// randomGraph returns a graph of the given number of random edges over n
+// nodes
+func randomGraph(rng *rand.Rand, n, edges int) Graph {
+	var g Graph
+	for i := 0; i < edges; i++ {
+		g.Edges = append(g.Edges, GraphEdge{
+			Source: fmt.Sprintf("N%d", rng.IntN(n)),
+			Target: fmt.Sprintf("N%d", rng.IntN(n)),
+			Type:   "related",
+		})
+	}
+	return g
+}
+
+// withWorkers runs fn with Workers set to n
+func withWorkers(n int, fn func()) {
+	old := Workers
+	Workers = n
+	defer func() { Workers = old }()
+	fn()
+}
+
+func TestCentralityParallelMatchesSerial(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	for trial := 0; trial < 20; trial++ {
+		g := randomGraph(rng, 1+rng.IntN(200), rng.IntN(600))
+
+		var rank, between map[string]float64
+		withWorkers(1, func() {
+			rank = g.PageRank(DefaultDamping, DefaultIterations)
+			between = g.BetweennessCentrality()
+		})
+		for _, workers := range []int{2, 7} {
+			withWorkers(workers, func() {
+				if got := g.PageRank(DefaultDamping, DefaultIterations); !reflect.DeepEqual(got, rank) {
+					t.Errorf("trial %d: PageRank with %d workers differs from serial", trial, workers)
+				}
+				if got := g.BetweennessCentrality(); !reflect.DeepEqual(got, between) {
+					t.Errorf("trial %d: betweenness with %d workers differs from serial", trial, workers)
+				}
+			})
+		}
+	}
+}
+
+func benchmarkCentrality(b *testing.B, workers int, fn func(Graph)) {
+	g := randomGraph(rand.New(rand.NewPCG(5, 6)), 3000, 9000)
+	withWorkers(workers, func() {
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			fn(g)
+		}
+	})
+}
+
+func pageRank(g Graph)    { g.PageRank(DefaultDamping, DefaultIterations) }
+func betweenness(g Graph) { g.BetweennessCentrality() }
+
+func BenchmarkPageRankSerial(b *testing.B)      { benchmarkCentrality(b, 1, pageRank) }
+func BenchmarkPageRankParallel(b *testing.B)    { benchmarkCentrality(b, 0, pageRank) }
+func BenchmarkBetweennessSerial(b *testing.B)   { benchmarkCentrality(b, 1, betweenness) }
+func BenchmarkBetweennessParallel(b *testing.B) { benchmarkCentrality(b, 0, betweenness) }
+
+func TestShortestPathBidirectionalMatchesBFS(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for trial := 0; trial < 200; trial++ {
+		n := 2 + rng.IntN(30)
+		g := randomGraph(rng, n, rng.IntN(2*n))
+		from, to := fmt.Sprintf("N%d", rng.IntN(n)), fmt.Sprintf("N%d", rng.IntN(n))
+
+		want, wantErr := g.ShortestPath(from, to)
+		got, err := g.ShortestPathBidirectional(from, to)
+		if (err == nil) != (wantErr == nil) {
+			t.Fatalf("trial %d %s->%s: err = %v, BFS err = %v", trial, from, to, err, wantErr)
+		}
+		if err != nil {
+			if !errors.Is(err, ErrNoPath) {
+				t.Fatalf("trial %d: err = %v, want ErrNoPath", trial, err)
+			}
+			continue
+		}
+		if len(got) != len(want) {
+			t.Fatalf("trial %d %s->%s: got %v, BFS %v", trial, from, to, got, want)
+		}
+		if got[0] != from || got[len(got)-1] != to {
+			t.Fatalf("trial %d: path %v does not run %s->%s", trial, got, from, to)
+		}
+		adj := g.BuildAdjacency()
+		for i := 1; i < len(got); i++ {
+			if _, ok := edgeBetween(adj, got[i-1], got[i]); !ok {
+				t.Fatalf("trial %d: no edge %s-%s in %v", trial, got[i-1], got[i], got)
+			}
+		}
+	}
+}
+
+func BenchmarkShortestPath(b *testing.B) {
+	g := syntheticGraph(10000)
+	for i := 0; i < b.N; i++ {
+		g.ShortestPath("N00000", "N05003")
+	}
+}
+
+func BenchmarkShortestPathBidirectional(b *testing.B) {
+	g := syntheticGraph(10000)
+	for i := 0; i < b.N; i++ {
+		g.ShortestPathBidirectional("N00000", "N05003")
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	// A star: the hub H outranks its leaves, which tie
+	g := Graph{Edges: []GraphEdge{
+		{Source: "H", Target: "L1"}, {Source: "H", Target: "L2"},
+		{Source: "H", Target: "L3"}, {Source: "L3", Target: "X"},
+	}}
+
+	rank := g.PageRank(DefaultDamping, DefaultIterations)
+	sum := 0.0
+	for _, score := range rank {
+		sum += score
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("scores sum to %g, want 1", sum)
+	}
+	if order := rankByScore(rank); order[0] != "H" {
+		t.Errorf("top ranked = %s, want H (%v)", order[0], rank)
+	}
+	if rank["L1"] != rank["L2"] || rank["L3"] <= rank["L1"] {
+		t.Errorf("leaf scores = %v", rank)
+	}
+
+	if got := (Graph{}).PageRank(DefaultDamping, DefaultIterations); len(got) != 0 {
+		t.Errorf("empty graph = %v", got)
+	}
+}
+
+func TestAllPaths(t *testing.T) {
+	g := cycleGraph()
+	g.Edges = append(g.Edges, GraphEdge{Source: "A", Target: "B", Type: "child"})
+
+	got := g.AllPaths("A", "D", 3)
+	want := [][]string{{"A", "C", "D"}, {"A", "B", "C", "D"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AllPaths(A, D, 3) = %v, want %v", got, want)
+	}
+	if got := g.AllPaths("A", "D", 2); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("AllPaths(A, D, 2) = %v, want %v", got, want[:1])
+	}
+	if got := g.AllPaths("A", "D", 1); len(got) != 0 {
+		t.Errorf("AllPaths(A, D, 1) = %v, want none", got)
+	}
+	if got := g.AllPaths("A", "A", 3); !reflect.DeepEqual(got, [][]string{{"A"}}) {
+		t.Errorf("AllPaths(A, A) = %v", got)
+	}
+}
+
+func TestAllPathsLimits(t *testing.T) {
+	// A complete graph on six nodes has 65 simple paths between two nodes
+	var g Graph
+	for i := range 6 {
+		for j := i + 1; j < 6; j++ {
+			g.Edges = append(g.Edges, GraphEdge{Source: fmt.Sprint(i), Target: fmt.Sprint(j), Type: "related"})
+		}
+	}
+
+	if paths, err := g.AllPathsContext(context.Background(), "0", "5", 5, 100); err != nil || len(paths) != 65 {
+		t.Errorf("under the cap: %d paths, %v; want 65", len(paths), err)
+	}
+	if _, err := g.AllPathsContext(context.Background(), "0", "5", 5, 64); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("path cap: err = %v, want ErrLimitExceeded", err)
+	}
+
+	// Parallel edges do not count against the cap as extra paths
+	parallel := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "x"}, {Source: "A", Target: "B", Type: "y"}, {Source: "B", Target: "A", Type: "z"},
+	}}
+	if paths, err := parallel.AllPathsContext(context.Background(), "A", "B", 2, 1); err != nil || len(paths) != 1 {
+		t.Errorf("parallel edges with a cap of 1: %v, %v; want the one path", paths, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := g.AllPathsContext(ctx, "0", "5", 5, 0)
+	if !errors.Is(err, ErrLimitExceeded) || !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled: err = %v, want ErrLimitExceeded wrapping context.Canceled", err)
+	}
+}
+
+func TestArticulationPoints(t *testing.T) {
+	// Two triangles joined through C, with a tail D-E and a parallel edge
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B"}, {Source: "B", Target: "C"}, {Source: "C", Target: "A"},
+		{Source: "C", Target: "D"}, {Source: "D", Target: "C"}, {Source: "D", Target: "F"},
+		{Source: "F", Target: "G"}, {Source: "G", Target: "D"},
+		{Source: "F", Target: "E"}, {Source: "E", Target: "E"},
+		{Source: "X", Target: "Y"},
+	}}
+	if got, want := g.ArticulationPoints(), []string{"C", "D", "F"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ArticulationPoints = %v, want %v", got, want)
+	}
+
+	rng := rand.New(rand.NewPCG(3, 4))
+	for trial := range 100 {
+		n := 2 + rng.IntN(20)
+		g := randomGraph(rng, n, rng.IntN(2*n))
+		if got, want := g.ArticulationPoints(), bruteForceCutVertices(g); !reflect.DeepEqual(got, want) {
+			t.Fatalf("trial %d: ArticulationPoints = %v, brute force %v\n%+v", trial, got, want, g.Edges)
+		}
+	}
+}
+
+// bruteForceCutVertices finds the nodes whose removal raises the component
+// count of the graph
+func bruteForceCutVertices(g Graph) []string {
+	components := func(without string) int {
+		var kept []string
+		for id := range g.BuildAdjacency() {
+			if id != without {
+				kept = append(kept, id)
+			}
+		}
+		sub := g.Subgraph(kept)
+		// Isolated nodes have no edges left in sub, so count them apart
+		n := len(sub.ConnectedComponents())
+		inSub := sub.BuildAdjacency()
+		for _, id := range kept {
+			if _, ok := inSub[id]; !ok {
+				n++
+			}
+		}
+		return n
+	}
+	base := components("")
+	points := []string{}
+	for _, id := range slices.Sorted(maps.Keys(g.BuildAdjacency())) {
+		if components(id) > base {
+			points = append(points, id)
+		}
+	}
+	return points
+}
+
+func TestReachSize(t *testing.T) {
+	// A -> B -> C -> A is a cycle with C -> D hanging off it; E -> E is a
+	// self-loop
+	g := cycleGraph()
+	g.Edges = append(g.Edges, GraphEdge{Source: "E", Target: "E", Type: "related"}, GraphEdge{Source: "D", Target: "D", Type: "related"})
+
+	tests := []struct {
+		id   string
+		dir  Direction
+		want int
+	}{
+		{"A", Outgoing, 3},
+		{"D", Outgoing, 0},
+		{"D", Incoming, 3},
+		{"A", Both, 3},
+		{"E", Outgoing, 0},
+		{"Z", Both, 0},
+	}
+	for _, tt := range tests {
+		if got := g.ReachSize(tt.id, tt.dir); got != tt.want {
+			t.Errorf("ReachSize(%s, %v) = %d, want %d", tt.id, tt.dir, got, tt.want)
+		}
+	}
+
+	want := map[string]int{"A": 3, "B": 3, "C": 3, "D": 0, "E": 0}
+	if got := g.ReachSizes(Outgoing); !maps.Equal(got, want) {
+		t.Errorf("ReachSizes(Outgoing) = %v, want %v", got, want)
+	}
+}
+
+func TestShortestPathTree(t *testing.T) {
+	g := cycleGraph()
+	g.Edges = append(g.Edges, GraphEdge{Source: "E", Target: "F", Type: "related"}, GraphEdge{Source: "D", Target: "D", Type: "related"})
+
+	want := map[string]string{"A": "", "B": "A", "C": "A", "D": "C"}
+	tree := g.ShortestPathTree("A")
+	if !maps.Equal(tree, want) {
+		t.Errorf("tree from A = %v, want %v", tree, want)
+	}
+	// Each parent is one hop closer to the root
+	for id, parent := range tree {
+		if parent == "" {
+			continue
+		}
+		if got, _ := g.ShortestPath("A", id); len(got) != len(mustPath(t, g, "A", parent))+1 {
+			t.Errorf("%s: parent %s is not one hop closer to A", id, parent)
+		}
+	}
+
+	if got := g.ShortestPathTree("Z"); got == nil || len(got) != 0 {
+		t.Errorf("tree from unknown root = %v, want empty", got)
+	}
+}
+
+// mustPath returns the shortest path from from to to, failing the test
+// if there is none
+func mustPath(t *testing.T, g Graph, from, to string) []string {
+	t.Helper()
+	path, err := g.ShortestPath(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNormalizeSymmetric(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "B", Target: "A", Type: "related", Weight: 2},
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "C", Target: "A", Type: "child"},
+		{Source: "C", Target: "A", Type: "child"},
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "C", Target: "A", Type: "related"},
+		{Source: "D", Target: "D", Type: "related"},
+	}}
+	before := slices.Clone(g.Edges)
+
+	got := NormalizeSymmetric(g, []string{"related"})
+	want := []GraphEdge{
+		{Source: "A", Target: "B", Type: "related", Weight: 2},
+		{Source: "C", Target: "A", Type: "child"},
+		{Source: "C", Target: "A", Type: "child"},
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "D", Target: "D", Type: "related"},
+	}
+	if !reflect.DeepEqual(got.Edges, want) {
+		t.Errorf("edges = %v, want %v", got.Edges, want)
+	}
+	if got.Metadata.EdgeCount != 5 || got.Metadata.NodeCount != 4 {
+		t.Errorf("metadata = %+v, want 5 edges and 4 nodes", got.Metadata)
+	}
+	if !reflect.DeepEqual(g.Edges, before) {
+		t.Error("NormalizeSymmetric modified its input")
+	}
+	if got := NormalizeSymmetric(g, nil); !reflect.DeepEqual(got.Edges, before) {
+		t.Errorf("no symmetric types changed the edges: %v", got.Edges)
+	}
+}
@@ -0,0 +1,70 @@
+// ORIGIN Go Kit - dataset hashing
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+)
+
+// DatasetHash returns the hex SHA-256 of a canonical serialization of the
+// packs and edges: packs sorted by ID with their related IDs and tags
+// sorted and their Extra fields re-encoded with sorted keys and no
+// whitespace, and edges sorted by source, target, type, weight and
+// attributes. Field and element order in the source files, including key
+// order inside nested Extra objects, does not change the hash; metadata
+// counts and schema_version are left out, since they describe the data
+// rather than being part of it.
+func DatasetHash(index PacksIndex, graph Graph) string {
+	packs := make([]Pack, len(index.Packs))
+	for i, p := range index.Packs {
+		p.Related = slices.Sorted(slices.Values(p.Related))
+		p.Tags = slices.Sorted(slices.Values(p.Tags))
+		p.Extra = canonicalExtra(p.Extra)
+		packs[i] = p
+	}
+	slices.SortFunc(packs, func(a, b Pack) int {
+		return cmp.Or(cmp.Compare(a.ID, b.ID), cmp.Compare(a.Title, b.Title), cmp.Compare(a.DisclosureTier, b.DisclosureTier),
+			slices.Compare(a.Related, b.Related), slices.Compare(a.Tags, b.Tags))
+	})
+
+	edges := slices.Clone(graph.Edges)
+	slices.SortFunc(edges, compareEdgeValues)
+
+	h := sha256.New()
+	// Encoding structs fixes the key order; errors are impossible for
+	// these types
+	enc := json.NewEncoder(h)
+	enc.Encode(packs)
+	enc.Encode(edges)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalExtra returns extra with each value re-encoded so that equal
+// JSON values have equal bytes: object keys sorted, whitespace removed and
+// numbers kept as written. A value that does not decode is kept as is.
+func canonicalExtra(extra map[string]json.RawMessage) map[string]json.RawMessage {
+	if extra == nil {
+		return nil
+	}
+	out := make(map[string]json.RawMessage, len(extra))
+	for key, raw := range extra {
+		out[key] = raw
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var v any
+		if dec.Decode(&v) != nil {
+			continue
+		}
+		if data, err := marshalJSON(v); err == nil {
+			out[key] = data
+		}
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDatasetHash(t *testing.T) {
+	var index1, index2 PacksIndex
+	var graph1, graph2 Graph
+	for _, x := range []struct {
+		data string
+		v    any
+	}{
+		{`{"metadata":{"pack_count":2},"packs":[{"id":"A","title":"Alpha","related":["C","B"]},{"id":"B","title":"Beta"}]}`, &index1},
+		{`{"packs":[{"title":"Beta","id":"B","related":[]},{"related":["B","C"],"id":"A","title":"Alpha"}]}`, &index2},
+		{`{"edges":[{"source":"A","target":"B","type":"related"},{"source":"B","target":"A","type":"related"}]}`, &graph1},
+		{`{"metadata":{"edge_count":2},"edges":[{"type":"related","target":"A","source":"B"},{"source":"A","target":"B","type":"related"}]}`, &graph2},
+	} {
+		if err := json.Unmarshal([]byte(x.data), x.v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := DatasetHash(index1, graph1)
+	if len(h) != 64 {
+		t.Fatalf("hash %q is not hex SHA-256", h)
+	}
+	if got := DatasetHash(index2, graph2); got != h {
+		t.Errorf("reordered dataset hashes to %s, want %s", got, h)
+	}
+
+	index2.Packs[0].Title = "Beta!"
+	if DatasetHash(index2, graph2) == h {
+		t.Error("changed title did not change the hash")
+	}
+	graph1.Edges[0].Weight = 2
+	if DatasetHash(index1, graph1) == h {
+		t.Error("changed weight did not change the hash")
+	}
+}
+
+func TestDatasetHashExtraKeyOrder(t *testing.T) {
+	var a, b PacksIndex
+	json.Unmarshal([]byte(`{"packs":[{"id":"A","claims":{"x":1,"y":{"p":[1,2],"q":"r"}}}]}`), &a)
+	json.Unmarshal([]byte(`{"packs":[{"id":"A","claims":{ "y":{"q":"r","p":[1, 2]}, "x":1 }}]}`), &b)
+
+	if DatasetHash(a, Graph{}) != DatasetHash(b, Graph{}) {
+		t.Error("reordered keys inside an Extra object changed the hash")
+	}
+	b.Packs[0].Extra["claims"] = json.RawMessage(`{"x":2,"y":{"p":[1,2],"q":"r"}}`)
+	if DatasetHash(a, Graph{}) == DatasetHash(b, Graph{}) {
+		t.Error("changed Extra value did not change the hash")
+	}
+}
@@ -0,0 +1,101 @@
+// ORIGIN Go Kit - pack health scores
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// HealthOptions are the points a pack loses for each problem. Scores
+// start at 100 and never go below 0.
+type HealthOptions struct {
+	// Orphan is lost by a pack with no edges
+	Orphan int
+	// Leaf is lost by a pack with exactly one edge
+	Leaf int
+	// EmptyTitle is lost by a pack whose title is blank
+	EmptyTitle int
+	// MissingTier is lost by a pack whose tier is empty or not in the
+	// tier order
+	MissingTier int
+	// RelatedMismatch is lost for each Related ID with no edge and each
+	// neighbor not listed in Related; see RelatedConsistency
+	RelatedMismatch int
+}
+
+// DefaultHealthOptions is the rubric HealthScore uses
+var DefaultHealthOptions = HealthOptions{
+	Orphan:          40,
+	Leaf:            10,
+	EmptyTitle:      20,
+	MissingTier:     20,
+	RelatedMismatch: 5,
+}
+
+// PackHealth is one pack's score and the problems that lowered it
+type PackHealth struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Score    int      `json:"score"`
+	Problems []string `json:"problems"`
+}
+
+// HealthScore rates pack id from 0 to 100 under DefaultHealthOptions;
+// higher is healthier. A pack missing from index scores 0.
+func (g Graph) HealthScore(index PacksIndex, id string) int {
+	p, ok := index.ByID()[id]
+	if !ok {
+		return 0
+	}
+	return packHealth(p, g.BuildAdjacency()[id], DefaultHealthOptions).Score
+}
+
+// HealthReport scores every pack in index under opts, lowest score first,
+// ties by ID
+func (g Graph) HealthReport(index PacksIndex, opts HealthOptions) []PackHealth {
+	adj := g.BuildAdjacency()
+	report := make([]PackHealth, len(index.Packs))
+	for i, p := range index.Packs {
+		report[i] = packHealth(p, adj[p.ID], opts)
+	}
+	slices.SortFunc(report, func(a, b PackHealth) int {
+		return cmp.Or(cmp.Compare(a.Score, b.Score), cmp.Compare(a.ID, b.ID))
+	})
+	return report
+}
+
+// packHealth scores p given its incident edges
+func packHealth(p Pack, incident []GraphEdge, opts HealthOptions) PackHealth {
+	h := PackHealth{ID: p.ID, Title: p.Title, Score: 100, Problems: []string{}}
+	penalize := func(points int, problem string) {
+		h.Score -= points
+		h.Problems = append(h.Problems, problem)
+	}
+
+	switch len(incident) {
+	case 0:
+		penalize(opts.Orphan, "orphan: no edges")
+	case 1:
+		penalize(opts.Leaf, "leaf: one edge")
+	}
+	if strings.TrimSpace(p.Title) == "" {
+		penalize(opts.EmptyTitle, "empty title")
+	}
+	if tierRank(p.DisclosureTier) < 0 {
+		penalize(opts.MissingTier, fmt.Sprintf("missing or unknown tier %q", p.DisclosureTier))
+	}
+	missing, extra := p.relatedConsistency(incident)
+	for _, id := range missing {
+		penalize(opts.RelatedMismatch, fmt.Sprintf("related %s has no edge", id))
+	}
+	for _, id := range extra {
+		penalize(opts.RelatedMismatch, fmt.Sprintf("neighbor %s is not in related", id))
+	}
+	h.Score = max(h.Score, 0)
+	return h
+}
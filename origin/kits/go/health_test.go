@@ -0,0 +1,47 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHealthScore(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", Title: "Alpha", DisclosureTier: "public", Related: []string{"B"}},
+		{ID: "B", Title: "Beta", DisclosureTier: "public", Related: []string{"A", "C"}},
+		{ID: "C", Title: " ", DisclosureTier: "public"},
+		{ID: "D", Title: "Delta"},
+	}}
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "C", Type: "child"},
+		{Source: "B", Target: "Z", Type: "child"},
+	}}
+
+	tests := map[string]int{
+		"A": 90, // leaf
+		"B": 95, // neighbor Z not in related
+		"C": 65, // leaf, empty title, neighbor B not in related
+		"D": 40, // orphan, missing tier
+		"Z": 0,  // not a pack
+	}
+	for id, want := range tests {
+		if got := graph.HealthScore(index, id); got != want {
+			t.Errorf("HealthScore(%s) = %d, want %d", id, got, want)
+		}
+	}
+
+	report := graph.HealthReport(index, DefaultHealthOptions)
+	if got := []string{report[0].ID, report[1].ID, report[2].ID, report[3].ID}; !slices.Equal(got, []string{"D", "C", "A", "B"}) {
+		t.Errorf("report order = %v, want lowest score first", got)
+	}
+	if !slices.Equal(report[0].Problems, []string{"orphan: no edges", `missing or unknown tier ""`}) {
+		t.Errorf("D problems = %q", report[0].Problems)
+	}
+
+	harsh := DefaultHealthOptions
+	harsh.Orphan = 200
+	if got := graph.HealthReport(index, harsh)[0]; got.ID != "D" || got.Score != 0 {
+		t.Errorf("harsh orphan weight: %+v, want D clamped to 0", got)
+	}
+}
@@ -0,0 +1,166 @@
+// ORIGIN Go Kit - usage and help
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// commandHelp describes a subcommand for help output
+type commandHelp struct {
+	name    string
+	args    string // synopsis of the arguments after the name
+	summary string
+	// flags is set for commands that parse their own flags, whose details
+	// help <name> prints from -h
+	flags bool
+}
+
+// commands lists every subcommand runCommand dispatches, by name
+var commands = []commandHelp{
+	{"backbone", "[-dot]", "minimum spanning forest as graph.json, or DOT", true},
+	{"backfill", "[-type=related] [-out=file [-apply]]", "graph.json built from the packs' related lists", true},
+	{"batch-path", "[-file=pairs.csv]", "shortest path for each from,target CSV row", true},
+	{"bridges", "", "top -limit packs by betweenness", false},
+	{"bundle", "[-out=file]", "index and graph in one JSON file", true},
+	{"central", "", "top -limit packs by degree", false},
+	{"closure", "[-type=depends_on] <id>", "everything a pack transitively depends on", true},
+	{"communities", "[-iterations=20] [-seed=s] [-sample=3]", "label-propagation communities", true},
+	{"components", "[-detail]", "connected components and their sizes", true},
+	{"crosstier", "[-lower=public] [-higher=a,b]", "edges linking lower-tier packs to higher tiers", true},
+	{"cutpoints", "", "packs whose removal disconnects the graph", false},
+	{"cycles", "-type=<t>", "directed cycles of one edge type", true},
+	{"demo", "", "short tour of the dist", false},
+	{"diff", "<oldDir> <newDir>", "pack and edge changes between two dists", false},
+	{"edges", "[-type=<t>] [-vocab]", "edges of one type, counts per type, or the types in use", true},
+	{"export", "<format> [-tier=a,b] [-attrs=k1,k2]", "dot, csv, edges-csv, adjacency, graphml, d3, deduped or subgraph", false},
+	{"extract", "[-depth=2] -out=<dir> <id>", "pack and its neighborhood as a standalone dist", true},
+	{"filter", "-tag=<t>", "packs carrying a tag", true},
+	{"fix-metadata", "[-dry-run]", "recompute graph.json node and edge counts", true},
+	{"hash", "", "SHA-256 of the sorted packs and edges", false},
+	{"health", "[-n=10]", "least healthy packs first", true},
+	{"help", "[command]", "this help, or one command's flags", false},
+	{"impact", "<id>", "what deleting a pack would drop, orphan or break", false},
+	{"incomplete", "", "packs with a blank id, title or tier, and what each lacks", false},
+	{"leaves", "", "packs with exactly one edge", false},
+	{"lineage", "[-type=parent] <id>", "breadcrumb from the root down to a pack", true},
+	{"list", "[-offset=n] [-page-size=n]", "page through the -tier packs", true},
+	{"lookup", "[-file=path]", "resolve newline-separated IDs in input order", true},
+	{"merge-related", "[-type=related] [-apply]", "add edges for related entries graph.json lacks", true},
+	{"metrics", "", "graph density, diameter and average degree", false},
+	{"namespaces", "", "ID namespaces with pack counts", false},
+	{"nearest", "[-tier=public] <id>", "closest pack of a tier, by hops", true},
+	{"neighbors", "[-min-shared=k] [-type=t] [-exclude-tier=t]... <id>", "incident edges with direction, type and title", true},
+	{"orphans", "", "packs with no edges and no related packs", false},
+	{"path", "[-weighted] <from> <to>", "shortest path between two packs", true},
+	{"paths", "[-depth=4] [-max-paths=1000] [-timeout=30s] <from> <to>", "every simple path up to -depth hops", true},
+	{"random", "[-n=5] [-seed=s]", "random sample of the -tier packs", true},
+	{"rank", "[-damping=0.85] [-iterations=50]", "top -limit packs by PageRank", true},
+	{"reach", "", "top -limit packs by how many packs their outgoing edges lead to", false},
+	{"reachable", "[-depth=2] [-type=t] [-exclude-tier=t]... <id>...", "packs within -depth hops of any given pack", true},
+	{"recommend", "[-n=5] [-walks=200] [-steps=4] [-seed=s] <id>", "packs most visited by random walks from a pack", true},
+	{"reconcile", "", "compare each pack's related list with the graph", false},
+	{"referrers", "<id>", "packs with an edge to a pack", false},
+	{"repl", "", "interactive shell over one load of the dataset", false},
+	{"report", "md", "Markdown wiki page of the dataset", false},
+	{"search", "[-fields=id,title] [-whole-word] [-case-sensitive] <query>", "substring search of titles or other fields", true},
+	{"serve", "[-addr=:8080] [-edit-token=t]", "JSON API over HTTP", true},
+	{"show", "[-expand] <id>", "pack fields and every neighbor", true},
+	{"similar", "<id>", "top -limit packs by shared-neighbor similarity", false},
+	{"spt", "<id>", "breadth-first tree from a pack as an outline", false},
+	{"stale", "[-older-than=90d]", "packs not updated recently, oldest first", true},
+	{"stats", "", "overview: counts, tiers, components, orphans, hubs", false},
+	{"suggest", "[-min-shared=k] <id>", "packs two hops away, ranked by shared neighbors", true},
+	{"tags", "", "distinct tags of the -tier packs, with counts", false},
+	{"tiers", "", "pack count per disclosure tier", false},
+	{"topo", "-type=<t>", "dependency-first ordering", true},
+	{"tree", "[-depth=n] <id>", "relationships as an indented tree", true},
+	{"validate", "[-tiers=a,b] [-fix [-yes]] [-checks=c] [-cache-file=f] ...", "check the dataset, failing on problems", true},
+	{"why-connected", "<from> <to>", "shortest path as prose", false},
+}
+
+// flagSet returns a new flag set for a subcommand. Problems and the flag
+// list printed for -h go to a.Err.
+func (a *app) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.Err)
+	fs.Usage = fs.PrintDefaults
+	return fs
+}
+
+// lookupCommand returns the help entry for name
+func lookupCommand(name string) (commandHelp, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return commandHelp{}, false
+}
+
+// defaultCommand returns the command line run when none is given:
+// ORIGIN_DEFAULT_COMMAND split into words if set, else help
+func defaultCommand() []string {
+	if args := strings.Fields(os.Getenv("ORIGIN_DEFAULT_COMMAND")); len(args) > 0 {
+		return args
+	}
+	return []string{"help"}
+}
+
+// printUsage writes the command list and the global flags to w
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: origin-kit [flags] <command> [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	width := 0
+	for _, c := range commands {
+		width = max(width, len(c.name))
+	}
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	out := flag.CommandLine.Output()
+	flag.CommandLine.SetOutput(w)
+	flag.PrintDefaults()
+	flag.CommandLine.SetOutput(out)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'origin-kit help <command>' for a command's arguments and flags.")
+}
+
+// cmdHelp prints the command list, or the usage and flags of one command
+func (a *app) cmdHelp(loader *Loader, args []string) error {
+	switch len(args) {
+	case 0:
+		printUsage(a.Out)
+		return nil
+	case 1:
+	default:
+		return fmt.Errorf("usage: help [command]")
+	}
+
+	c, ok := lookupCommand(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command %q; run help for the list", args[0])
+	}
+	fmt.Fprintf(a.Out, "Usage: origin-kit %s\n\n%s.\n", strings.TrimSpace(c.name+" "+c.args), capitalize(c.summary))
+	if !c.flags {
+		return nil
+	}
+	// The command's own flag set prints its flags for -h on a.Err
+	fmt.Fprintln(a.Out, "\nFlags:")
+	help := *a
+	help.Err = a.Out
+	if err := help.runCommand(loader, c.name, []string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCommandsTable(t *testing.T) {
+	if !slices.IsSortedFunc(commands, func(a, b commandHelp) int { return strings.Compare(a.name, b.name) }) {
+		t.Error("commands is not sorted by name")
+	}
+	// Every dist file is missing, so commands that run fail at once
+	loader := &Loader{FS: fstest.MapFS{}}
+	for _, c := range commands {
+		a, _, _ := testApp()
+		err := a.runCommand(loader, c.name, []string{"-h"})
+		if err != nil && err.Error() == fmt.Sprintf("unknown command %q", c.name) {
+			t.Errorf("%s is in the help table but runCommand does not know it", c.name)
+		}
+		if got := errors.Is(err, flag.ErrHelp); got != c.flags {
+			t.Errorf("%s -h: err = %v; want flag.ErrHelp = %v, as the table says", c.name, err, c.flags)
+		}
+	}
+}
+
+func TestHelp(t *testing.T) {
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{}}
+
+	if err := a.run(loader, nil); err != nil {
+		t.Fatalf("no command: %v", err)
+	}
+	for _, want := range []string{"Usage: origin-kit [flags] <command> [args]", "\n  cutpoints ", "\n  demo ", "-filter string"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("help output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := a.runCommand(loader, "help", []string{"batch-path"}); err != nil {
+		t.Fatalf("help batch-path: %v", err)
+	}
+	want := "Usage: origin-kit batch-path [-file=pairs.csv]\n\nShortest path for each from,target CSV row.\n\nFlags:\n  -file string\n"
+	if !strings.HasPrefix(out.String(), want) {
+		t.Errorf("help batch-path = %q, want it to start %q", out.String(), want)
+	}
+
+	if err := a.runCommand(loader, "help", []string{"nope"}); err == nil {
+		t.Error("help for an unknown command: want an error")
+	}
+}
+
+func TestDefaultCommand(t *testing.T) {
+	t.Setenv("ORIGIN_DEFAULT_COMMAND", "tiers")
+	a, out, _ := testApp()
+	loader := &Loader{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha","disclosure_tier":"public"}]}`)},
+	}}
+	if err := a.run(loader, nil); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if strings.Contains(out.String(), "Usage:") || !strings.Contains(out.String(), "public") {
+		t.Errorf("ORIGIN_DEFAULT_COMMAND=tiers output = %q, want the tier counts", out.String())
+	}
+}
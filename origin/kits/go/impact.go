@@ -0,0 +1,97 @@
+// ORIGIN Go Kit - deletion impact
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import "slices"
+
+// DeleteImpact is what removing one pack would do to a dataset
+type DeleteImpact struct {
+	ID string `json:"id"`
+	// DroppedEdges are the edges with the pack at either end, in graph
+	// order
+	DroppedEdges []GraphEdge `json:"dropped_edges"`
+	// NewOrphans are the packs that would be left with no edges and no
+	// related packs, in index order; see FindOrphans
+	NewOrphans []string `json:"new_orphans"`
+	// ComponentsBefore and ComponentsAfter count the connected components
+	// of every pack and edge endpoint, before and after the deletion
+	ComponentsBefore int `json:"components_before"`
+	ComponentsAfter  int `json:"components_after"`
+	// BrokenRelated are the packs whose related lists name the pack, in
+	// index order
+	BrokenRelated []string `json:"broken_related"`
+}
+
+// SplitsComponent reports whether the deletion would increase the number
+// of connected components
+func (d DeleteImpact) SplitsComponent() bool {
+	return d.ComponentsAfter > d.ComponentsBefore
+}
+
+// SimulateDelete reports the effect of removing pack id and its edges,
+// without changing index or graph. A pack counts as a new orphan if it is
+// not an orphan now but would have no edges left and no related packs
+// other than id.
+func SimulateDelete(index PacksIndex, graph Graph, id string) DeleteImpact {
+	impact := DeleteImpact{ID: id, DroppedEdges: []GraphEdge{}, NewOrphans: []string{}, BrokenRelated: []string{}}
+	var kept Graph
+	for _, edge := range graph.Edges {
+		if edge.Source == id || edge.Target == id {
+			impact.DroppedEdges = append(impact.DroppedEdges, edge)
+		} else {
+			kept.Edges = append(kept.Edges, edge)
+		}
+	}
+
+	before, after := graph.BuildAdjacency(), kept.BuildAdjacency()
+	for _, p := range index.Packs {
+		if p.ID == id {
+			continue
+		}
+		if slices.Contains(p.Related, id) {
+			impact.BrokenRelated = append(impact.BrokenRelated, p.ID)
+		}
+		wasOrphan := len(before[p.ID]) == 0 && len(p.Related) == 0
+		related := slices.DeleteFunc(slices.Clone(p.Related), func(r string) bool { return r == id })
+		if !wasOrphan && len(after[p.ID]) == 0 && len(related) == 0 {
+			impact.NewOrphans = append(impact.NewOrphans, p.ID)
+		}
+	}
+
+	nodes := make(map[string]bool, len(before)+len(index.Packs))
+	for nodeID := range before {
+		nodes[nodeID] = true
+	}
+	for _, p := range index.Packs {
+		nodes[p.ID] = true
+	}
+	impact.ComponentsBefore = countComponents(nodes, before)
+	delete(nodes, id)
+	impact.ComponentsAfter = countComponents(nodes, after)
+	return impact
+}
+
+// countComponents counts the undirected connected components of nodes
+// under adj, with nodes that have no edges each counting as one
+func countComponents(nodes map[string]bool, adj map[string][]GraphEdge) int {
+	seen := make(map[string]bool, len(nodes))
+	count := 0
+	for id := range nodes {
+		if seen[id] {
+			continue
+		}
+		count++
+		seen[id] = true
+		for queue := []string{id}; len(queue) > 0; queue = queue[1:] {
+			for _, edge := range adj[queue[0]] {
+				if other := otherEnd(edge, queue[0]); !seen[other] {
+					seen[other] = true
+					queue = append(queue, other)
+				}
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSimulateDelete(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", Related: []string{"B"}},
+		{ID: "B", Related: []string{"A"}},
+		{ID: "C"},
+		{ID: "D", Related: []string{"B", "A"}},
+		{ID: "E"},
+	}}
+	// A - B - C is a path, D hangs off B, E is already an orphan
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "C", Type: "child"},
+		{Source: "D", Target: "B", Type: "related"},
+	}}
+	before := slices.Clone(graph.Edges)
+
+	impact := SimulateDelete(index, graph, "B")
+	want := DeleteImpact{
+		ID:               "B",
+		DroppedEdges:     before,
+		NewOrphans:       []string{"A", "C"},
+		ComponentsBefore: 2,
+		ComponentsAfter:  4,
+		BrokenRelated:    []string{"A", "D"},
+	}
+	if !reflect.DeepEqual(impact, want) {
+		t.Errorf("delete B = %+v, want %+v", impact, want)
+	}
+	if !impact.SplitsComponent() {
+		t.Error("deleting B should split its component")
+	}
+	if !reflect.DeepEqual(graph.Edges, before) || !slices.Equal(index.Packs[0].Related, []string{"B"}) {
+		t.Error("SimulateDelete modified its input")
+	}
+
+	// A leaf leaves the component count alone; E was an orphan already
+	impact = SimulateDelete(index, graph, "C")
+	if len(impact.DroppedEdges) != 1 || len(impact.NewOrphans) != 0 || impact.SplitsComponent() {
+		t.Errorf("delete C = %+v", impact)
+	}
+	if impact.ComponentsBefore != 2 || impact.ComponentsAfter != 2 {
+		t.Errorf("delete C: components %d -> %d, want 2 -> 2", impact.ComponentsBefore, impact.ComponentsAfter)
+	}
+
+	// Deleting an orphan removes its own component
+	impact = SimulateDelete(index, graph, "E")
+	if len(impact.DroppedEdges) != 0 || impact.ComponentsAfter != 1 || impact.SplitsComponent() {
+		t.Errorf("delete E = %+v", impact)
+	}
+}
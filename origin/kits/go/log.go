@@ -0,0 +1,50 @@
+// ORIGIN Go Kit - diagnostic logging
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Log formats accepted by newLogger
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// discardLogger stands in for a nil logger
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// newLogger returns a logger writing to w. Verbosity 0 logs warnings only,
+// 1 adds info events such as loads, and 2 or more adds debug detail.
+func newLogger(w io.Writer, verbosity int, format string) (*slog.Logger, error) {
+	level := slog.LevelWarn
+	switch {
+	case verbosity >= 2:
+		level = slog.LevelDebug
+	case verbosity == 1:
+		level = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case LogFormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want %s or %s)", format, LogFormatText, LogFormatJSON)
+	}
+}
+
+// log returns the loader's logger, or one that discards everything
+func (l *Loader) log() *slog.Logger {
+	if l.Logger != nil {
+		return l.Logger
+	}
+	return discardLogger
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoaderLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, 1, LogFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := &Loader{Logger: logger, FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A"},{"id":"B"}]}`)},
+	}}
+
+	if _, err := loader.LoadIndex(); err != nil {
+		t.Fatalf("LoadIndex: %v", err)
+	}
+	if _, err := loader.LoadGraph(); err == nil {
+		t.Fatal("LoadGraph succeeded without a graph file")
+	}
+	got := buf.String()
+	if !strings.Contains(got, `"msg":"loaded index"`) || !strings.Contains(got, `"packs":2`) {
+		t.Errorf("log = %s, want a loaded index event with 2 packs", got)
+	}
+	if strings.Contains(got, "loading graph failed") {
+		t.Errorf("debug event logged at verbosity 1: %s", got)
+	}
+}
+
+func TestNewLoggerFormat(t *testing.T) {
+	if _, err := newLogger(&bytes.Buffer{}, 0, "xml"); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
@@ -6,112 +6,465 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 )
 
-const ATTRIBUTION = "Ande + Kai (OI) + Whānau (OIs)"
+// defaultDist is the dist directory relative to kits/go
+var defaultDist = filepath.Join("..", "..", "knowledge", "dist")
 
-type Pack struct {
-	ID             string   `json:"id"`
-	Title          string   `json:"title"`
-	DisclosureTier string   `json:"disclosure_tier"`
-	Related        []string `json:"related"`
+var (
+	tierFlag      = flag.String("tier", "public", "comma-separated disclosure tiers to list, or \"all\"")
+	limitFlag     = flag.Int("limit", 3, "maximum number of entries to print")
+	embeddedFlag  = flag.Bool("embedded", false, "read the dist compiled into the binary")
+	jsonFlag      = flag.Bool("json", false, "emit command output as JSON")
+	idsOnlyFlag   = flag.Bool("ids-only", false, "print only pack IDs, one per line, for listing commands")
+	csvFlag       = flag.Bool("csv", false, "emit pack and edge listings as CSV")
+	ndjsonFlag    = flag.Bool("ndjson", false, "write listings as one JSON object per line")
+	watchFlag     = flag.Bool("watch", false, "re-run the command whenever the dist files change")
+	tierOrderFlag = flag.String("tier-order", "", "comma-separated disclosure tiers, least restricted first (default public,internal,restricted,secret)")
+	strictFlag    = flag.Bool("strict", false, "fail if the dataset has any validation problem")
+	indexFileFlag = flag.String("index-file", IndexFile, "name of the packs index file in the dist")
+	shardsFlag    = flag.String("index-shards", "", "read the index from the shard files `prefix`.NNN.json instead of -index-file")
+	graphFileFlag = flag.String("graph-file", GraphFile, "name of the graph file in the dist")
+	sortFlag      = flag.String("sort", SortByID, "sort pack listings by id, title or tier")
+	verboseFlag   = flag.Bool("v", false, "log loads and validation findings to stderr")
+	debugFlag     = flag.Bool("vv", false, "like -v, plus debug detail")
+	logFormatFlag = flag.String("log-format", LogFormatText, "log format: text or json")
+	noColorFlag   = flag.Bool("no-color", false, "disable colored output (also disabled by NO_COLOR or when stdout is not a terminal)")
+	prefixFlag    = flag.String("prefix", "", "scope every command to packs whose IDs start with this, such as core/")
+	relatedFlag   = flag.Bool("include-related", false, "also treat each pack's related IDs as related edges")
+	workersFlag   = flag.Int("workers", runtime.NumCPU(), "goroutines for PageRank and betweenness (rank, bridges)")
+	quietFlag     = flag.Bool("quiet", false, "do not show progress for long computations on stderr")
+	symmetricFlag = flag.String("normalize-symmetric", "", "comma-separated edge types to fold to one direction (lower ID first), dropping the reverse duplicates")
+	graphOnlyFlag = flag.Bool("graph-only", false, "build placeholder packs from the graph's nodes instead of reading an index file")
+	titlesFlag    = flag.Bool("with-titles", true, "show titles beside the IDs of neighbors and paths in text output")
+	filterFlag    = flag.String("filter", "", "only list packs matching this expression, such as \"tier=public AND tag~core\" (pack listing commands only)")
+)
+
+// distPath returns ORIGIN_DIST if set, else the default relative path
+func distPath() string {
+	if p := os.Getenv("ORIGIN_DIST"); p != "" {
+		return p
+	}
+	return defaultDist
 }
 
-type PacksIndex struct {
-	Metadata struct {
-		PackCount int `json:"pack_count"`
-	} `json:"metadata"`
-	Packs []Pack `json:"packs"`
+// newLoader returns a loader for the embedded dist or the dist on disk,
+// reading the files named by -index-file and -graph-file and honoring
+// -include-related
+func newLoader() *Loader {
+	loader := NewLoader(distPath())
+	if *embeddedFlag {
+		loader = &Loader{FS: embeddedDist()}
+	}
+	loader.IndexName = *indexFileFlag
+	loader.GraphName = *graphFileFlag
+	loader.IndexShards = *shardsFlag
+	loader.IncludeRelated = *relatedFlag
+	loader.Prefix = *prefixFlag
+	loader.GraphOnly = *graphOnlyFlag
+	loader.SymmetricTypes = splitList(*symmetricFlag)
+	return loader
 }
 
-type GraphEdge struct {
-	Source string `json:"source"`
-	Target string `json:"target"`
-	Type   string `json:"type"`
+// verbosity returns the log verbosity selected by -v and -vv
+func verbosity() int {
+	switch {
+	case *debugFlag:
+		return 2
+	case *verboseFlag:
+		return 1
+	}
+	return 0
 }
 
-type Graph struct {
-	Metadata struct {
-		NodeCount int `json:"node_count"`
-		EdgeCount int `json:"edge_count"`
-	} `json:"metadata"`
-	Edges []GraphEdge `json:"edges"`
+// outputMode returns the output format selected by -json, -ids-only,
+// -csv and -ndjson
+func outputMode() outputFormat {
+	switch {
+	case *jsonFlag:
+		return formatJSON
+	case *idsOnlyFlag:
+		return formatIDs
+	case *csvFlag:
+		return formatCSV
+	case *ndjsonFlag:
+		return formatNDJSON
+	}
+	return formatText
 }
 
-func loadJSON(filename string, v interface{}) error {
-	basePath, _ := os.Getwd()
-	fullPath := filepath.Join(basePath, "..", "..", "knowledge", "dist", filename)
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		return err
+// app holds the streams a run reads from and reports to. main wires them
+// to stdin, stdout and stderr; tests substitute buffers.
+type app struct {
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
+	// Output writes command results to Out; nil selects one from the
+	// output flags on first use
+	Output Formatter
+}
+
+// output returns the app's Formatter
+func (a *app) output() Formatter {
+	if a.Output == nil {
+		a.Output = newFormatter(a.Out, outputMode())
 	}
-	return json.Unmarshal(data, v)
+	return a.Output
 }
 
 func main() {
-	fmt.Println("ORIGIN Kit - Go")
-	fmt.Println("===============")
-	fmt.Printf("Attribution: %s\n\n", ATTRIBUTION)
-
-	// Load index
-	var index PacksIndex
-	if err := loadJSON("packs.index.json", &index); err != nil {
-		fmt.Printf("Error loading index: %v\n", err)
-		return
+	flag.Usage = func() { printUsage(flag.CommandLine.Output()) }
+	flag.Parse()
+	args := flag.Args()
+	SetTierOrder(splitList(*tierOrderFlag))
+	colorOutput = useColor(*noColorFlag, os.Stdout)
+	withTitles = *titlesFlag && outputMode() == formatText
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "limit" && *limitFlag >= 0 {
+			neighborLimit = *limitFlag
+		}
+	})
+	progressOutput = !*quietFlag && isTerminal(os.Stderr)
+	Workers = *workersFlag
+
+	a := &app{In: os.Stdin, Out: os.Stdout, Err: os.Stderr}
+	if *filterFlag != "" {
+		var err error
+		if packFilter, err = ParseFilter(*filterFlag); err != nil {
+			fmt.Fprintf(a.Err, "Error: -filter: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	formats := 0
+	for _, set := range []bool{*jsonFlag, *idsOnlyFlag, *csvFlag, *ndjsonFlag} {
+		if set {
+			formats++
+		}
+	}
+	if formats > 1 {
+		fmt.Fprintln(a.Err, "Error: use only one of -json, -ids-only, -csv and -ndjson")
+		os.Exit(1)
 	}
+	a.Output = newFormatter(a.Out, outputMode())
+	logger, err := newLogger(a.Err, verbosity(), *logFormatFlag)
+	if err != nil {
+		fmt.Fprintf(a.Err, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	loader := newLoader()
+	loader.Logger = logger
+	loader.StrictSchema = *strictFlag
 
-	// Load graph
-	var graph Graph
-	if err := loadJSON("graph.json", &graph); err != nil {
-		fmt.Printf("Error loading graph: %v\n", err)
+	if *watchFlag {
+		if loader.FS != nil {
+			fmt.Fprintln(a.Err, "Error: -watch cannot be used with -embedded")
+			os.Exit(1)
+		}
+		if isURL(loader.BasePath) {
+			fmt.Fprintln(a.Err, "Error: -watch needs a local dist directory, not a URL")
+			os.Exit(1)
+		}
+		a.runWatched(loader.distFiles(), func() error { return a.run(loader, args) })
 		return
 	}
 
-	fmt.Printf("Loaded %d packs from index.\n", len(index.Packs))
-	fmt.Printf("Loaded graph with %d nodes, %d edges.\n\n",
-		graph.Metadata.NodeCount, graph.Metadata.EdgeCount)
+	if err := a.run(loader, args); err != nil {
+		// A subcommand's -h has already printed its flags
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintf(a.Err, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
 
-	// Filter to public tier
-	var publicPacks []Pack
-	for _, p := range index.Packs {
-		if p.DisclosureTier == "public" {
-			publicPacks = append(publicPacks, p)
+// run checks the dataset and then runs the subcommand in args, or the
+// default command when args is empty. Help needs no dataset and validate
+// reports the problems itself, so they run without the check; fix-metadata
+// repairs a dataset -strict would reject, so its problems are only
+// warnings.
+func (a *app) run(loader *Loader, args []string) error {
+	if len(args) == 0 {
+		args = defaultCommand()
+	}
+	if err := checkFilterCommand(args[0]); err != nil {
+		return err
+	}
+	// Share one read of each dist file between the check and the command
+	loader = memoize(loader)
+	switch args[0] {
+	case "help", "validate":
+	case "fix-metadata":
+		if err := a.checkDataset(loader, false); err != nil {
+			return err
 		}
+	default:
+		if err := a.checkDataset(loader, *strictFlag); err != nil {
+			return err
+		}
+	}
+	return a.runCommand(loader, args[0], args[1:])
+}
+
+// checkDataset runs Validate and ValidateTiers on the loader's dataset.
+// Problems are errors when strict and warnings on a.Err otherwise. A
+// missing index is left for the command to report, and a missing graph
+// limits the check to the index.
+func (a *app) checkDataset(loader *Loader, strict bool) error {
+	index, err := loader.LoadIndex()
+	if err != nil {
+		return nil
 	}
+	graph, _ := loader.LoadGraph()
 
-	fmt.Printf("Public tier packs (%d):\n", len(publicPacks))
-	for i, p := range publicPacks {
-		if i >= 3 {
-			fmt.Printf("  ... and %d more\n", len(publicPacks)-3)
+	errs := ValidateWithProgress(index, graph, newProgress(a.Err, "validating"))
+	errs = append(errs, ValidateTiers(index.Packs, Tiers())...)
+	for _, e := range errs {
+		loader.log().Debug("validation finding", "problem", e.Error())
+	}
+	loader.log().Info("validated dataset", "problems", len(errs))
+	if len(errs) == 0 {
+		return nil
+	}
+
+	label := "Warning"
+	if strict {
+		label = "Problem"
+	}
+	for _, e := range errs {
+		fmt.Fprintf(a.Err, "%s: %v\n", label, e)
+	}
+	if strict {
+		return fmt.Errorf("strict: dataset has %d problems", len(errs))
+	}
+	return nil
+}
+
+// runWatched runs run now and again after each change to the files in
+// paths, until interrupted
+func (a *app) runWatched(paths []string, run func() error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rerun := func() {
+		if err := run(); err != nil {
+			fmt.Fprintf(a.Err, "Error: %v\n", err)
+		}
+	}
+
+	rerun()
+	watchFiles(ctx, paths, func() {
+		fmt.Fprintf(a.Out, "\n--- %s ---\n", time.Now().Format(time.RFC3339))
+		rerun()
+	})
+}
+
+// runCommand dispatches a subcommand by name
+func (a *app) runCommand(loader *Loader, name string, args []string) error {
+	switch name {
+	case "hash":
+		return a.cmdHash(loader, args)
+	case "health":
+		return a.cmdHealth(loader, args)
+	case "help":
+		return a.cmdHelp(loader, args)
+	case "impact":
+		return a.cmdImpact(loader, args)
+	case "incomplete":
+		return a.cmdIncomplete(loader, args)
+	case "leaves":
+		return a.cmdLeaves(loader, args)
+	case "lineage":
+		return a.cmdLineage(loader, args)
+	case "list":
+		return a.cmdList(loader, args)
+	case "lookup":
+		return a.cmdLookup(loader, args)
+	case "merge-related":
+		return a.cmdMergeRelated(loader, args)
+	case "metrics":
+		return a.cmdMetrics(loader, args)
+	case "neighbors":
+		return a.cmdNeighbors(loader, args)
+	case "namespaces":
+		return a.cmdNamespaces(loader, args)
+	case "nearest":
+		return a.cmdNearest(loader, args)
+	case "orphans":
+		return a.cmdOrphans(loader, args)
+	case "path":
+		return a.cmdPath(loader, args)
+	case "paths":
+		return a.cmdPaths(loader, args)
+	case "backbone":
+		return a.cmdBackbone(loader, args)
+	case "backfill":
+		return a.cmdBackfill(loader, args)
+	case "batch-path":
+		return a.cmdBatchPath(loader, args)
+	case "bridges":
+		return a.cmdBridges(loader, args)
+	case "bundle":
+		return a.cmdBundle(loader, args)
+	case "central":
+		return a.cmdCentral(loader, args)
+	case "closure":
+		return a.cmdClosure(loader, args)
+	case "communities":
+		return a.cmdCommunities(loader, args)
+	case "components":
+		return a.cmdComponents(loader, args)
+	case "crosstier":
+		return a.cmdCrossTier(loader, args)
+	case "cutpoints":
+		return a.cmdCutpoints(loader, args)
+	case "cycles":
+		return a.cmdCycles(loader, args)
+	case "demo":
+		return a.demo(loader)
+	case "diff":
+		return a.cmdDiff(loader, args)
+	case "edges":
+		return a.cmdEdges(loader, args)
+	case "extract":
+		return a.cmdExtract(loader, args)
+	case "export":
+		return a.cmdExport(loader, args)
+	case "filter":
+		return a.cmdFilter(loader, args)
+	case "fix-metadata":
+		return a.cmdFixMetadata(loader, args)
+	case "random":
+		return a.cmdRandom(loader, args)
+	case "recommend":
+		return a.cmdRecommend(loader, args)
+	case "rank":
+		return a.cmdRank(loader, args)
+	case "reach":
+		return a.cmdReach(loader, args)
+	case "reachable":
+		return a.cmdReachable(loader, args)
+	case "reconcile":
+		return a.cmdReconcile(loader, args)
+	case "referrers":
+		return a.cmdReferrers(loader, args)
+	case "report":
+		return a.cmdReport(loader, args)
+	case "repl":
+		return a.runREPL(loader, a.In)
+	case "search":
+		return a.cmdSearch(loader, args)
+	case "serve":
+		return a.cmdServe(loader, args)
+	case "show":
+		return a.cmdShow(loader, args)
+	case "similar":
+		return a.cmdSimilar(loader, args)
+	case "spt":
+		return a.cmdSPT(loader, args)
+	case "stale":
+		return a.cmdStale(loader, args)
+	case "stats":
+		return a.cmdStats(loader, args)
+	case "suggest":
+		return a.cmdSuggest(loader, args)
+	case "tags":
+		return a.cmdTags(loader, args)
+	case "tiers":
+		return a.cmdTiers(loader, args)
+	case "topo":
+		return a.cmdTopo(loader, args)
+	case "tree":
+		return a.cmdTree(loader, args)
+	case "validate":
+		return a.cmdValidate(loader, args)
+	case "why-connected":
+		return a.cmdWhyConnected(loader, args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// capitalize upper-cases the first letter of s
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// demo loads the dist and prints a short tour of the packs. An empty
+// -tier is a usage error and an unknown -sort is returned; load problems
+// are printed as part of the tour.
+func (a *app) demo(loader *Loader) error {
+	tiers := splitList(*tierFlag)
+	if len(tiers) == 0 {
+		return fmt.Errorf("usage: demo needs -tier=<tier>[,<tier>...] or -tier=all")
+	}
+
+	fmt.Fprintln(a.Out, "ORIGIN Kit - Go")
+	fmt.Fprintln(a.Out, "===============")
+	fmt.Fprintf(a.Out, "Attribution: %s\n\n", ATTRIBUTION)
+
+	// Load index, then graph; the tour still lists packs without a graph
+	index, err := loader.LoadIndex()
+	if err != nil {
+		fmt.Fprintf(a.Out, "Error %v\n", err)
+		return nil
+	}
+	fmt.Fprintf(a.Out, "Loaded %d packs from index.\n", len(index.Packs))
+
+	graph, graphErr := loader.LoadGraph()
+	if graphErr != nil {
+		fmt.Fprintf(a.Out, "Graph unavailable: %v\n\n", graphErr)
+	} else {
+		fmt.Fprintf(a.Out, "Loaded graph with %d nodes, %d edges.\n\n",
+			graph.Metadata.NodeCount, graph.Metadata.EdgeCount)
+	}
+
+	// Filter by tier
+	tierPacks := filterListing(FilterByTier(index.Packs, tiers))
+	if err := SortPacks(tierPacks, *sortFlag); err != nil {
+		return err
+	}
+	limit := *limitFlag
+
+	switch {
+	case slices.Contains(tiers, TierAll):
+		fmt.Fprintf(a.Out, "All packs (%d):\n", len(tierPacks))
+	case len(tiers) == 1:
+		fmt.Fprintf(a.Out, "%s tier packs (%d):\n", capitalize(tiers[0]), len(tierPacks))
+	default:
+		fmt.Fprintf(a.Out, "Packs in tiers %s (%d):\n", strings.Join(tiers, ", "), len(tierPacks))
+	}
+	for i, p := range tierPacks {
+		if i >= limit {
+			fmt.Fprintf(a.Out, "  ... and %d more\n", len(tierPacks)-limit)
 			break
 		}
-		fmt.Printf("  - %s: %s\n", p.ID, p.Title)
+		fmt.Fprintf(a.Out, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title))
 	}
 
 	// Traverse from first pack
-	if len(index.Packs) > 0 {
+	if graphErr == nil && len(index.Packs) > 0 {
 		first := index.Packs[0]
-		fmt.Printf("\nTraversing from %s (%s):\n", first.ID, first.Title)
-
-		count := 0
-		for _, edge := range graph.Edges {
-			if edge.Source == first.ID || edge.Target == first.ID {
-				otherID := edge.Target
-				if edge.Source != first.ID {
-					otherID = edge.Source
-				}
-				fmt.Printf("  → %s: %s\n", edge.Type, otherID)
-				count++
-				if count >= 3 {
-					break
-				}
-			}
-		}
+		fmt.Fprintln(a.Out)
+		neighborsOf(index, graph.BuildAdjacency(), first, limit).writeText(a.Out)
 	}
 
-	fmt.Printf("\nAttribution: %s\n", ATTRIBUTION)
+	fmt.Fprintf(a.Out, "\nAttribution: %s\n", ATTRIBUTION)
+	return nil
 }
@@ -0,0 +1,958 @@
+// ORIGIN Go Kit - library
+// Types and loaders for ORIGIN knowledge packs
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ATTRIBUTION = "Ande + Kai (OI) + Whānau (OIs)"
+
+// Default dist file names
+const (
+	IndexFile = "packs.index.json"
+	GraphFile = "graph.json"
+)
+
+type Pack struct {
+	ID             string   `json:"id"`
+	Title          string   `json:"title"`
+	DisclosureTier string   `json:"disclosure_tier"`
+	Related        []string `json:"related"`
+	// Tags are free-form labels; packs without a tags key have none
+	Tags []string `json:"tags,omitempty"`
+	// UpdatedAt is when the pack was last revised, from an RFC 3339
+	// updated_at key; it is zero for packs without one
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	// Synthetic marks a placeholder built by SynthesizeIndex from a graph
+	// node, whose title is empty and whose tier is SyntheticTier. It is
+	// saved as "synthetic": true, and omitted for real packs.
+	Synthetic bool `json:"synthetic,omitempty"`
+	// Extra holds the fields the kit does not model, such as summary and
+	// claims, so they survive a load and save
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type PacksIndex struct {
+	// SchemaVersion is the dist format version; zero means unversioned
+	SchemaVersion int           `json:"schema_version,omitempty"`
+	Metadata      IndexMetadata `json:"metadata"`
+	Packs         []Pack        `json:"packs"`
+}
+
+// IndexMetadata is the metadata block of a packs index
+type IndexMetadata struct {
+	PackCount int `json:"pack_count"`
+	// Extra holds the other metadata fields, such as generated_at
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// SupportedSchemaVersion is the newest dist format version this kit reads
+const SupportedSchemaVersion = 1
+
+// ErrUnsupportedSchema is returned for dist files newer than the kit. It
+// matches ErrInvalidSchema.
+var ErrUnsupportedSchema = invalidSchema(errors.New("unsupported schema version"))
+
+// CheckSchemaVersion reports an error if v is newer than
+// SupportedSchemaVersion. Unversioned (zero) files are accepted.
+func CheckSchemaVersion(v int) error {
+	if v > SupportedSchemaVersion {
+		return fmt.Errorf("%w %d (this kit supports up to %d; upgrade the kit)", ErrUnsupportedSchema, v, SupportedSchemaVersion)
+	}
+	return nil
+}
+
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+	// Weight is the traversal cost of the edge. Zero, whether explicit or
+	// from an absent key, means unset and costs 1; negative weights are
+	// rejected by validate and ValidateSchema.
+	Weight float64 `json:"weight,omitempty"`
+	// Attributes holds optional metadata such as confidence or provenance
+	Attributes EdgeAttributes `json:"attributes,omitzero"`
+}
+
+// Cost returns the edge weight, defaulting to 1 when unset
+func (e GraphEdge) Cost() float64 {
+	if e.Weight == 0 {
+		return 1
+	}
+	return e.Weight
+}
+
+// Attribute returns the value of attribute key and whether the edge has it
+func (e GraphEdge) Attribute(key string) (string, bool) {
+	v, ok := e.Attributes.Map()[key]
+	return v, ok
+}
+
+// AttributeKeys returns the edge's attribute keys, sorted
+func (e GraphEdge) AttributeKeys() []string {
+	return slices.Sorted(maps.Keys(e.Attributes.Map()))
+}
+
+// SetAttribute sets attribute key to value
+func (e *GraphEdge) SetAttribute(key, value string) {
+	m := e.Attributes.Map()
+	if m == nil {
+		m = make(map[string]string)
+	}
+	m[key] = value
+	e.Attributes = NewEdgeAttributes(m)
+}
+
+// EdgeAttributes is a set of string attributes in comparable form, so
+// that edges can be compared with == and used as map keys. The zero value
+// has no attributes. In JSON it is an object of string values.
+type EdgeAttributes struct {
+	// encoded is the attributes as a JSON object with sorted keys, or
+	// empty for none
+	encoded string
+}
+
+// NewEdgeAttributes returns the attributes in m; an empty m gives the
+// zero value
+func NewEdgeAttributes(m map[string]string) EdgeAttributes {
+	if len(m) == 0 {
+		return EdgeAttributes{}
+	}
+	// Maps encode with sorted keys, so equal sets encode equally
+	data, _ := marshalJSON(m)
+	return EdgeAttributes{string(data)}
+}
+
+// Map returns the attributes as a new map, or nil if there are none
+func (a EdgeAttributes) Map() map[string]string {
+	if a.encoded == "" {
+		return nil
+	}
+	var m map[string]string
+	json.Unmarshal([]byte(a.encoded), &m)
+	return m
+}
+
+// IsZero reports whether there are no attributes
+func (a EdgeAttributes) IsZero() bool {
+	return a.encoded == ""
+}
+
+func (a EdgeAttributes) MarshalJSON() ([]byte, error) {
+	if a.encoded == "" {
+		return []byte("null"), nil
+	}
+	return []byte(a.encoded), nil
+}
+
+func (a *EdgeAttributes) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*a = NewEdgeAttributes(m)
+	return nil
+}
+
+type Graph struct {
+	// SchemaVersion is the dist format version; zero means unversioned
+	SchemaVersion int `json:"schema_version,omitempty"`
+	Metadata      struct {
+		NodeCount int `json:"node_count"`
+		EdgeCount int `json:"edge_count"`
+	} `json:"metadata"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// ErrDistNotFound is wrapped by the error for a missing dist file: from a
+// Loader, whether the file is absent from the directory, answered 404 by a
+// URL or missing from a bundle; from the path-based loaders such as
+// LoadIndexFiltered; and from LoadFromTar for a missing archive or entry.
+// Those errors match fs.ErrNotExist too.
+var ErrDistNotFound = errors.New("dist file not found")
+
+// ErrInvalidSchema is matched by every error for a dist file that does not
+// decode as the dist format: invalid JSON, a value of the wrong type,
+// ErrMalformedDist and ErrUnsupportedSchema
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// schemaError is an error that matches ErrInvalidSchema, keeping the
+// message of the error it wraps
+type schemaError struct{ err error }
+
+func (e *schemaError) Error() string        { return e.err.Error() }
+func (e *schemaError) Unwrap() error        { return e.err }
+func (e *schemaError) Is(target error) bool { return target == ErrInvalidSchema }
+
+// invalidSchema marks a decoding error as matching ErrInvalidSchema; nil
+// stays nil
+func invalidSchema(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &schemaError{err}
+}
+
+// distNotFound wraps a missing-file error from a path-based load with
+// ErrDistNotFound, leaving other errors alone
+func distNotFound(err error) error {
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrDistNotFound, err)
+}
+
+// Loader reads dist files from a base directory, from a base URL when
+// BasePath starts with http:// or https://, or from a bundle file written
+// by WriteBundle when NewLoader is given a file
+type Loader struct {
+	// BasePath is the dist directory, URL or bundle file; empty means the
+	// current directory
+	BasePath string
+	// FS, when set, is read instead of BasePath on disk
+	FS fs.FS
+	// HTTPClient fetches URL dist files; nil uses a client with a 30s
+	// timeout
+	HTTPClient *http.Client
+	// Logger receives load events; nil disables logging
+	Logger *slog.Logger
+	// IndexName and GraphName override the dist file names; empty means
+	// IndexFile and GraphFile
+	IndexName string
+	GraphName string
+	// IndexShards, when set, makes LoadIndex read the shard files
+	// IndexShards.NNN.json instead of the single index file; see
+	// LoadIndexShards
+	IndexShards string
+	// StrictSchema makes a schema version newer than the kit supports a
+	// load error instead of a logged warning, and checks each file with
+	// ValidateSchema before decoding it
+	StrictSchema bool
+	// IncludeRelated makes LoadGraph add each pack's Related entries as
+	// related edges; see CombinedGraph
+	IncludeRelated bool
+	// Prefix, when set, scopes the loaded data to packs whose IDs start
+	// with it, and their edges and related IDs to ones among them;
+	// metadata counts describe the scoped data
+	Prefix string
+	// GraphOnly makes LoadIndex build the index from the graph file with
+	// SynthesizeIndex instead of reading an index file
+	GraphOnly bool
+	// SymmetricTypes are edge types LoadGraph folds to one direction with
+	// NormalizeSymmetric
+	SymmetricTypes []string
+
+	// bundle is the bundle file BasePath names, if NewLoader found one
+	bundle *bundleFile
+}
+
+// NewLoader returns a loader rooted at base, which may also be a bundle
+// file
+func NewLoader(base string) *Loader {
+	l := &Loader{BasePath: base}
+	if isBundle(base) {
+		l.bundle = &bundleFile{path: base}
+	}
+	return l
+}
+
+// fsys returns the filesystem the loader reads from
+func (l *Loader) fsys() fs.FS {
+	if l.FS != nil {
+		return l.FS
+	}
+	if isURL(l.BasePath) {
+		client := l.HTTPClient
+		if client == nil {
+			client = &http.Client{Timeout: httpTimeout}
+		}
+		return httpFS{base: l.BasePath, client: client}
+	}
+	if l.bundle != nil {
+		return bundleFS{file: l.bundle, index: l.indexName(), graph: l.graphName()}
+	}
+	if l.BasePath == "" {
+		// os.DirFS("") would resolve names against the filesystem root
+		return os.DirFS(".")
+	}
+	return os.DirFS(l.BasePath)
+}
+
+// resolve returns the full path a dist file is read from, for messages.
+// A loader with both FS and BasePath, such as a memoized one, reports paths
+// under BasePath.
+func (l *Loader) resolve(name string) string {
+	if l.FS != nil && l.BasePath == "" {
+		return name
+	}
+	if isURL(l.BasePath) {
+		return joinURL(l.BasePath, name)
+	}
+	if l.bundle != nil {
+		return l.BasePath + "#" + name
+	}
+	path := filepath.Join(l.BasePath, name)
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// wrapNotFound turns a missing-file error into one naming the resolved
+// path and wrapping ErrDistNotFound
+func (l *Loader) wrapNotFound(name string, err error) error {
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return fmt.Errorf("%w: %s (set ORIGIN_DIST to your knowledge/dist directory): %w",
+		ErrDistNotFound, l.resolve(name), err)
+}
+
+// load decodes the dist file name into v, falling back to a gzipped
+// name.gz when the plain file is absent
+func (l *Loader) load(name string, v interface{}) error {
+	err := l.loadJSON(name, v)
+	if errors.Is(err, fs.ErrNotExist) {
+		if gzErr := l.loadJSON(name+".gz", v); !errors.Is(gzErr, fs.ErrNotExist) {
+			return gzErr
+		}
+	}
+	return l.wrapNotFound(name, err)
+}
+
+// loadJSON decodes the file name into v, first checking its structure with
+// ValidateSchema under StrictSchema
+func (l *Loader) loadJSON(name string, v interface{}) error {
+	data, err := fs.ReadFile(l.fsys(), name)
+	if err != nil {
+		return err
+	}
+	if data, err = decompress(name, data); err != nil {
+		return err
+	}
+	if l.StrictSchema {
+		if err := ValidateSchema(data); err != nil {
+			return fmt.Errorf("%s: %w", l.resolve(name), err)
+		}
+	}
+	return invalidSchema(json.Unmarshal(data, v))
+}
+
+// indexName returns the index file name the loader reads
+func (l *Loader) indexName() string {
+	if l.IndexName != "" {
+		return l.IndexName
+	}
+	return IndexFile
+}
+
+// graphName returns the graph file name the loader reads
+func (l *Loader) graphName() string {
+	if l.GraphName != "" {
+		return l.GraphName
+	}
+	return GraphFile
+}
+
+// LoadIndex loads packs.index.json (or packs.index.json.gz), or the file
+// named by IndexName, from the base directory, with related IDs renamed by
+// AliasesFile. For indexes too large to hold in memory, use StreamPacks
+// instead.
+func (l *Loader) LoadIndex() (PacksIndex, error) {
+	index, err := l.loadIndex()
+	if err != nil {
+		return index, err
+	}
+	if err := l.applyAliases(&index, nil); err != nil {
+		return PacksIndex{}, err
+	}
+	if l.Prefix == "" {
+		return index, nil
+	}
+	index.Packs = FilterByPrefix(index.Packs, l.Prefix)
+	for i, p := range index.Packs {
+		if p.Related != nil {
+			index.Packs[i].Related = slices.DeleteFunc(slices.Clone(p.Related), func(id string) bool {
+				return !strings.HasPrefix(id, l.Prefix)
+			})
+		}
+	}
+	index.Metadata.PackCount = len(index.Packs)
+	return index, nil
+}
+
+// loadIndex is LoadIndex before Prefix is applied
+func (l *Loader) loadIndex() (PacksIndex, error) {
+	if l.GraphOnly {
+		return l.synthesizeIndex()
+	}
+	if l.IndexShards != "" {
+		return l.loadIndexShards()
+	}
+	var index PacksIndex
+	name := l.indexName()
+	if err := l.load(name, &index); err != nil {
+		l.log().Debug("loading index failed", "path", l.resolve(name), "err", err)
+		return index, err
+	}
+	if err := l.checkSchema(name, index.SchemaVersion); err != nil {
+		return PacksIndex{}, err
+	}
+	l.log().Info("loaded index", "path", l.resolve(name), "packs", len(index.Packs))
+	return index, nil
+}
+
+// synthesizeIndex is loadIndex for GraphOnly. The graph is read without
+// IncludeRelated, since there are no related lists to add, or Prefix,
+// which LoadIndex applies to the packs.
+func (l *Loader) synthesizeIndex() (PacksIndex, error) {
+	graphLoader := *l
+	graphLoader.IncludeRelated = false
+	graphLoader.Prefix = ""
+	graph, err := graphLoader.LoadGraph()
+	if err != nil {
+		return PacksIndex{}, err
+	}
+	index := SynthesizeIndex(graph)
+	l.log().Info("synthesized index from graph", "path", l.resolve(l.graphName()), "packs", len(index.Packs))
+	return index, nil
+}
+
+// LoadIndexShards loads the index shards prefix.000.json, prefix.001.json
+// and so on (each optionally gzipped as .json.gz) from dir, in name order,
+// as one index: the packs are concatenated, PackCount is the sum of the
+// shards' counts and SchemaVersion the highest. Packs repeated across
+// shards are all kept, as they would be in a single file, for Validate to
+// report; a Loader with IndexShards and StrictSchema set rejects them.
+func LoadIndexShards(dir, prefix string) (PacksIndex, error) {
+	return (&Loader{BasePath: dir, IndexShards: prefix}).LoadIndex()
+}
+
+// loadIndexShards is LoadIndex for a sharded index
+func (l *Loader) loadIndexShards() (PacksIndex, error) {
+	names, err := l.shardNames()
+	if err != nil {
+		return PacksIndex{}, err
+	}
+	if len(names) == 0 {
+		return PacksIndex{}, l.wrapNotFound(l.IndexShards+".NNN.json", fs.ErrNotExist)
+	}
+
+	var index PacksIndex
+	shardOf := make(map[string]string) // pack ID -> first shard holding it
+	for i, name := range names {
+		var shard PacksIndex
+		if err := l.loadJSON(name, &shard); err != nil {
+			return PacksIndex{}, fmt.Errorf("shard %s: %w", name, err)
+		}
+		if err := l.checkSchema(name, shard.SchemaVersion); err != nil {
+			return PacksIndex{}, err
+		}
+		for _, p := range shard.Packs {
+			first, seen := shardOf[p.ID]
+			if !seen {
+				shardOf[p.ID] = name
+			} else if first != name && l.StrictSchema {
+				return PacksIndex{}, fmt.Errorf("pack %s is in both shard %s and shard %s", p.ID, first, name)
+			}
+		}
+		if i == 0 {
+			index.Metadata.Extra = shard.Metadata.Extra
+		}
+		index.SchemaVersion = max(index.SchemaVersion, shard.SchemaVersion)
+		index.Metadata.PackCount += shard.Metadata.PackCount
+		index.Packs = append(index.Packs, shard.Packs...)
+	}
+	l.log().Info("loaded index shards", "prefix", l.resolve(l.IndexShards), "shards", len(names), "packs", len(index.Packs))
+	return index, nil
+}
+
+// shardNames returns the IndexShards files in name order, taking
+// name.json over name.json.gz when both exist
+func (l *Loader) shardNames() ([]string, error) {
+	matches, err := fs.Glob(l.fsys(), l.IndexShards+".*.json*")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range matches {
+		plain := strings.TrimSuffix(name, ".gz")
+		num, ok := strings.CutSuffix(strings.TrimPrefix(plain, l.IndexShards+"."), ".json")
+		if !ok || num == "" || strings.Trim(num, "0123456789") != "" || seen[plain] {
+			continue
+		}
+		seen[plain] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// LoadGraph loads graph.json (or graph.json.gz), or the file named by
+// GraphName, from the base directory, with edge endpoints renamed by
+// AliasesFile. With IncludeRelated it also loads the index and returns the
+// combined graph.
+func (l *Loader) LoadGraph() (Graph, error) {
+	var graph Graph
+	name := l.graphName()
+	if err := l.load(name, &graph); err != nil {
+		l.log().Debug("loading graph failed", "path", l.resolve(name), "err", err)
+		return graph, err
+	}
+	if err := l.checkSchema(name, graph.SchemaVersion); err != nil {
+		return Graph{}, err
+	}
+	if err := l.applyAliases(nil, &graph); err != nil {
+		return Graph{}, err
+	}
+	if l.IncludeRelated {
+		index, err := l.LoadIndex()
+		if err != nil {
+			return Graph{}, fmt.Errorf("combining related edges: %w", err)
+		}
+		explicit := len(graph.Edges)
+		graph = CombinedGraph(index, graph, "related")
+		l.log().Debug("added related edges", "edges", len(graph.Edges)-explicit)
+	}
+	if len(l.SymmetricTypes) > 0 {
+		before := len(graph.Edges)
+		graph = NormalizeSymmetric(graph, l.SymmetricTypes)
+		l.log().Info("normalized symmetric edges", "types", strings.Join(l.SymmetricTypes, ","), "collapsed", before-len(graph.Edges))
+	}
+	if l.Prefix != "" {
+		graph.Edges = slices.DeleteFunc(slices.Clone(graph.Edges), func(edge GraphEdge) bool {
+			return !strings.HasPrefix(edge.Source, l.Prefix) || !strings.HasPrefix(edge.Target, l.Prefix)
+		})
+		graph = graph.RecomputeMetadata()
+	}
+	// node_count is the metadata's claim, which validate checks; counting
+	// nodes would mean building the adjacency just for this line
+	l.log().Info("loaded graph", "path", l.resolve(name),
+		"node_count", graph.Metadata.NodeCount, "edges", len(graph.Edges))
+	return graph, nil
+}
+
+// checkSchema applies CheckSchemaVersion to the file name, failing under
+// StrictSchema and logging a warning otherwise
+func (l *Loader) checkSchema(name string, version int) error {
+	err := CheckSchemaVersion(version)
+	if err == nil {
+		return nil
+	}
+	if l.StrictSchema {
+		return fmt.Errorf("%s: %w", l.resolve(name), err)
+	}
+	l.log().Warn("newer schema version; results may be wrong", "path", l.resolve(name), "err", err)
+	return nil
+}
+
+// LoadIndexAuto loads a packs index from path, decompressing it first if
+// it is gzipped
+func LoadIndexAuto(path string) (PacksIndex, error) {
+	return LoadIndexContext(context.Background(), path)
+}
+
+// FieldMap maps canonical pack field names (the JSON keys of Pack, such
+// as "id" and "title") to the keys used in a particular file
+type FieldMap map[string]string
+
+// packFields are the canonical JSON keys of Pack
+var packFields = []string{"id", "title", "disclosure_tier", "related", "tags", "updated_at", "synthetic"}
+
+// LoadIndexWithSchema loads a packs index from path whose pack objects use
+// the keys in schema. A field with no mapping, or whose mapped key is
+// absent from a pack, is read from its default key. Keys that are neither
+// mapped nor canonical are kept in Extra.
+func LoadIndexWithSchema(path string, schema FieldMap) (PacksIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PacksIndex{}, distNotFound(err)
+	}
+
+	var raw struct {
+		Metadata json.RawMessage              `json:"metadata"`
+		Packs    []map[string]json.RawMessage `json:"packs"`
+	}
+	if err := decodeJSON(path, data, &raw); err != nil {
+		return PacksIndex{}, err
+	}
+
+	var index PacksIndex
+	if len(raw.Metadata) > 0 {
+		if err := json.Unmarshal(raw.Metadata, &index.Metadata); err != nil {
+			return PacksIndex{}, fmt.Errorf("%s: metadata: %w", path, invalidSchema(err))
+		}
+	}
+	for i, fields := range raw.Packs {
+		canonical := make(map[string]json.RawMessage, len(fields))
+		mapped := make(map[string]bool, len(schema))
+		for _, name := range packFields {
+			if v, ok := fields[schema[name]]; ok && schema[name] != "" {
+				canonical[name] = v
+				mapped[schema[name]] = true
+			} else if v, ok := fields[name]; ok {
+				canonical[name] = v
+			}
+		}
+		// Other keys are kept for Extra, as a plain load keeps them
+		for key, v := range fields {
+			if _, ok := canonical[key]; !ok && !mapped[key] && !slices.Contains(packFields, key) {
+				canonical[key] = v
+			}
+		}
+		remapped, err := json.Marshal(canonical)
+		if err != nil {
+			return PacksIndex{}, err
+		}
+		var p Pack
+		if err := json.Unmarshal(remapped, &p); err != nil {
+			return PacksIndex{}, fmt.Errorf("%s: pack %d: %w", path, i, invalidSchema(err))
+		}
+		index.Packs = append(index.Packs, p)
+	}
+	return index, nil
+}
+
+// readChunk bounds each read so cancellation is noticed promptly
+const readChunk = 64 << 10
+
+// ctxReader fails reads once its context is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) > readChunk {
+		p = p[:readChunk]
+	}
+	return c.r.Read(p)
+}
+
+// LoadIndexContext loads a packs index from path like LoadIndexAuto, but
+// reads and decodes in chunks and returns ctx.Err() once ctx is done
+func LoadIndexContext(ctx context.Context, path string) (PacksIndex, error) {
+	var index PacksIndex
+	f, err := os.Open(path)
+	if err != nil {
+		return index, distNotFound(err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(ctxReader{ctx, f})
+	var r io.Reader = br
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			if ctx.Err() != nil {
+				return index, ctx.Err()
+			}
+			return index, fmt.Errorf("decompressing %s: %w", path, err)
+		}
+		r = zr
+	}
+
+	if err := json.NewDecoder(ctxReader{ctx, r}).Decode(&index); err != nil {
+		if ctx.Err() != nil {
+			return PacksIndex{}, ctx.Err()
+		}
+		if _, ok := r.(*gzip.Reader); ok && !isJSONError(err) {
+			return PacksIndex{}, fmt.Errorf("decompressing %s: %w", path, err)
+		}
+		if isJSONError(err) {
+			err = invalidSchema(err)
+		}
+		return PacksIndex{}, err
+	}
+	return index, nil
+}
+
+// LoadIndexFiltered streams a packs index from path, decompressing it if it
+// is gzipped, and keeps only packs whose tier is one of keepTiers (TierAll
+// keeps every pack). PackCount is set to the number kept, so memory use
+// follows the subset rather than the whole file.
+func LoadIndexFiltered(path string, keepTiers []string) (PacksIndex, error) {
+	var index PacksIndex
+	f, err := os.Open(path)
+	if err != nil {
+		return index, distNotFound(err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return index, fmt.Errorf("decompressing %s: %w", path, err)
+		}
+		r = zr
+	}
+
+	keep := make(map[string]bool, len(keepTiers))
+	for _, tier := range keepTiers {
+		keep[tier] = true
+	}
+	err = StreamPacks(r, func(p Pack) error {
+		if keep[TierAll] || keep[p.DisclosureTier] {
+			index.Packs = append(index.Packs, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return PacksIndex{}, fmt.Errorf("%s: %w", path, err)
+	}
+	index.Metadata.PackCount = len(index.Packs)
+	return index, nil
+}
+
+// isJSONError reports whether err came from JSON decoding rather than
+// from the underlying reader
+func isJSONError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// LoadAll loads the index and graph concurrently. Both loads always finish
+// before it returns; the first error to occur is returned.
+func LoadAll(l *Loader) (PacksIndex, Graph, error) {
+	var (
+		index    PacksIndex
+		graph    Graph
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() { firstErr = err })
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var err error
+		if index, err = l.LoadIndex(); err != nil {
+			fail(fmt.Errorf("loading index: %w", err))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		if graph, err = l.LoadGraph(); err != nil {
+			fail(fmt.Errorf("loading graph: %w", err))
+		}
+	}()
+	wg.Wait()
+
+	if firstErr != nil {
+		return PacksIndex{}, Graph{}, firstErr
+	}
+	return index, graph, nil
+}
+
+// LoadIndexFS loads a packs index named name from fsys
+func LoadIndexFS(fsys fs.FS, name string) (PacksIndex, error) {
+	var index PacksIndex
+	err := loadJSON(fsys, name, &index)
+	return index, err
+}
+
+// LoadGraphFS loads a graph named name from fsys
+func LoadGraphFS(fsys fs.FS, name string) (Graph, error) {
+	var graph Graph
+	err := loadJSON(fsys, name, &graph)
+	return graph, err
+}
+
+func loadJSON(fsys fs.FS, name string, v interface{}) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return distNotFound(err)
+	}
+	return decodeJSON(name, data, v)
+}
+
+// gzipMagic is the two-byte header of every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decodeJSON unmarshals data into v, transparently decompressing gzip
+// input. name is used in error messages.
+func decodeJSON(name string, data []byte, v interface{}) error {
+	data, err := decompress(name, data)
+	if err != nil {
+		return err
+	}
+	return invalidSchema(json.Unmarshal(data, v))
+}
+
+// decompress returns data, gunzipped if it starts with the gzip header.
+// name is used in error messages.
+func decompress(name string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s: %w", name, err)
+	}
+	if data, err = io.ReadAll(zr); err != nil {
+		return nil, fmt.Errorf("decompressing %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// StreamPacks decodes a packs index from r one pack at a time, calling fn
+// for each without holding the full slice. Iteration stops at the first
+// error returned by fn, which StreamPacks returns.
+func StreamPacks(r io.Reader, fn func(Pack) error) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key, _ := tok.(string); key != "packs" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for dec.More() {
+			var p Pack
+			if err := dec.Decode(&p); err != nil {
+				if isJSONError(err) {
+					err = invalidSchema(err)
+				}
+				return err
+			}
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// maxLineSize bounds a single NDJSON line
+const maxLineSize = 16 << 20
+
+// LoadPacksNDJSON decodes one pack per line from r, skipping blank lines.
+// A malformed line fails the load with an error naming its line number.
+func LoadPacksNDJSON(r io.Reader) ([]Pack, error) {
+	var packs []Pack
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var p Pack
+		if err := json.Unmarshal(text, &p); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, invalidSchema(err))
+		}
+		packs = append(packs, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return packs, nil
+}
+
+// expectDelim reads the next token and checks that it is delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return invalidSchema(fmt.Errorf("expected %q, got %v", delim, tok))
+	}
+	return nil
+}
+
+// LoadMerged loads the index and graph from each dist directory in turn
+// and merges them. A pack from a later path replaces an earlier pack with
+// the same ID. Edges with the same source, target and type are kept once,
+// in the first one's position, and likewise the later edge's weight and
+// attributes win. Metadata counts describe the merged result.
+func LoadMerged(paths ...string) (PacksIndex, Graph, error) {
+	var merged PacksIndex
+	var graph Graph
+	position := make(map[string]int)
+	type key struct{ source, target, typ string }
+	edgeAt := make(map[key]int)
+
+	for _, path := range paths {
+		loader := NewLoader(path)
+		index, err := loader.LoadIndex()
+		if err != nil {
+			return PacksIndex{}, Graph{}, fmt.Errorf("loading index from %s: %w", path, err)
+		}
+		g, err := loader.LoadGraph()
+		if err != nil {
+			return PacksIndex{}, Graph{}, fmt.Errorf("loading graph from %s: %w", path, err)
+		}
+
+		for _, p := range index.Packs {
+			if i, ok := position[p.ID]; ok {
+				merged.Packs[i] = p
+				continue
+			}
+			position[p.ID] = len(merged.Packs)
+			merged.Packs = append(merged.Packs, p)
+		}
+		for _, edge := range g.Edges {
+			k := key{edge.Source, edge.Target, edge.Type}
+			if i, ok := edgeAt[k]; ok {
+				graph.Edges[i] = edge
+				continue
+			}
+			edgeAt[k] = len(graph.Edges)
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+
+	merged.Metadata.PackCount = len(merged.Packs)
+	graph.Metadata.NodeCount = len(graph.BuildAdjacency())
+	graph.Metadata.EdgeCount = len(graph.Edges)
+	return merged, graph, nil
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoaderReadsFromBasePath(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, `{"metadata":{"pack_count":1},"packs":[{"id":"A","title":"Alpha","disclosure_tier":"public"}]}`)
+	writeFile(t, dir, GraphFile, `{"metadata":{"node_count":2,"edge_count":1},"edges":[{"source":"A","target":"B","type":"related"}]}`)
+
+	loader := NewLoader(dir)
+	index, err := loader.LoadIndex()
+	if err != nil {
+		t.Fatalf("LoadIndex: %v", err)
+	}
+	if len(index.Packs) != 1 || index.Packs[0].ID != "A" {
+		t.Errorf("unexpected packs: %+v", index.Packs)
+	}
+
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		t.Fatalf("LoadGraph: %v", err)
+	}
+	if len(graph.Edges) != 1 || graph.Metadata.EdgeCount != 1 {
+		t.Errorf("unexpected graph: %+v", graph)
+	}
+}
+
+func TestLoaderMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewLoader(dir).LoadIndex()
+	if !errors.Is(err, ErrDistNotFound) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("err = %v, want ErrDistNotFound wrapping os.ErrNotExist", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, filepath.Join(dir, IndexFile)) || !strings.Contains(msg, "ORIGIN_DIST") {
+		t.Errorf("message %q should name the path and ORIGIN_DIST", msg)
+	}
+}
+
+func TestLoaderCustomFileNames(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "packs.index.v2.json", `{"packs":[{"id":"A"},{"id":"B"}]}`)
+	writeFile(t, dir, "graph.v2.json", `{"edges":[{"source":"A","target":"B","type":"related"}]}`)
+
+	loader := memoize(&Loader{BasePath: dir, IndexName: "packs.index.v2.json", GraphName: "graph.v2.json"})
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if len(index.Packs) != 2 || len(graph.Edges) != 1 {
+		t.Errorf("got %d packs and %d edges, want 2 and 1", len(index.Packs), len(graph.Edges))
+	}
+
+	if _, err := (&Loader{BasePath: dir}).LoadIndex(); !errors.Is(err, ErrDistNotFound) {
+		t.Errorf("default name: err = %v, want ErrDistNotFound", err)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	if err := CheckSchemaVersion(0); err != nil {
+		t.Errorf("unversioned: %v", err)
+	}
+	if err := CheckSchemaVersion(SupportedSchemaVersion); err != nil {
+		t.Errorf("supported version: %v", err)
+	}
+	if err := CheckSchemaVersion(SupportedSchemaVersion + 1); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Errorf("newer version: err = %v, want ErrUnsupportedSchema", err)
+	}
+
+	newer := fmt.Sprintf(`{"schema_version":%d,"packs":[{"id":"A"}]}`, SupportedSchemaVersion+1)
+	fsys := fstest.MapFS{IndexFile: {Data: []byte(newer)}}
+
+	var logs bytes.Buffer
+	logger, _ := newLogger(&logs, 0, LogFormatText)
+	index, err := (&Loader{FS: fsys, Logger: logger}).LoadIndex()
+	if err != nil || len(index.Packs) != 1 {
+		t.Errorf("lenient load = %+v, %v; want the pack", index, err)
+	}
+	if !strings.Contains(logs.String(), "newer schema version") {
+		t.Errorf("lenient load logged %q, want a warning", logs.String())
+	}
+
+	if _, err := (&Loader{FS: fsys, StrictSchema: true}).LoadIndex(); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Errorf("strict load: err = %v, want ErrUnsupportedSchema", err)
+	}
+}
+
+func TestLoadIndexFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"idx.json": {Data: []byte(`{"metadata":{"pack_count":1},"packs":[{"id":"A"}]}`)},
+	}
+	index, err := LoadIndexFS(fsys, "idx.json")
+	if err != nil {
+		t.Fatalf("LoadIndexFS: %v", err)
+	}
+	if index.Metadata.PackCount != 1 || len(index.Packs) != 1 {
+		t.Errorf("unexpected index: %+v", index)
+	}
+
+	if _, err := (&Loader{FS: fsys}).LoadGraph(); err == nil {
+		t.Error("expected error for graph missing from FS")
+	}
+}
+
+func TestLoadIndexWithSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "legacy.json", `{"metadata":{"pack_count":2},"packs":[
+		{"pack_id":"A","name":"Alpha","disclosure_tier":"public","related":["B"]},
+		{"id":"B","name":"Beta"}
+	]}`)
+
+	index, err := LoadIndexWithSchema(filepath.Join(dir, "legacy.json"), FieldMap{
+		"id":      "pack_id",
+		"title":   "name",
+		"unknown": "whatever",
+	})
+	if err != nil {
+		t.Fatalf("LoadIndexWithSchema: %v", err)
+	}
+
+	want := []Pack{
+		{ID: "A", Title: "Alpha", DisclosureTier: "public", Related: []string{"B"}},
+		{ID: "B", Title: "Beta"},
+	}
+	if index.Metadata.PackCount != 2 || !reflect.DeepEqual(index.Packs, want) {
+		t.Errorf("got %+v, want %+v", index.Packs, want)
+	}
+}
+
+func TestLoadIndexWithSchemaExtra(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "legacy.json", `{"packs":[{"pack_id":"A","summary":"kept","claims":[1]}]}`)
+
+	index, err := LoadIndexWithSchema(filepath.Join(dir, "legacy.json"), FieldMap{"id": "pack_id"})
+	if err != nil {
+		t.Fatalf("LoadIndexWithSchema: %v", err)
+	}
+	want := map[string]json.RawMessage{"summary": json.RawMessage(`"kept"`), "claims": json.RawMessage(`[1]`)}
+	if p := index.Packs[0]; p.ID != "A" || !reflect.DeepEqual(p.Extra, want) {
+		t.Errorf("pack = %+v, want ID A and Extra %s", p, want)
+	}
+}
+
+func TestStreamPacks(t *testing.T) {
+	input := `{"metadata":{"pack_count":3},"packs":[{"id":"A"},{"id":"B"},{"id":"C"}],"extra":[1,2]}`
+
+	var ids []string
+	err := StreamPacks(strings.NewReader(input), func(p Pack) error {
+		ids = append(ids, p.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamPacks: %v", err)
+	}
+	if strings.Join(ids, ",") != "A,B,C" {
+		t.Errorf("ids = %v", ids)
+	}
+}
+
+func TestLoadPacksNDJSON(t *testing.T) {
+	packs, err := LoadPacksNDJSON(strings.NewReader(`{"id":"A","title":"Alpha"}
+
+{"id":"B","related":["A"]}
+`))
+	if err != nil {
+		t.Fatalf("LoadPacksNDJSON: %v", err)
+	}
+	if want := []Pack{{ID: "A", Title: "Alpha"}, {ID: "B", Related: []string{"A"}}}; !reflect.DeepEqual(packs, want) {
+		t.Errorf("got %+v, want %+v", packs, want)
+	}
+
+	_, err = LoadPacksNDJSON(strings.NewReader(`{"id":"A"}
+{"id":"B"}
+{"id": "C",
+{"id":"D"}
+`))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("malformed line error = %v, want one naming line 3", err)
+	}
+}
+
+func TestStreamPacksStopsOnCallbackError(t *testing.T) {
+	input := `{"packs":[{"id":"A"},{"id":"B"},{"id":"C"}]}`
+	stop := errors.New("stop")
+
+	seen := 0
+	err := StreamPacks(strings.NewReader(input), func(p Pack) error {
+		seen++
+		if p.ID == "B" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("err = %v, want stop", err)
+	}
+	if seen != 2 {
+		t.Errorf("callback ran %d times, want 2", seen)
+	}
+}
+
+func TestLoadMerged(t *testing.T) {
+	core, overlay := t.TempDir(), t.TempDir()
+	writeFile(t, core, IndexFile, `{"packs":[{"id":"A","title":"Alpha"},{"id":"B","title":"Beta"}]}`)
+	writeFile(t, core, GraphFile, `{"edges":[{"source":"A","target":"B","type":"related"}]}`)
+	writeFile(t, overlay, IndexFile, `{"packs":[{"id":"B","title":"Beta v2"},{"id":"C","title":"Gamma"}]}`)
+	writeFile(t, overlay, GraphFile, `{"edges":[{"source":"A","target":"B","type":"related","weight":2,"attributes":{"confidence":"high"}},{"source":"B","target":"C","type":"child"}]}`)
+
+	index, graph, err := LoadMerged(core, overlay)
+	if err != nil {
+		t.Fatalf("LoadMerged: %v", err)
+	}
+	if len(index.Packs) != 3 || index.Packs[1].Title != "Beta v2" || index.Metadata.PackCount != 3 {
+		t.Errorf("unexpected merged index: %+v", index)
+	}
+	if len(graph.Edges) != 2 || graph.Metadata.EdgeCount != 2 || graph.Metadata.NodeCount != 3 {
+		t.Errorf("unexpected merged graph: %+v", graph)
+	}
+	if e := graph.Edges[0]; e.Weight != 2 || e.Attributes.Map()["confidence"] != "high" {
+		t.Errorf("merged A -> B = %+v, want the overlay's weight and attributes", e)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, _, err := LoadMerged(core, missing); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("err = %v, want it to name %s", err, missing)
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, `{"packs":[{"id":"A"}]}`)
+
+	if _, _, err := LoadAll(NewLoader(dir)); !errors.Is(err, ErrDistNotFound) || !strings.Contains(err.Error(), "graph") {
+		t.Errorf("err = %v, want missing graph", err)
+	}
+
+	writeFile(t, dir, GraphFile, `{"edges":[{"source":"A","target":"A","type":"related"}]}`)
+	index, graph, err := LoadAll(NewLoader(dir))
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if len(index.Packs) != 1 || len(graph.Edges) != 1 {
+		t.Errorf("unexpected result: %+v %+v", index, graph)
+	}
+}
+
+// writeLargeDist writes an index and graph of n packs under dir
+func writeLargeDist(b *testing.B, dir string, n int) {
+	b.Helper()
+	var index PacksIndex
+	var graph Graph
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("P%06d", i)
+		index.Packs = append(index.Packs, Pack{ID: id, Title: "Pack " + id, DisclosureTier: "public"})
+		graph.Edges = append(graph.Edges, GraphEdge{Source: id, Target: fmt.Sprintf("P%06d", (i+1)%n), Type: "related"})
+	}
+	for name, v := range map[string]any{IndexFile: index, GraphFile: graph} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadSequential(b *testing.B) {
+	dir := b.TempDir()
+	writeLargeDist(b, dir, 100000)
+	loader := NewLoader(dir)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := loader.LoadIndex(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := loader.LoadGraph(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadAll(b *testing.B) {
+	dir := b.TempDir()
+	writeLargeDist(b, dir, 100000)
+	loader := NewLoader(dir)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := LoadAll(loader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadIndexAutoGzip(t *testing.T) {
+	dir := t.TempDir()
+	const index = `{"packs":[{"id":"A"},{"id":"B"}]}`
+	writeFile(t, dir, "plain.json", index)
+	writeFile(t, dir, "packed.json.gz", string(gzipBytes(t, index)))
+
+	for _, name := range []string{"plain.json", "packed.json.gz"} {
+		got, err := LoadIndexAuto(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(got.Packs) != 2 {
+			t.Errorf("%s: got %d packs, want 2", name, len(got.Packs))
+		}
+	}
+
+	corrupt := append(gzipBytes(t, index)[:12], "garbage"...)
+	writeFile(t, dir, "corrupt.json.gz", string(corrupt))
+	_, err := LoadIndexAuto(filepath.Join(dir, "corrupt.json.gz"))
+	if err == nil || !strings.Contains(err.Error(), "decompressing") {
+		t.Errorf("corrupt gzip: err = %v, want decompressing error", err)
+	}
+}
+
+func TestLoadIndexFiltered(t *testing.T) {
+	dir := t.TempDir()
+	const index = `{"metadata":{"pack_count":3},"packs":[` +
+		`{"id":"A","disclosure_tier":"public"},{"id":"B","disclosure_tier":"internal"},{"id":"C","disclosure_tier":"public"}]}`
+	writeFile(t, dir, "packs.json.gz", string(gzipBytes(t, index)))
+	path := filepath.Join(dir, "packs.json.gz")
+
+	got, err := LoadIndexFiltered(path, []string{"public"})
+	if err != nil {
+		t.Fatalf("LoadIndexFiltered: %v", err)
+	}
+	if ids := packIDs(got.Packs); !reflect.DeepEqual(ids, []string{"A", "C"}) || got.Metadata.PackCount != 2 {
+		t.Errorf("public = %v (pack_count %d), want [A C] (2)", ids, got.Metadata.PackCount)
+	}
+
+	all, err := LoadIndexFiltered(path, []string{TierAll})
+	if err != nil || all.Metadata.PackCount != 3 {
+		t.Errorf("all tiers = %+v, %v", all, err)
+	}
+}
+
+func TestLoaderIncludeRelated(t *testing.T) {
+	loader := &Loader{IncludeRelated: true, FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","related":["B"]},{"id":"B"},{"id":"C","related":["A"]}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"related"}]}`)},
+	}}
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		t.Fatalf("LoadGraph: %v", err)
+	}
+	if len(graph.Edges) != 2 || graph.Edges[1] != (GraphEdge{Source: "C", Target: "A", Type: "related"}) {
+		t.Errorf("combined edges = %v", graph.Edges)
+	}
+}
+
+func TestLoaderFallsBackToGzip(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, GraphFile+".gz", string(gzipBytes(t, `{"edges":[{"source":"A","target":"B"}]}`)))
+
+	graph, err := NewLoader(dir).LoadGraph()
+	if err != nil {
+		t.Fatalf("LoadGraph: %v", err)
+	}
+	if len(graph.Edges) != 1 {
+		t.Errorf("got %d edges, want 1", len(graph.Edges))
+	}
+}
+
+func TestLoadIndexContextCancelled(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, IndexFile)
+	writeFile(t, dir, IndexFile, `{"packs":[{"id":"A"}]}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := LoadIndexContext(ctx, path); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	index, err := LoadIndexContext(context.Background(), path)
+	if err != nil || len(index.Packs) != 1 {
+		t.Errorf("LoadIndexContext = %+v, %v", index, err)
+	}
+}
+
+func TestLoadIndexShards(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "packs.index.000.json", `{"schema_version":1,"metadata":{"pack_count":2,"generator":"g"},"packs":[{"id":"A"},{"id":"B"}]}`)
+	if err := os.WriteFile(filepath.Join(dir, "packs.index.001.json.gz"), gzipBytes(t, `{"metadata":{"pack_count":1},"packs":[{"id":"C"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, IndexFile, `{"metadata":{"pack_count":1},"packs":[{"id":"X"}]}`)
+	writeFile(t, dir, "packs.index.old.json", `{"metadata":{"pack_count":1},"packs":[{"id":"Y"}]}`)
+
+	index, err := LoadIndexShards(dir, "packs.index")
+	if err != nil {
+		t.Fatalf("LoadIndexShards: %v", err)
+	}
+	if got := packIDs(index.Packs); !slices.Equal(got, []string{"A", "B", "C"}) {
+		t.Errorf("packs = %v, want [A B C]", got)
+	}
+	if index.Metadata.PackCount != 3 || index.SchemaVersion != 1 || index.Metadata.Extra["generator"] == nil {
+		t.Errorf("metadata = %+v, schema %d; want 3 packs from the shards", index.Metadata, index.SchemaVersion)
+	}
+
+	writeFile(t, dir, "packs.index.002.json", `{"metadata":{"pack_count":1},"packs":[{"id":"B"}]}`)
+	if index, err := LoadIndexShards(dir, "packs.index"); err != nil || len(index.Packs) != 4 {
+		t.Errorf("lenient duplicate: %d packs, %v; want all 4 kept", len(index.Packs), err)
+	}
+	strict := &Loader{BasePath: dir, IndexShards: "packs.index", StrictSchema: true}
+	if _, err := strict.LoadIndex(); err == nil || !strings.Contains(err.Error(), "pack B is in both shard packs.index.000.json and shard packs.index.002.json") {
+		t.Errorf("strict duplicate: err = %v, want it to name both shards", err)
+	}
+
+	if _, err := LoadIndexShards(dir, "missing"); !errors.Is(err, ErrDistNotFound) {
+		t.Errorf("no shards: err = %v, want ErrDistNotFound", err)
+	}
+}
+
+func TestLoaderPrefix(t *testing.T) {
+	loader := &Loader{Prefix: "core/", IncludeRelated: true, FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"metadata":{"pack_count":3},"packs":[{"id":"core/a","related":["team/c"]},{"id":"core/b"},{"id":"team/c"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"core/a","target":"core/b","type":"child"},{"source":"core/b","target":"team/c","type":"related"}]}`)},
+	}}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if got := packIDs(index.Packs); !slices.Equal(got, []string{"core/a", "core/b"}) || index.Metadata.PackCount != 2 {
+		t.Errorf("scoped index = %v (pack_count %d), want the core packs", got, index.Metadata.PackCount)
+	}
+	if len(index.Packs[0].Related) != 0 {
+		t.Errorf("core/a related = %v, want the team/c entry dropped", index.Packs[0].Related)
+	}
+	want := []GraphEdge{{Source: "core/a", Target: "core/b", Type: "child"}}
+	if !reflect.DeepEqual(graph.Edges, want) || graph.Metadata.NodeCount != 2 || graph.Metadata.EdgeCount != 1 {
+		t.Errorf("scoped graph = %+v, want only the edge inside core/", graph)
+	}
+}
+
+func TestEdgeAttributes(t *testing.T) {
+	loader := &Loader{FS: fstest.MapFS{GraphFile: {Data: []byte(`{"edges":[` +
+		`{"source":"A","target":"B","type":"related","attributes":{"confidence":"high","source":"review"}},` +
+		`{"source":"B","target":"C","type":"related"}]}`)}}}
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		t.Fatalf("LoadGraph: %v", err)
+	}
+
+	with, without := graph.Edges[0], graph.Edges[1]
+	if v, ok := with.Attribute("confidence"); !ok || v != "high" {
+		t.Errorf(`Attribute("confidence") = %q, %v; want "high", true`, v, ok)
+	}
+	if got, want := with.AttributeKeys(), []string{"confidence", "source"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AttributeKeys = %v, want %v", got, want)
+	}
+	if !without.Attributes.IsZero() || len(without.AttributeKeys()) != 0 {
+		t.Errorf("edge without attributes = %+v, want zero Attributes", without)
+	}
+	if _, ok := without.Attribute("confidence"); ok {
+		t.Error("Attribute on an edge without attributes reported it set")
+	}
+
+	without.SetAttribute("confidence", "low")
+	if v, _ := without.Attribute("confidence"); v != "low" {
+		t.Errorf("after SetAttribute, confidence = %q, want low", v)
+	}
+
+	var buf bytes.Buffer
+	if err := (Graph{Edges: []GraphEdge{graph.Edges[1]}}).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "attributes") {
+		t.Errorf("edge without attributes written with the key:\n%s", buf.String())
+	}
+
+	// Attributes compare by value, whatever order they were set in
+	other := GraphEdge{Source: "A", Target: "B", Type: "related"}
+	other.SetAttribute("source", "review")
+	other.SetAttribute("confidence", "high")
+	if other != with {
+		t.Errorf("%+v != %+v, want equal edges", other, with)
+	}
+	buf.Reset()
+	if err := (Graph{Edges: []GraphEdge{with}}).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"attributes": {`) || !strings.Contains(buf.String(), `"confidence": "high"`) {
+		t.Errorf("edge with attributes written as:\n%s", buf.String())
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "bad.json", `{"packs":[{"id":1}]}`)
+	bad := filepath.Join(dir, "bad.json")
+	missing := filepath.Join(dir, "missing.json")
+	index := PacksIndex{Packs: samplePacks()}
+	graph := cycleGraph()
+	graph.Edges = append(graph.Edges, GraphEdge{Source: "E", Target: "F", Type: "related"})
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"requirePack", func() error { _, err := requirePack(index.ByID(), "Z"); return err }(), ErrPackNotFound},
+		{"packDetail", func() error { _, err := packDetail(index, graph, "Z"); return err }(), ErrPackNotFound},
+		{"ShortestPath", func() error { _, err := graph.ShortestPath("A", "E"); return err }(), ErrNoPath},
+		{"ShortestPathBidirectional", func() error { _, err := graph.ShortestPathBidirectional("A", "E"); return err }(), ErrNoPath},
+		{"WeightedShortestPath", func() error { _, _, err := graph.WeightedShortestPath("A", "E"); return err }(), ErrNoPath},
+		{"NearestOfTier", func() error { _, _, err := graph.NearestOfTier(index, "A", "restricted"); return err }(), ErrNoPath},
+		{"TopoSort", func() error { _, err := graph.TopoSort("related"); return err }(), ErrCycleDetected},
+		{"Loader.LoadIndex missing", func() error { _, err := (&Loader{FS: fstest.MapFS{}}).LoadIndex(); return err }(), ErrDistNotFound},
+		{"LoadIndexAuto missing", func() error { _, err := LoadIndexAuto(missing); return err }(), ErrDistNotFound},
+		{"LoadIndexFiltered missing", func() error { _, err := LoadIndexFiltered(missing, []string{TierAll}); return err }(), ErrDistNotFound},
+		{"LoadIndexWithSchema missing", func() error { _, err := LoadIndexWithSchema(missing, nil); return err }(), ErrDistNotFound},
+		{"LoadIndexFS missing", func() error { _, err := LoadIndexFS(fstest.MapFS{}, IndexFile); return err }(), ErrDistNotFound},
+		{"LoadFromTar missing", func() error { _, _, err := LoadFromTar(missing); return err }(), ErrDistNotFound},
+		{"Loader.LoadIndex", func() error {
+			_, err := (&Loader{FS: fstest.MapFS{IndexFile: {Data: []byte(`{"packs":{}}`)}}}).LoadIndex()
+			return err
+		}(), ErrInvalidSchema},
+		{"LoadIndexAuto", func() error { _, err := LoadIndexAuto(bad); return err }(), ErrInvalidSchema},
+		{"LoadIndexFiltered", func() error { _, err := LoadIndexFiltered(bad, []string{TierAll}); return err }(), ErrInvalidSchema},
+		{"LoadIndexWithSchema", func() error { _, err := LoadIndexWithSchema(bad, nil); return err }(), ErrInvalidSchema},
+		{"LoadPacksNDJSON", func() error { _, err := LoadPacksNDJSON(strings.NewReader(`{"id":[]}`)); return err }(), ErrInvalidSchema},
+		{"LoadBundle", func() error { _, _, err := LoadBundle(strings.NewReader(`{"index":{}}`)); return err }(), ErrInvalidSchema},
+		{"ValidateSchema", ValidateSchema([]byte(`[]`)), ErrInvalidSchema},
+		{"CheckSchemaVersion", CheckSchemaVersion(SupportedSchemaVersion + 1), ErrInvalidSchema},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+
+	// The wrappers keep their own sentinels distinct
+	if err := ValidateSchema([]byte(`[]`)); !errors.Is(err, ErrMalformedDist) || errors.Is(err, ErrUnsupportedSchema) {
+		t.Errorf("ValidateSchema: err = %v, want only ErrMalformedDist", err)
+	}
+	if err := CheckSchemaVersion(SupportedSchemaVersion + 1); errors.Is(err, ErrMalformedDist) {
+		t.Errorf("CheckSchemaVersion: err = %v matches ErrMalformedDist", err)
+	}
+}
+
+func TestLoaderGraphOnly(t *testing.T) {
+	loader := &Loader{GraphOnly: true, Prefix: "x/", IncludeRelated: true, FS: fstest.MapFS{
+		GraphFile: {Data: []byte(`{"edges":[{"source":"x/b","target":"x/a","type":"related"},{"source":"x/a","target":"y/c","type":"child"}]}`)},
+	}}
+
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if got := packIDs(index.Packs); !slices.Equal(got, []string{"x/a", "x/b"}) {
+		t.Errorf("packs = %v, want the prefixed graph nodes", got)
+	}
+	if index.Metadata.PackCount != 2 || !index.Packs[0].Synthetic {
+		t.Errorf("index = %+v", index)
+	}
+	if len(graph.Edges) != 1 {
+		t.Errorf("graph edges = %v, want the one inside the prefix", graph.Edges)
+	}
+
+	if _, err := (&Loader{GraphOnly: true, FS: fstest.MapFS{}}).LoadIndex(); !errors.Is(err, ErrDistNotFound) {
+		t.Errorf("no graph: err = %v, want ErrDistNotFound", err)
+	}
+}
+
+func TestLoaderSymmetricTypes(t *testing.T) {
+	var logs bytes.Buffer
+	logger, _ := newLogger(&logs, 1, LogFormatText)
+	loader := &Loader{Logger: logger, SymmetricTypes: []string{"related"}, FS: fstest.MapFS{
+		GraphFile: {Data: []byte(`{"edges":[{"source":"B","target":"A","type":"related"},{"source":"A","target":"B","type":"related"},` +
+			`{"source":"B","target":"A","type":"child"}]}`)},
+	}}
+
+	graph, err := loader.LoadGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GraphEdge{{Source: "A", Target: "B", Type: "related"}, {Source: "B", Target: "A", Type: "child"}}
+	if !reflect.DeepEqual(graph.Edges, want) {
+		t.Errorf("edges = %v, want %v", graph.Edges, want)
+	}
+	if !strings.Contains(logs.String(), "normalized symmetric edges") || !strings.Contains(logs.String(), "collapsed=1") {
+		t.Errorf("logs = %q, want the collapsed count", logs.String())
+	}
+}
+
+func TestLoaderEmptyBasePath(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, `{"metadata":{"pack_count":1},"packs":[{"id":"A"}]}`)
+	t.Chdir(dir)
+
+	index, err := (&Loader{}).LoadIndex()
+	if err != nil || len(index.Packs) != 1 {
+		t.Errorf("empty BasePath: %+v, %v; want the index in the current directory", index, err)
+	}
+}
@@ -0,0 +1,243 @@
+// ORIGIN Go Kit - command output
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+)
+
+// outputFormat selects the Formatter a run writes with
+type outputFormat int
+
+const (
+	formatText outputFormat = iota
+	formatJSON
+	formatIDs
+	formatCSV
+	formatNDJSON
+)
+
+// Formatter writes command output in one format. main selects it once from
+// the output flags, so commands never branch on them: listings of plain
+// packs or edges go through Packs and Edges, and every other command
+// result through Result.
+type Formatter interface {
+	Packs([]Pack) error
+	Edges([]GraphEdge) error
+	Result(v any) error
+}
+
+// newFormatter returns the Formatter for format, writing to w
+func newFormatter(w io.Writer, format outputFormat) Formatter {
+	switch format {
+	case formatJSON:
+		return jsonFormatter{w}
+	case formatIDs:
+		return idsFormatter{w}
+	case formatCSV:
+		return csvFormatter{w}
+	case formatNDJSON:
+		return ndjsonFormatter{w}
+	}
+	return textFormatter{w}
+}
+
+// textOutput is implemented by command results with a human-readable form
+type textOutput interface {
+	writeText(w io.Writer)
+}
+
+// idOutput is implemented by listing results, whose pack IDs -ids-only
+// prints one per line
+type idOutput interface {
+	ids() []string
+}
+
+// packLister is implemented by results that are a list of packs,
+// which -csv writes as rows
+type packLister interface {
+	packList() []Pack
+}
+
+// edgeLister is implemented by results that are a list of edges
+type edgeLister interface {
+	edgeList() []GraphEdge
+}
+
+// recordLister is implemented by results that list records other than
+// packs and edges, such as neighbors, which -ndjson writes one per line
+type recordLister interface {
+	records() iter.Seq[any]
+}
+
+// packIDs returns the IDs of packs in order
+func packIDs(packs []Pack) []string {
+	return listIDs(packs, func(p Pack) string { return p.ID })
+}
+
+// listIDs returns the ID that id gives for each item of a listing, in
+// order; results use it for ids
+func listIDs[T any](items []T, id func(T) string) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = id(item)
+	}
+	return ids
+}
+
+// listRecords yields each item of a listing as one record; results use
+// it for records
+func listRecords[T any](items []T) iter.Seq[any] {
+	return func(yield func(any) bool) {
+		for _, item := range items {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// textFormatter writes decorated text for people
+type textFormatter struct{ w io.Writer }
+
+func (f textFormatter) Packs(packs []Pack) error {
+	for _, p := range packs {
+		if _, err := fmt.Fprintf(f.w, "  - %s: %s\n", colorID(p.ID), colorTitle(p.Title)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f textFormatter) Edges(edges []GraphEdge) error {
+	for _, edge := range edges {
+		if _, err := fmt.Fprintf(f.w, "  %s -> %s (%s)\n", colorID(edge.Source), colorID(edge.Target), colorType(edge.Type)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f textFormatter) Result(v any) error {
+	if t, ok := v.(textOutput); ok {
+		t.writeText(f.w)
+		return nil
+	}
+	_, err := fmt.Fprintln(f.w, v)
+	return err
+}
+
+// jsonFormatter writes indented JSON for -json
+type jsonFormatter struct{ w io.Writer }
+
+func (f jsonFormatter) Packs(packs []Pack) error      { return f.Result(packs) }
+func (f jsonFormatter) Edges(edges []GraphEdge) error { return f.Result(edges) }
+
+func (f jsonFormatter) Result(v any) error {
+	enc := json.NewEncoder(f.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// idsFormatter writes bare pack IDs, one per line, for -ids-only
+type idsFormatter struct{ w io.Writer }
+
+// errNotPackListing is returned by idsFormatter for output that does not
+// list packs
+var errNotPackListing = errors.New("-ids-only only applies to commands that list packs")
+
+func (f idsFormatter) Packs(packs []Pack) error { return f.writeIDs(packIDs(packs)) }
+func (f idsFormatter) Edges([]GraphEdge) error  { return errNotPackListing }
+
+func (f idsFormatter) Result(v any) error {
+	l, ok := v.(idOutput)
+	if !ok {
+		return errNotPackListing
+	}
+	return f.writeIDs(l.ids())
+}
+
+func (f idsFormatter) writeIDs(ids []string) error {
+	for _, id := range ids {
+		if _, err := fmt.Fprintln(f.w, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// csvFormatter writes pack and edge listings as CSV rows for -csv
+type csvFormatter struct{ w io.Writer }
+
+func (f csvFormatter) Packs(packs []Pack) error      { return WritePacksCSV(f.w, packs) }
+func (f csvFormatter) Edges(edges []GraphEdge) error { return writeEdgesCSV(f.w, edges) }
+
+func (f csvFormatter) Result(v any) error {
+	switch l := v.(type) {
+	case packLister:
+		return f.Packs(l.packList())
+	case edgeLister:
+		return f.Edges(l.edgeList())
+	}
+	return errors.New("-csv only applies to commands that list packs or edges")
+}
+
+// ndjsonFormatter writes listings as one compact JSON object per line for
+// -ndjson, and other results as a single line. Listings are built in full
+// before they are written.
+type ndjsonFormatter struct{ w io.Writer }
+
+func (f ndjsonFormatter) Packs(packs []Pack) error {
+	return encodeNDJSON(f.w, slices.Values(packs), nil)
+}
+
+func (f ndjsonFormatter) Edges(edges []GraphEdge) error {
+	return encodeNDJSON(f.w, slices.Values(edges), nil)
+}
+
+func (f ndjsonFormatter) Result(v any) error {
+	switch l := v.(type) {
+	case packLister:
+		return f.Packs(l.packList())
+	case edgeLister:
+		return f.Edges(l.edgeList())
+	case recordLister:
+		return encodeNDJSON(f.w, l.records(), nil)
+	}
+	return encodeNDJSON(f.w, slices.Values([]any{v}), nil)
+}
+
+// ndjsonFlushLines is how many lines encodeNDJSON writes between flushes
+const ndjsonFlushLines = 64
+
+// encodeNDJSON writes each of items to w as one line of compact JSON,
+// calling flush, if set, every ndjsonFlushLines lines and
+// at the end. An item that fails to encode ends the stream with a final
+// {"error": "..."} line, so readers can tell it from a complete one, and
+// its error is returned.
+func encodeNDJSON[T any](w io.Writer, items iter.Seq[T], flush func()) error {
+	if flush == nil {
+		flush = func() {}
+	}
+	defer flush()
+	enc := json.NewEncoder(w)
+	n := 0
+	for item := range items {
+		if err := enc.Encode(item); err != nil {
+			// Encode writes nothing for a value that fails to marshal
+			enc.Encode(errorBody{Error: err.Error()})
+			return err
+		}
+		if n++; n%ndjsonFlushLines == 0 {
+			flush()
+		}
+	}
+	return nil
+}
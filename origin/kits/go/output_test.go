@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFormatters(t *testing.T) {
+	packs := samplePacks()[:2]
+	edges := []GraphEdge{{Source: "B", Target: "A", Type: "child"}, {Source: "A", Target: "B", Type: "related"}}
+
+	tests := []struct {
+		format       outputFormat
+		packs, edges string
+	}{
+		{formatText, "  - A: Alpha\n  - B: Beta\n", "  B -> A (child)\n  A -> B (related)\n"},
+		{formatIDs, "A\nB\n", ""},
+		{formatCSV, "id,title,disclosure_tier,related\nA,Alpha,public,\nB,Beta,internal,\n", "source,target,type\nB,A,child\nA,B,related\n"},
+		{formatNDJSON, `{"id":"A","title":"Alpha","disclosure_tier":"public","related":null}` + "\n" + `{"id":"B","title":"Beta","disclosure_tier":"internal","related":null}` + "\n",
+			`{"source":"B","target":"A","type":"child"}` + "\n" + `{"source":"A","target":"B","type":"related"}` + "\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		f := newFormatter(&out, tt.format)
+		if err := f.Packs(packs); err != nil || out.String() != tt.packs {
+			t.Errorf("format %d packs = %q, %v; want %q", tt.format, out.String(), err, tt.packs)
+		}
+		out.Reset()
+		err := f.Edges(edges)
+		if tt.edges == "" {
+			if err == nil {
+				t.Errorf("format %d accepted edges", tt.format)
+			}
+		} else if err != nil || out.String() != tt.edges {
+			t.Errorf("format %d edges = %q, %v; want %q", tt.format, out.String(), err, tt.edges)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := newFormatter(&out, formatJSON).Packs(packs[:1]); err != nil {
+		t.Fatalf("JSON packs: %v", err)
+	}
+	if want := "[\n  {\n    \"id\": \"A\",\n    \"title\": \"Alpha\",\n    \"disclosure_tier\": \"public\",\n    \"related\": null\n  }\n]\n"; out.String() != want {
+		t.Errorf("JSON packs = %q, want %q", out.String(), want)
+	}
+}
+
+func TestFormatterResult(t *testing.T) {
+	var out bytes.Buffer
+	result := searchResult{Query: "a", Matches: samplePacks()}
+	if err := newFormatter(&out, formatIDs).Result(result); err != nil {
+		t.Fatalf("Result: %v", err)
+	}
+	if got, want := out.String(), "A\nB\nC\n"; got != want {
+		t.Errorf("ids-only output = %q, want %q", got, want)
+	}
+	if err := newFormatter(&out, formatIDs).Result(tiersResult{}); err == nil {
+		t.Error("ids-only accepted a result that lists no packs")
+	}
+
+	out.Reset()
+	if err := newFormatter(&out, formatCSV).Result(result); err != nil {
+		t.Fatalf("CSV Result: %v", err)
+	}
+	if got := out.String(); !strings.HasPrefix(got, "id,title,") || strings.Count(got, "\n") != 4 {
+		t.Errorf("CSV search output = %q, want a header and 3 rows", got)
+	}
+	if err := newFormatter(&out, formatCSV).Result(tiersResult{}); err == nil {
+		t.Error("CSV accepted a result that lists no packs or edges")
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	var out bytes.Buffer
+	f := newFormatter(&out, formatNDJSON)
+	n := neighborsResult{ID: "A", Neighbors: []neighbor{{ID: "B", Direction: "out"}, {ID: "C", Direction: "in"}}}
+	if err := f.Result(n); err != nil || strings.Count(out.String(), "\n") != 2 || !strings.Contains(out.String(), `"id":"B"`) {
+		t.Errorf("neighbors = %q, %v; want one line per neighbor", out.String(), err)
+	}
+	out.Reset()
+	if err := f.Result(tiersResult{}); err != nil || strings.Count(out.String(), "\n") != 1 {
+		t.Errorf("non-listing = %q, %v; want one line", out.String(), err)
+	}
+
+	out.Reset()
+	flushes := 0
+	items := []any{1, math.Inf(1), 3}
+	err := encodeNDJSON(&out, slices.Values(items), func() { flushes++ })
+	if err == nil || !strings.HasPrefix(out.String(), "1\n{\"error\":") || strings.Contains(out.String(), "3") {
+		t.Errorf("mid-stream failure = %q, %v; want the first item then an error line", out.String(), err)
+	}
+	if flushes != 1 {
+		t.Errorf("flushed %d times, want once at the end", flushes)
+	}
+}
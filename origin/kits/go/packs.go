@@ -0,0 +1,640 @@
+// ORIGIN Go Kit - pack queries
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrPackNotFound is returned when a pack ID is not in the index
+var ErrPackNotFound = errors.New("pack not found")
+
+// TierAll selects every pack regardless of disclosure tier
+const TierAll = "all"
+
+// TierNone buckets packs with an empty disclosure tier
+const TierNone = "(none)"
+
+// PackMap maps pack IDs to packs; build one with ByID and reuse it for
+// repeated lookups
+type PackMap map[string]Pack
+
+// ByID maps pack IDs to packs. When an ID appears more than once, the
+// first occurrence wins.
+func (idx PacksIndex) ByID() PackMap {
+	byID := make(PackMap, len(idx.Packs))
+	for _, p := range idx.Packs {
+		if _, ok := byID[p.ID]; !ok {
+			byID[p.ID] = p
+		}
+	}
+	return byID
+}
+
+// Get returns the first pack with the given ID. It builds a PackMap on
+// each call; use ByID for repeated lookups.
+func (idx PacksIndex) Get(id string) (Pack, bool) {
+	return idx.ByID().Get(id)
+}
+
+// Get returns the pack with the given ID
+func (m PackMap) Get(id string) (Pack, bool) {
+	p, ok := m[id]
+	return p, ok
+}
+
+// LookupResult is the outcome of resolving one ID. Pack is nil when the ID
+// is not in the index.
+type LookupResult struct {
+	ID    string `json:"id"`
+	Found bool   `json:"found"`
+	Pack  *Pack  `json:"pack,omitempty"`
+}
+
+// LookupMany resolves each ID against the index, keeping input order and
+// any repeated IDs
+func LookupMany(index PacksIndex, ids []string) []LookupResult {
+	byID := index.ByID()
+	results := make([]LookupResult, 0, len(ids))
+	for _, id := range ids {
+		result := LookupResult{ID: id}
+		if p, ok := byID[id]; ok {
+			result.Found = true
+			result.Pack = &p
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Referrers returns the IDs of packs that list id in their Related field,
+// in index order, excluding id itself. Graph.Referrers covers edges.
+func (idx PacksIndex) Referrers(id string) []string {
+	var refs []string
+	for _, p := range idx.Packs {
+		if p.ID != id && slices.Contains(p.Related, id) {
+			refs = append(refs, p.ID)
+		}
+	}
+	return refs
+}
+
+// FilterByTier returns packs whose disclosure tier is one of tiers. If
+// tiers contains TierAll, every pack is returned.
+func FilterByTier(packs []Pack, tiers []string) []Pack {
+	want := make(map[string]bool, len(tiers))
+	for _, tier := range tiers {
+		if tier == TierAll {
+			return packs
+		}
+		want[tier] = true
+	}
+	var filtered []Pack
+	for _, p := range packs {
+		if want[p.DisclosureTier] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// FilterByTag returns packs carrying tag. Packs without tags never match.
+func FilterByTag(packs []Pack, tag string) []Pack {
+	matches := []Pack{}
+	for _, p := range packs {
+		if slices.Contains(p.Tags, tag) {
+			matches = append(matches, p)
+		}
+	}
+	return matches
+}
+
+// FilterByPrefix returns packs whose IDs start with prefix. Use a trailing
+// "/", as in "core/", to match one namespace exactly.
+func FilterByPrefix(packs []Pack, prefix string) []Pack {
+	matches := []Pack{}
+	for _, p := range packs {
+		if strings.HasPrefix(p.ID, prefix) {
+			matches = append(matches, p)
+		}
+	}
+	return matches
+}
+
+// namespaceOf returns the text of id before its first "/", or "" for an
+// ID without one
+func namespaceOf(id string) string {
+	ns, _, found := strings.Cut(id, "/")
+	if !found {
+		return ""
+	}
+	return ns
+}
+
+// Namespaces returns the distinct namespaces of the packs' IDs, sorted.
+// IDs without a "/" belong to the empty namespace.
+func Namespaces(packs []Pack) []string {
+	seen := make(map[string]bool)
+	for _, p := range packs {
+		seen[namespaceOf(p.ID)] = true
+	}
+	return slices.Sorted(maps.Keys(seen))
+}
+
+// TagCounts returns the number of packs carrying each tag. A tag repeated
+// within one pack counts once.
+func TagCounts(packs []Pack) map[string]int {
+	counts := make(map[string]int)
+	for _, p := range packs {
+		seen := make(map[string]bool, len(p.Tags))
+		for _, tag := range p.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				counts[tag]++
+			}
+		}
+	}
+	return counts
+}
+
+// Paginate returns the page of packs starting at offset. A negative
+// offset counts as 0 and an offset past the end yields an empty slice; a
+// negative limit means no limit.
+func Paginate(packs []Pack, offset, limit int) []Pack {
+	offset = max(offset, 0)
+	if offset >= len(packs) {
+		return []Pack{}
+	}
+	end := len(packs)
+	if limit >= 0 && limit < end-offset {
+		end = offset + limit
+	}
+	return packs[offset:end]
+}
+
+// SamplePacks returns n packs chosen uniformly at random using reservoir
+// sampling, in shuffled order. The same seed always gives the same sample;
+// asking for more packs than exist returns all of them shuffled. packs is
+// not modified.
+func SamplePacks(packs []Pack, n int, seed int64) []Pack {
+	if n <= 0 {
+		return []Pack{}
+	}
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	sample := make([]Pack, 0, min(n, len(packs)))
+	for i, p := range packs {
+		if i < n {
+			sample = append(sample, p)
+		} else if j := rng.IntN(i + 1); j < n {
+			sample[j] = p
+		}
+	}
+	rng.Shuffle(len(sample), func(i, j int) { sample[i], sample[j] = sample[j], sample[i] })
+	return sample
+}
+
+// Sort keys accepted by SortPacks
+const (
+	SortByID    = "id"
+	SortByTitle = "title"
+	SortByTier  = "tier"
+)
+
+// SortPacks sorts packs in place by ID, title or tier. Title and tier ties
+// are broken by ID; tiers follow the current tier order, with unknown tiers
+// last in name order. An unknown key is an error.
+func SortPacks(packs []Pack, by string) error {
+	var less func(a, b Pack) int
+	switch by {
+	case SortByID:
+		less = func(a, b Pack) int { return cmp.Compare(a.ID, b.ID) }
+	case SortByTitle:
+		less = func(a, b Pack) int {
+			return cmp.Or(cmp.Compare(a.Title, b.Title), cmp.Compare(a.ID, b.ID))
+		}
+	case SortByTier:
+		rank := func(p Pack) int {
+			if r := tierRank(p.DisclosureTier); r >= 0 {
+				return r
+			}
+			return len(tierOrder)
+		}
+		less = func(a, b Pack) int {
+			return cmp.Or(cmp.Compare(rank(a), rank(b)),
+				cmp.Compare(a.DisclosureTier, b.DisclosureTier), cmp.Compare(a.ID, b.ID))
+		}
+	default:
+		return fmt.Errorf("unknown sort key %q (want %s, %s or %s)", by, SortByID, SortByTitle, SortByTier)
+	}
+	slices.SortStableFunc(packs, less)
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping blank entries
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// listFlag is a flag that may be repeated, each value a comma-separated
+// list, collecting every entry
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, splitList(s)...)
+	return nil
+}
+
+// FindOrphans returns packs that appear in no graph edge and declare no
+// related packs
+func FindOrphans(index PacksIndex, graph Graph) []Pack {
+	adj := graph.BuildAdjacency()
+	var orphans []Pack
+	for _, p := range index.Packs {
+		if len(adj[p.ID]) == 0 && len(p.Related) == 0 {
+			orphans = append(orphans, p)
+		}
+	}
+	return orphans
+}
+
+// IncompletePacks returns the packs, in order, with an ID, title or
+// disclosure tier that is empty or only whitespace; see MissingFields
+func IncompletePacks(packs []Pack) []Pack {
+	incomplete := []Pack{}
+	for _, p := range packs {
+		if len(p.MissingFields()) > 0 {
+			incomplete = append(incomplete, p)
+		}
+	}
+	return incomplete
+}
+
+// MissingFields returns the JSON keys of the required fields p leaves
+// blank, of id, title and disclosure_tier, in that order
+func (p Pack) MissingFields() []string {
+	var missing []string
+	for _, f := range []struct{ key, value string }{
+		{"id", p.ID}, {"title", p.Title}, {"disclosure_tier", p.DisclosureTier},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			missing = append(missing, f.key)
+		}
+	}
+	return missing
+}
+
+// StalePacks returns the packs last updated more than olderThan ago,
+// oldest first, ties by ID. Packs with no UpdatedAt are left out; see
+// UnknownAge.
+func StalePacks(packs []Pack, olderThan time.Duration) []Pack {
+	return stalePacks(packs, time.Now().Add(-olderThan))
+}
+
+// stalePacks is StalePacks for packs updated before cutoff
+func stalePacks(packs []Pack, cutoff time.Time) []Pack {
+	stale := []Pack{}
+	for _, p := range packs {
+		if !p.UpdatedAt.IsZero() && p.UpdatedAt.Before(cutoff) {
+			stale = append(stale, p)
+		}
+	}
+	slices.SortStableFunc(stale, func(a, b Pack) int {
+		return cmp.Or(a.UpdatedAt.Compare(b.UpdatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return stale
+}
+
+// UnknownAge returns the packs with no UpdatedAt, in order
+func UnknownAge(packs []Pack) []Pack {
+	unknown := []Pack{}
+	for _, p := range packs {
+		if p.UpdatedAt.IsZero() {
+			unknown = append(unknown, p)
+		}
+	}
+	return unknown
+}
+
+// SyntheticTier is the tier SynthesizeIndex gives its packs, so the
+// default -tier lists them
+const SyntheticTier = "public"
+
+// SynthesizeIndex builds a minimal index for a graph that came without
+// one: a pack for each distinct edge endpoint, sorted by ID, with an empty
+// title, no related IDs, SyntheticTier and Synthetic set. PackCount is the
+// number of packs.
+func SynthesizeIndex(graph Graph) PacksIndex {
+	ids, _ := sortedNodes(graph.BuildAdjacency())
+	index := PacksIndex{Packs: make([]Pack, len(ids))}
+	for i, id := range ids {
+		index.Packs[i] = Pack{ID: id, DisclosureTier: SyntheticTier, Related: []string{}, Synthetic: true}
+	}
+	index.Metadata.PackCount = len(index.Packs)
+	return index
+}
+
+// ExpandRelated resolves p.Related to packs in order, skipping IDs that
+// are not in index. The number skipped is len(p.Related) minus the length
+// of the result.
+func ExpandRelated(index PacksIndex, p Pack) []Pack {
+	byID := index.ByID()
+	expanded := []Pack{}
+	for _, id := range p.Related {
+		if rp, ok := byID[id]; ok {
+			expanded = append(expanded, rp)
+		}
+	}
+	return expanded
+}
+
+// EdgesFromRelated returns one edgeType edge from each pack to each ID in
+// its Related list, in pack order, skipping IDs that are not packs
+func EdgesFromRelated(packs []Pack, edgeType string) []GraphEdge {
+	known := make(map[string]bool, len(packs))
+	for _, p := range packs {
+		known[p.ID] = true
+	}
+
+	edges := []GraphEdge{}
+	for _, p := range packs {
+		for _, id := range p.Related {
+			if known[id] {
+				edges = append(edges, GraphEdge{Source: p.ID, Target: id, Type: edgeType})
+			}
+		}
+	}
+	return edges
+}
+
+// CombinedGraph returns graph followed by a relatedType edge for each
+// Related entry not already present, with the metadata counts recomputed.
+// Only the derived edges are merged: one is dropped when an explicit edge
+// or an earlier derived one has its source, target and type. The explicit
+// edges, repeats included, and the rest of graph's metadata are kept as
+// they are.
+func CombinedGraph(index PacksIndex, graph Graph, relatedType string) Graph {
+	type key struct{ source, target, typ string }
+	seen := make(map[key]bool, len(graph.Edges))
+	for _, edge := range graph.Edges {
+		seen[key{edge.Source, edge.Target, edge.Type}] = true
+	}
+	combined := graph
+	combined.Edges = slices.Clone(graph.Edges)
+	for _, edge := range EdgesFromRelated(index.Packs, relatedType) {
+		if k := (key{edge.Source, edge.Target, edge.Type}); !seen[k] {
+			seen[k] = true
+			combined.Edges = append(combined.Edges, edge)
+		}
+	}
+	return combined.RecomputeMetadata()
+}
+
+// RelatedConsistency compares p.Related with p's neighbors in g. missing
+// lists related IDs with no edge; extra lists neighbors not in p.Related.
+func (p Pack) RelatedConsistency(g Graph) (missing []string, extra []string) {
+	return p.relatedConsistency(g.BuildAdjacency()[p.ID])
+}
+
+// relatedConsistency is RelatedConsistency given the edges incident to p
+func (p Pack) relatedConsistency(incident []GraphEdge) (missing []string, extra []string) {
+	neighbors := make(map[string]bool)
+	var order []string
+	for _, edge := range incident {
+		otherID := otherEnd(edge, p.ID)
+		if !neighbors[otherID] {
+			neighbors[otherID] = true
+			order = append(order, otherID)
+		}
+	}
+
+	related := make(map[string]bool, len(p.Related))
+	for _, id := range p.Related {
+		related[id] = true
+		if !neighbors[id] {
+			missing = append(missing, id)
+		}
+	}
+	for _, id := range order {
+		if !related[id] {
+			extra = append(extra, id)
+		}
+	}
+	return missing, extra
+}
+
+// Fields Search can match, as SearchOptions.Fields
+const (
+	SearchID    = "id"
+	SearchTitle = "title"
+)
+
+// SearchOptions selects what Search matches. The zero value, apart from
+// Query, is a case-insensitive substring search of titles.
+type SearchOptions struct {
+	Query string
+	// Fields are the pack fields to match, SearchID and SearchTitle; a
+	// pack matches if any of them does. Empty means SearchTitle.
+	Fields []string
+	// CaseSensitive compares case exactly
+	CaseSensitive bool
+	// WholeWord only matches the query where it is not joined to letters
+	// or digits on either side, so "seed" matches "Vision Seed" and
+	// "seed-pack" but not "seedling"
+	WholeWord bool
+}
+
+// Search returns the packs matching opts, in order. An empty query
+// matches nothing.
+func Search(packs []Pack, opts SearchOptions) []Pack {
+	if opts.Query == "" {
+		return nil
+	}
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = []string{SearchTitle}
+	}
+	query := opts.Query
+	if !opts.CaseSensitive {
+		query = strings.ToLower(query)
+	}
+	matches := func(s string) bool {
+		if !opts.CaseSensitive {
+			s = strings.ToLower(s)
+		}
+		if opts.WholeWord {
+			return containsWord(s, query)
+		}
+		return strings.Contains(s, query)
+	}
+
+	var found []Pack
+	for _, p := range packs {
+		if (slices.Contains(fields, SearchTitle) && matches(p.Title)) ||
+			(slices.Contains(fields, SearchID) && matches(p.ID)) {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// containsWord reports whether word occurs in s with no letter or digit
+// directly before or after it
+func containsWord(s, word string) bool {
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for from := 0; from <= len(s)-len(word); {
+		i := strings.Index(s[from:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(word)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(s) || !isWordRune(after)) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(s[start:])
+		from = start + size
+	}
+	return false
+}
+
+// SearchByTitle returns packs whose title contains query, ignoring case.
+// An empty query matches nothing.
+func SearchByTitle(packs []Pack, query string) []Pack {
+	return Search(packs, SearchOptions{Query: query})
+}
+
+// TierCounts returns the number of packs in each disclosure tier
+func TierCounts(packs []Pack) map[string]int {
+	counts := make(map[string]int)
+	for _, p := range packs {
+		tier := p.DisclosureTier
+		if tier == "" {
+			tier = TierNone
+		}
+		counts[tier]++
+	}
+	return counts
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// ClosestIDs returns up to n pack IDs nearest to query by edit distance,
+// ties broken by ID. IDs more than half the query's length away, rounded
+// up, are too different to suggest and are left out; a negative n returns
+// none.
+func ClosestIDs(index PacksIndex, query string, n int) []string {
+	return closestIDs(index.ByID(), query, n)
+}
+
+func closestIDs(byID PackMap, query string, n int) []string {
+	n = max(n, 0)
+	maxDistance := (utf8.RuneCountInString(query) + 1) / 2
+	distance := make(map[string]int, len(byID))
+	ids := []string{}
+	for id := range byID {
+		if d := levenshtein(query, id); d <= maxDistance {
+			distance[id] = d
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if distance[ids[i]] != distance[ids[j]] {
+			return distance[ids[i]] < distance[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > n {
+		ids = ids[:n]
+	}
+	return ids
+}
+
+// suggestCount is how many alternatives requirePack offers on a miss
+const suggestCount = 3
+
+// requirePack returns the pack with the given ID from byID, or an error
+// wrapping ErrPackNotFound that suggests the closest real IDs
+func requirePack(byID PackMap, id string) (Pack, error) {
+	if p, ok := byID.Get(id); ok {
+		return p, nil
+	}
+	if closest := closestIDs(byID, id, suggestCount); len(closest) > 0 {
+		return Pack{}, fmt.Errorf("%w: %s (did you mean: %s?)", ErrPackNotFound, id, strings.Join(closest, ", "))
+	}
+	return Pack{}, fmt.Errorf("%w: %s", ErrPackNotFound, id)
+}
+
+// LabelID returns "id (title)" for the pack with the given ID, or just id
+// if index has no such pack or its title is blank
+func LabelID(index PacksIndex, id string) string {
+	return labelID(index.ByID(), id)
+}
+
+func labelID(byID map[string]Pack, id string) string {
+	if p, ok := byID[id]; ok && strings.TrimSpace(p.Title) != "" {
+		return id + " (" + p.Title + ")"
+	}
+	return id
+}
+
+// withTitles makes text output label graph IDs with their titles. Like
+// colorOutput it is set by main, from -with-titles.
+var withTitles bool
+
+// idLabels renders the pack IDs of neighbors and paths in text output; nil
+// leaves them bare
+type idLabels map[string]Pack
+
+// newIDLabels returns labels for the packs of index under -with-titles
+func newIDLabels(index PacksIndex) idLabels {
+	if !withTitles {
+		return nil
+	}
+	return idLabels(index.ByID())
+}
+
+// label styles id, with its title under -with-titles
+func (l idLabels) label(id string) string {
+	return colorID(labelID(l, id))
+}
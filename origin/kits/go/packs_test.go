@@ -0,0 +1,513 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func samplePacks() []Pack {
+	return []Pack{
+		{ID: "A", Title: "Alpha", DisclosureTier: "public"},
+		{ID: "B", Title: "Beta", DisclosureTier: "internal"},
+		{ID: "C", Title: "Gamma", DisclosureTier: "public"},
+	}
+}
+
+func TestFilterByTier(t *testing.T) {
+	packs := samplePacks()
+
+	if got := FilterByTier(packs, []string{"public"}); len(got) != 2 || got[0].ID != "A" || got[1].ID != "C" {
+		t.Errorf("public: got %+v", got)
+	}
+	if got := FilterByTier(packs, []string{"internal", "public"}); len(got) != 3 {
+		t.Errorf("internal,public: got %d packs, want 3", len(got))
+	}
+	if got := FilterByTier(packs, []string{"restricted"}); len(got) != 0 {
+		t.Errorf("restricted: got %+v", got)
+	}
+	if got := FilterByTier(packs, nil); len(got) != 0 {
+		t.Errorf("empty list: got %+v", got)
+	}
+	if got := FilterByTier(packs, []string{"restricted", TierAll}); len(got) != len(packs) {
+		t.Errorf("all: got %d packs, want %d", len(got), len(packs))
+	}
+}
+
+func TestFilterByTag(t *testing.T) {
+	var index PacksIndex
+	input := `{"packs":[{"id":"A","tags":["seed","ui"]},{"id":"B"},{"id":"C","tags":["seed","seed"]}]}`
+	if err := json.Unmarshal([]byte(input), &index); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := packIDs(FilterByTag(index.Packs, "seed")); !reflect.DeepEqual(got, []string{"A", "C"}) {
+		t.Errorf("seed: got %v, want [A C]", got)
+	}
+	if got := FilterByTag(index.Packs, ""); len(got) != 0 {
+		t.Errorf("empty tag matched %+v", got)
+	}
+	if want := map[string]int{"seed": 2, "ui": 1}; !reflect.DeepEqual(TagCounts(index.Packs), want) {
+		t.Errorf("TagCounts = %v, want %v", TagCounts(index.Packs), want)
+	}
+}
+
+func TestLookupMany(t *testing.T) {
+	index := PacksIndex{Packs: samplePacks()}
+
+	got := LookupMany(index, []string{"C", "X", "A", "C"})
+	var ids []string
+	for _, r := range got {
+		ids = append(ids, r.ID)
+		if r.Found != (r.Pack != nil) || (r.Found && r.Pack.ID != r.ID) {
+			t.Errorf("result %+v is inconsistent", r)
+		}
+	}
+	if !reflect.DeepEqual(ids, []string{"C", "X", "A", "C"}) {
+		t.Errorf("IDs = %v, want input order", ids)
+	}
+	if got[1].Found || !got[2].Found {
+		t.Errorf("found = %v, %v; want false, true", got[1].Found, got[2].Found)
+	}
+}
+
+func TestIndexReferrers(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", Related: []string{"B", "C"}},
+		{ID: "B", Related: []string{"B"}},
+		{ID: "C", Related: []string{"B"}},
+	}}
+	if got := index.Referrers("B"); !reflect.DeepEqual(got, []string{"A", "C"}) {
+		t.Errorf("Referrers(B) = %v, want [A C]", got)
+	}
+}
+
+func TestSortPacks(t *testing.T) {
+	packs := []Pack{
+		{ID: "C", Title: "Alpha", DisclosureTier: "internal"},
+		{ID: "A", Title: "Gamma", DisclosureTier: "zeta"},
+		{ID: "D", Title: "Alpha", DisclosureTier: "public"},
+		{ID: "B", Title: "Beta", DisclosureTier: "internal"},
+		{ID: "E", Title: "Beta", DisclosureTier: "omega"},
+	}
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{SortByID, []string{"A", "B", "C", "D", "E"}},
+		{SortByTitle, []string{"C", "D", "B", "E", "A"}},
+		{SortByTier, []string{"D", "B", "C", "E", "A"}},
+	}
+	for _, tt := range tests {
+		sorted := slices.Clone(packs)
+		if err := SortPacks(sorted, tt.by); err != nil {
+			t.Fatalf("SortPacks(%s): %v", tt.by, err)
+		}
+		var got []string
+		for _, p := range sorted {
+			got = append(got, p.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SortPacks(%s) = %v, want %v", tt.by, got, tt.want)
+		}
+	}
+
+	if err := SortPacks(packs, "size"); err == nil || !strings.Contains(err.Error(), `"size"`) {
+		t.Errorf("SortPacks(size) error = %v", err)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	packs := samplePacks()
+	tests := []struct {
+		offset, limit int
+		want          []string
+	}{
+		{0, 2, []string{"A", "B"}},
+		{1, 5, []string{"B", "C"}},
+		{2, -1, []string{"C"}},
+		{0, -1, []string{"A", "B", "C"}},
+		{-3, 1, []string{"A"}},
+		{3, 1, []string{}},
+		{10, -1, []string{}},
+		{0, 0, []string{}},
+		{1, math.MaxInt, []string{"B", "C"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, p := range Paginate(packs, tt.offset, tt.limit) {
+			got = append(got, p.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Paginate(%d, %d) = %v, want %v", tt.offset, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSamplePacks(t *testing.T) {
+	packs := make([]Pack, 20)
+	for i := range packs {
+		packs[i] = Pack{ID: string(rune('a' + i))}
+	}
+	ids := func(ps []Pack) []string {
+		var out []string
+		for _, p := range ps {
+			out = append(out, p.ID)
+		}
+		return out
+	}
+
+	first := SamplePacks(packs, 5, 42)
+	if len(first) != 5 {
+		t.Fatalf("sample = %v, want 5 packs", ids(first))
+	}
+	if again := SamplePacks(packs, 5, 42); !reflect.DeepEqual(ids(first), ids(again)) {
+		t.Errorf("same seed gave %v then %v", ids(first), ids(again))
+	}
+	seen := make(map[string]bool)
+	for _, p := range first {
+		if seen[p.ID] {
+			t.Errorf("sample %v repeats %s", ids(first), p.ID)
+		}
+		seen[p.ID] = true
+	}
+
+	all := ids(SamplePacks(packs, 50, 7))
+	if len(all) != len(packs) {
+		t.Fatalf("oversized sample has %d packs, want %d", len(all), len(packs))
+	}
+	if slices.IsSorted(all) {
+		t.Errorf("oversized sample %v was not shuffled", all)
+	}
+	slices.Sort(all)
+	if !reflect.DeepEqual(all, ids(packs)) {
+		t.Errorf("oversized sample = %v, want every pack", all)
+	}
+
+	if got := SamplePacks(packs, 0, 1); len(got) != 0 {
+		t.Errorf("n=0 sample = %v, want none", ids(got))
+	}
+}
+
+func TestExpandRelated(t *testing.T) {
+	index := PacksIndex{Packs: samplePacks()}
+	p := Pack{ID: "X", Related: []string{"C", "Z", "A"}}
+
+	got := ExpandRelated(index, p)
+	if ids := packIDs(got); len(ids) != 2 || ids[0] != "C" || ids[1] != "A" || got[0].Title != "Gamma" {
+		t.Errorf("ExpandRelated = %+v, want C and A with titles", got)
+	}
+}
+
+func TestEdgesFromRelated(t *testing.T) {
+	packs := []Pack{
+		{ID: "A", Related: []string{"B", "Z", "C"}},
+		{ID: "B", Related: []string{"A"}},
+		{ID: "C"},
+	}
+
+	got := EdgesFromRelated(packs, "related")
+	want := []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "B", Target: "A", Type: "related"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EdgesFromRelated = %v, want %v", got, want)
+	}
+}
+
+func TestCombinedGraph(t *testing.T) {
+	packs := samplePacks()
+	packs[0].Related = []string{"B", "C", "Z", "C"}
+	graph := Graph{SchemaVersion: 1, Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related", Weight: 2},
+		{Source: "A", Target: "B", Type: "related"},
+	}}
+
+	got := CombinedGraph(PacksIndex{Packs: packs}, graph, "related")
+	want := []GraphEdge{
+		{Source: "A", Target: "B", Type: "related", Weight: 2},
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "A", Target: "C", Type: "related"},
+	}
+	if !reflect.DeepEqual(got.Edges, want) || got.Metadata.EdgeCount != 3 || got.Metadata.NodeCount != 3 || got.SchemaVersion != 1 {
+		t.Errorf("CombinedGraph = %+v, want edges %v", got, want)
+	}
+	if len(graph.Edges) != 2 {
+		t.Error("CombinedGraph modified the input graph")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if got := splitList(" public, internal,,"); !reflect.DeepEqual(got, []string{"public", "internal"}) {
+		t.Errorf("splitList = %q", got)
+	}
+}
+
+func TestFindOrphans(t *testing.T) {
+	packs := samplePacks()
+	packs = append(packs,
+		Pack{ID: "D", Title: "Delta", Related: []string{"A"}},
+		Pack{ID: "E", Title: "Epsilon"},
+	)
+	index := PacksIndex{Packs: packs}
+	graph := Graph{Edges: []GraphEdge{{Source: "A", Target: "B", Type: "related"}}}
+
+	orphans := FindOrphans(index, graph)
+	if len(orphans) != 2 || orphans[0].ID != "C" || orphans[1].ID != "E" {
+		t.Errorf("orphans = %+v, want C and E", orphans)
+	}
+}
+
+func TestRelatedConsistency(t *testing.T) {
+	p := Pack{ID: "A", Related: []string{"B", "C"}}
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "D", Target: "A", Type: "child"},
+	}}
+
+	missing, extra := p.RelatedConsistency(g)
+	if !reflect.DeepEqual(missing, []string{"C"}) {
+		t.Errorf("missing = %v, want [C]", missing)
+	}
+	if !reflect.DeepEqual(extra, []string{"D"}) {
+		t.Errorf("extra = %v, want [D]", extra)
+	}
+}
+
+func TestSearchByTitle(t *testing.T) {
+	packs := samplePacks()
+
+	if got := SearchByTitle(packs, "ALP"); len(got) != 1 || got[0].ID != "A" {
+		t.Errorf("ALP: got %+v", got)
+	}
+	if got := SearchByTitle(packs, "a"); len(got) != 3 {
+		t.Errorf("a: got %d matches, want 3", len(got))
+	}
+	if got := SearchByTitle(packs, ""); len(got) != 0 {
+		t.Errorf("empty query: got %+v", got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	packs := []Pack{
+		{ID: "core/holodeck-seed", Title: "Holodeck Vision"},
+		{ID: "C0002", Title: "Seedling Notes"},
+		{ID: "C0003", Title: "The Seed, Planted"},
+	}
+	search := func(opts SearchOptions) []string {
+		return packIDs(Search(packs, opts))
+	}
+
+	if got := search(SearchOptions{Query: "seed"}); !slices.Equal(got, []string{"C0002", "C0003"}) {
+		t.Errorf("default = %v, want the titles containing seed", got)
+	}
+	if got := search(SearchOptions{Query: "seed", Fields: []string{SearchID}}); !slices.Equal(got, []string{"core/holodeck-seed"}) {
+		t.Errorf("id only = %v", got)
+	}
+	if got := search(SearchOptions{Query: "seed", Fields: []string{SearchID, SearchTitle}, WholeWord: true}); !slices.Equal(got, []string{"core/holodeck-seed", "C0003"}) {
+		t.Errorf("whole word over both = %v, want the slug and The Seed", got)
+	}
+	if got := search(SearchOptions{Query: "Seed", CaseSensitive: true}); !slices.Equal(got, []string{"C0002", "C0003"}) {
+		t.Errorf("case-sensitive Seed = %v", got)
+	}
+	if got := search(SearchOptions{Query: "seed", CaseSensitive: true}); len(got) != 0 {
+		t.Errorf("case-sensitive seed = %v, want none", got)
+	}
+	if got := search(SearchOptions{Query: "c000", WholeWord: true, Fields: []string{SearchID}}); len(got) != 0 {
+		t.Errorf("whole word inside an ID = %v, want none", got)
+	}
+}
+
+func TestTierCounts(t *testing.T) {
+	packs := append(samplePacks(), Pack{ID: "D"})
+
+	want := map[string]int{"public": 2, "internal": 1, TierNone: 1}
+	counts := TierCounts(packs)
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	if got := rankByScore(counts); !reflect.DeepEqual(got, []string{"public", TierNone, "internal"}) {
+		t.Errorf("sorted = %v", got)
+	}
+}
+
+func TestByIDKeepsFirst(t *testing.T) {
+	index := PacksIndex{Packs: append(samplePacks(), Pack{ID: "A", Title: "Alpha again"})}
+
+	byID := index.ByID()
+	if len(byID) != 3 || byID["A"].Title != "Alpha" {
+		t.Errorf("ByID = %+v", byID)
+	}
+	if p, ok := byID.Get("A"); !ok || p.Title != "Alpha" {
+		t.Errorf("Get(A) = %+v, %v", p, ok)
+	}
+	if _, ok := byID.Get("Z"); ok {
+		t.Error("Get(Z) found a pack")
+	}
+	if p, ok := index.Get("A"); !ok || p.Title != "Alpha" {
+		t.Errorf("index.Get(A) = %+v, %v", p, ok)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"coreB", "coreB", 0},
+		{"coerB", "coreB", 2},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosestIDs(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{{ID: "coreA"}, {ID: "coreB"}, {ID: "team/x"}}}
+
+	if got := ClosestIDs(index, "coerB", 2); !reflect.DeepEqual(got, []string{"coreB", "coreA"}) {
+		t.Errorf("ClosestIDs = %v", got)
+	}
+	if got := ClosestIDs(index, "coerB", 5); !reflect.DeepEqual(got, []string{"coreB", "coreA"}) {
+		t.Errorf("ClosestIDs(5) = %v, want team/x left out as too distant", got)
+	}
+	if got := ClosestIDs(index, "coerB", -1); len(got) != 0 {
+		t.Errorf("ClosestIDs(-1) = %v, want none", got)
+	}
+	if got := ClosestIDs(index, "zzzzzzzz", 3); len(got) != 0 {
+		t.Errorf("ClosestIDs(zzzzzzzz) = %v, want none", got)
+	}
+
+	_, err := requirePack(index.ByID(), "coerB")
+	if !errors.Is(err, ErrPackNotFound) || !strings.Contains(err.Error(), "did you mean: coreB") {
+		t.Errorf("requirePack err = %v", err)
+	}
+}
+
+func TestFilterByPrefixAndNamespaces(t *testing.T) {
+	packs := []Pack{{ID: "core/foo"}, {ID: "team-a/bar"}, {ID: "core/baz"}, {ID: "legacy"}, {ID: "core2/x"}}
+
+	if got := packIDs(FilterByPrefix(packs, "core/")); !reflect.DeepEqual(got, []string{"core/foo", "core/baz"}) {
+		t.Errorf("FilterByPrefix(core/) = %v", got)
+	}
+	if got := FilterByPrefix(packs, "none/"); got == nil || len(got) != 0 {
+		t.Errorf("no match = %#v, want an empty slice", got)
+	}
+	if got, want := Namespaces(packs), []string{"", "core", "core2", "team-a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Namespaces = %v, want %v", got, want)
+	}
+}
+
+func TestStalePacks(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	packs := []Pack{
+		{ID: "A", UpdatedAt: day(20)},
+		{ID: "B"},
+		{ID: "C", UpdatedAt: day(3)},
+		{ID: "D", UpdatedAt: day(10)},
+		{ID: "E", UpdatedAt: day(3)},
+	}
+
+	if got, want := packIDs(stalePacks(packs, day(15))), []string{"C", "E", "D"}; !slices.Equal(got, want) {
+		t.Errorf("stale before the 15th = %v, want %v", got, want)
+	}
+	if got := stalePacks(packs, day(3)); got == nil || len(got) != 0 {
+		t.Errorf("stale before the 3rd = %v, want empty", got)
+	}
+	if got, want := packIDs(UnknownAge(packs)), []string{"B"}; !slices.Equal(got, want) {
+		t.Errorf("UnknownAge = %v, want %v", got, want)
+	}
+
+	recent := []Pack{{ID: "new", UpdatedAt: time.Now().Add(-time.Hour)}, {ID: "old", UpdatedAt: day(1)}}
+	if got, want := packIDs(StalePacks(recent, 24*time.Hour)), []string{"old"}; !slices.Equal(got, want) {
+		t.Errorf("StalePacks(24h) = %v, want %v", got, want)
+	}
+}
+
+func TestSynthesizeIndex(t *testing.T) {
+	graph := cycleGraph()
+	graph.Edges = append(graph.Edges, GraphEdge{Source: "E", Target: "E", Type: "related"})
+
+	index := SynthesizeIndex(graph)
+	if got, want := packIDs(index.Packs), []string{"A", "B", "C", "D", "E"}; !slices.Equal(got, want) {
+		t.Fatalf("packs = %v, want %v", got, want)
+	}
+	if index.Metadata.PackCount != 5 {
+		t.Errorf("PackCount = %d, want 5", index.Metadata.PackCount)
+	}
+	for _, p := range index.Packs {
+		if !p.Synthetic || p.Title != "" || p.DisclosureTier != SyntheticTier || p.Related == nil || len(p.Related) != 0 {
+			t.Errorf("pack %+v is not a placeholder", p)
+		}
+	}
+	if errs := ValidateWith(index, graph, ValidateOptions{Checks: []string{"pack-count", "duplicate-ids", "dangling", "related"}}); len(errs) != 0 {
+		t.Errorf("synthesized index does not validate against its graph: %v", errs)
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSchema(data); err != nil {
+		t.Errorf("synthesized index fails the schema: %v", err)
+	}
+	var back PacksIndex
+	if err := json.Unmarshal(data, &back); err != nil || !back.Packs[0].Synthetic || back.Packs[0].Extra != nil {
+		t.Errorf("round trip = %+v, %v; want Synthetic kept as a modeled field", back.Packs[0], err)
+	}
+	if data, _ := json.Marshal(Pack{ID: "A"}); strings.Contains(string(data), "synthetic") {
+		t.Errorf("real pack encodes as %s, want no synthetic key", data)
+	}
+
+	if got := SynthesizeIndex(Graph{}); got.Packs == nil || len(got.Packs) != 0 || got.Metadata.PackCount != 0 {
+		t.Errorf("empty graph = %+v", got)
+	}
+}
+
+func TestIncompletePacks(t *testing.T) {
+	packs := []Pack{
+		{ID: "A", Title: "Alpha", DisclosureTier: "public"},
+		{ID: "B", Title: " ", DisclosureTier: "public"},
+		{Title: "No ID"},
+		{ID: "D", Title: "Delta", DisclosureTier: "internal"},
+	}
+
+	got := IncompletePacks(packs)
+	if len(got) != 2 || got[0].ID != "B" || got[1].Title != "No ID" {
+		t.Fatalf("IncompletePacks = %+v, want B and the pack with no ID", got)
+	}
+	if m := got[0].MissingFields(); !slices.Equal(m, []string{"title"}) {
+		t.Errorf("B missing %v, want [title]", m)
+	}
+	if m := got[1].MissingFields(); !slices.Equal(m, []string{"id", "disclosure_tier"}) {
+		t.Errorf("no-ID pack missing %v, want [id disclosure_tier]", m)
+	}
+	if got := IncompletePacks(packs[:1]); got == nil || len(got) != 0 {
+		t.Errorf("complete packs = %v, want empty", got)
+	}
+}
+
+func TestLabelID(t *testing.T) {
+	index := PacksIndex{Packs: append(samplePacks(), Pack{ID: "D", Title: " "})}
+
+	for id, want := range map[string]string{"A": "A (Alpha)", "D": "D", "X": "X"} {
+		if got := LabelID(index, id); got != want {
+			t.Errorf("LabelID(%s) = %q, want %q", id, got, want)
+		}
+	}
+	if got := idLabels(nil).label("A"); got != "A" {
+		t.Errorf("nil labels = %q, want the bare ID", got)
+	}
+}
@@ -0,0 +1,54 @@
+// ORIGIN Go Kit - progress reporting
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Progress receives updates from a long computation: done of total units
+// are finished. It is called on the computing goroutine and must not
+// affect the result.
+type Progress func(done, total int)
+
+// report calls p if it is set
+func (p Progress) report(done, total int) {
+	if p != nil {
+		p(done, total)
+	}
+}
+
+// progressInterval is how many units cheap per-unit loops finish between
+// reports
+const progressInterval = 4096
+
+// progressOutput enables progress lines on stderr. Like colorOutput it is
+// off by default; main turns it on for terminals unless -quiet is set.
+var progressOutput bool
+
+// newProgress returns a Progress that rewrites one "label: n%" line on w
+// each time the percentage changes and erases it when done, or nil when
+// progress output is disabled. w should be stderr so progress never mixes
+// with command output such as -json.
+func newProgress(w io.Writer, label string) Progress {
+	if !progressOutput {
+		return nil
+	}
+	last := -1
+	return func(done, total int) {
+		if total <= 0 {
+			return
+		}
+		if done >= total {
+			fmt.Fprint(w, "\r\x1b[K")
+			return
+		}
+		if pct := done * 100 / total; pct != last {
+			last = pct
+			fmt.Fprintf(w, "\r%s: %d%% (%d/%d)", label, pct, done, total)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+func TestNewProgress(t *testing.T) {
+	var buf bytes.Buffer
+	if p := newProgress(&buf, "work"); p != nil {
+		t.Fatal("progress enabled without progressOutput")
+	}
+
+	progressOutput = true
+	defer func() { progressOutput = false }()
+	p := newProgress(&buf, "work")
+	for done := range 5 {
+		p(done, 200)
+	}
+	p(200, 200)
+	want := "\rwork: 0% (0/200)\rwork: 1% (2/200)\rwork: 2% (4/200)\r\x1b[K"
+	if buf.String() != want {
+		t.Errorf("progress output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestProgressLeavesResultsUnchanged(t *testing.T) {
+	g := randomGraph(rand.New(rand.NewPCG(7, 0)), 40, 120)
+	var calls, last int
+	record := func(done, total int) {
+		calls++
+		if done < last || done > total {
+			t.Errorf("progress went from %d to %d of %d", last, done, total)
+		}
+		last = done
+	}
+
+	if got := g.PageRankWithProgress(0.85, 20, record); !reflect.DeepEqual(got, g.PageRank(0.85, 20)) {
+		t.Error("PageRankWithProgress changed the scores")
+	}
+	if calls != 20 || last != 20 {
+		t.Errorf("pagerank: %d calls ending at %d, want 20 ending at 20", calls, last)
+	}
+
+	calls, last = 0, 0
+	want := g.BetweennessCentrality()
+	for id, score := range g.BetweennessCentralityWithProgress(record) {
+		// Sources are visited in map order, so sums may differ in the last bits
+		if math.Abs(score-want[id]) > 1e-9 {
+			t.Errorf("betweenness of %s = %v with progress, %v without", id, score, want[id])
+		}
+	}
+	if n := len(g.BuildAdjacency()); calls != n || last != n {
+		t.Errorf("betweenness: %d calls ending at %d, want %d", calls, last, n)
+	}
+}
@@ -0,0 +1,78 @@
+// ORIGIN Go Kit - dist over HTTP
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// httpTimeout bounds each dist file fetch when the Loader has no client
+const httpTimeout = 30 * time.Second
+
+// isURL reports whether base is an http:// or https:// URL
+func isURL(base string) bool {
+	return strings.HasPrefix(base, "http://") || strings.HasPrefix(base, "https://")
+}
+
+// joinURL appends name to base with exactly one slash between them
+func joinURL(base, name string) string {
+	return strings.TrimSuffix(base, "/") + "/" + name
+}
+
+// httpFS reads dist files with GET requests relative to a base URL. A 404
+// is reported as fs.ErrNotExist so gzip fallback still applies.
+type httpFS struct {
+	base   string
+	client *http.Client
+}
+
+func (h httpFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	url := joinURL(h.base, name)
+	resp, err := h.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, &fs.PathError{Op: "open", Path: url, Err: fs.ErrNotExist}
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
+	}
+	return &memFile{Reader: bytes.NewReader(data), name: name, size: int64(len(data))}, nil
+}
+
+// memFile is a file held in memory
+type memFile struct {
+	*bytes.Reader
+	name string
+	size int64
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f, nil }
+func (f *memFile) Close() error               { return nil }
+
+func (f *memFile) Name() string       { return f.name }
+func (f *memFile) Size() int64        { return f.size }
+func (f *memFile) Mode() fs.FileMode  { return 0o444 }
+func (f *memFile) ModTime() time.Time { return time.Time{} }
+func (f *memFile) IsDir() bool        { return false }
+func (f *memFile) Sys() any           { return nil }
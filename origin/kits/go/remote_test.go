@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoaderFromURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dist/" + IndexFile:
+			w.Write([]byte(`{"packs":[{"id":"A","title":"Alpha"}]}`))
+		case "/dist/" + GraphFile + ".gz":
+			w.Write(gzipBytes(t, `{"edges":[{"source":"A","target":"B","type":"related"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	loader := NewLoader(srv.URL + "/dist/")
+	index, graph, err := LoadAll(loader)
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if len(index.Packs) != 1 || len(graph.Edges) != 1 {
+		t.Errorf("got %d packs and %d edges, want 1 and 1", len(index.Packs), len(graph.Edges))
+	}
+
+	_, err = NewLoader(srv.URL + "/missing").LoadIndex()
+	if !errors.Is(err, ErrDistNotFound) || !strings.Contains(err.Error(), srv.URL+"/missing/"+IndexFile) {
+		t.Errorf("missing file error = %v", err)
+	}
+}
+
+func TestLoaderURLStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	_, err := NewLoader(srv.URL).LoadIndex()
+	if err == nil || !strings.Contains(err.Error(), "403 Forbidden") {
+		t.Errorf("error = %v, want one naming the 403 status", err)
+	}
+}
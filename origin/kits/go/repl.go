@@ -0,0 +1,60 @@
+// ORIGIN Go Kit - interactive shell
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// replPrompt is printed before each line the REPL reads
+const replPrompt = "origin> "
+
+// runREPL loads the dataset once, then reads commands from in one line at
+// a time and runs them as subcommands until quit, exit or end of input.
+// Prompts, output and command errors all go to a.Out.
+func (a *app) runREPL(loader *Loader, in io.Reader) error {
+	out := a.Out
+	memo := memoize(loader)
+	if _, err := memo.LoadIndex(); err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+	// The graph is optional: commands that need it report its absence
+	if _, err := memo.LoadGraph(); err != nil {
+		fmt.Fprintf(out, "Warning: loading graph: %v\n", err)
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, replPrompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "quit", "exit":
+			return nil
+		case "repl", "serve":
+			fmt.Fprintf(out, "Error: %s is not available inside the REPL\n", fields[0])
+			continue
+		}
+		err := checkFilterCommand(fields[0])
+		if err == nil {
+			err = a.runCommand(memo, fields[0], fields[1:])
+		}
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(out, "Error: %v\n", err)
+		}
+	}
+}
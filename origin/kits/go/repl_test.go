@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestREPL(t *testing.T) {
+	fsys := &countingFS{FS: fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[]}`)},
+	}}
+
+	var out strings.Builder
+	in := strings.NewReader("bogus\n\n  \ntiers\nserve\nquit\ntiers\n")
+	a := &app{Out: &out, Err: &out}
+	if err := a.runREPL(&Loader{FS: fsys}, in); err != nil {
+		t.Fatalf("runREPL: %v", err)
+	}
+
+	got := out.String()
+	if !strings.Contains(got, `Error: unknown command "bogus"`) {
+		t.Errorf("unknown command not reported:\n%s", got)
+	}
+	if strings.Count(got, "Disclosure tiers (1 packs):") != 1 {
+		t.Errorf("tiers should run once, before quit:\n%s", got)
+	}
+	if !strings.Contains(got, "serve is not available") {
+		t.Errorf("serve not rejected:\n%s", got)
+	}
+	if n := strings.Count(got, replPrompt); n != 6 {
+		t.Errorf("printed %d prompts, want 6 (stopping at quit):\n%s", n, got)
+	}
+	// The index, the graph and one probe for AliasesFile
+	if n := fsys.opens.Load(); n != 3 {
+		t.Errorf("opened dist files %d times, want 3", n)
+	}
+}
+
+func TestREPLEndOfInput(t *testing.T) {
+	fsys := fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[]}`)},
+		GraphFile: {Data: []byte(`{"edges":[]}`)},
+	}
+	var out strings.Builder
+	a := &app{Out: &out, Err: &out}
+	if err := a.runREPL(&Loader{FS: fsys}, strings.NewReader("")); err != nil {
+		t.Fatalf("runREPL at EOF: %v", err)
+	}
+	if out.String() != replPrompt+"\n" {
+		t.Errorf("output = %q", out.String())
+	}
+}
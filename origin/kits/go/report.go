@@ -0,0 +1,115 @@
+// ORIGIN Go Kit - Markdown report
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// reportHubs is the number of hub packs listed in a report
+const reportHubs = 10
+
+// wikiEscaper keeps titles from breaking wiki links and table cells
+var wikiEscaper = strings.NewReplacer("|", `\|`, "[", `\[`, "]", `\]`)
+
+// wikiLink returns a [[id|title]] link, or [[id]] when title is empty
+func wikiLink(id, title string) string {
+	if title == "" {
+		return "[[" + id + "]]"
+	}
+	return "[[" + id + "|" + wikiEscaper.Replace(title) + "]]"
+}
+
+// WriteMarkdownReport writes a Markdown overview of the dataset: a tier
+// table, the top hub packs, and a section per pack linking its neighbors.
+// Packs and links are sorted by ID so regenerating the report yields
+// minimal diffs.
+func WriteMarkdownReport(w io.Writer, index PacksIndex, graph Graph) error {
+	bw := bufio.NewWriter(w)
+	byID := index.ByID()
+	tiers := TierCounts(index.Packs)
+
+	fmt.Fprintln(bw, "# ORIGIN Knowledge Graph")
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "%d packs, %d edges.\n", len(index.Packs), len(graph.Edges))
+
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "## Tiers")
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "| Tier | Packs |")
+	fmt.Fprintln(bw, "| --- | ---: |")
+	for _, tier := range rankByScore(tiers) {
+		fmt.Fprintf(bw, "| %s | %d |\n", wikiEscaper.Replace(tier), tiers[tier])
+	}
+
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "## Top hubs")
+	fmt.Fprintln(bw)
+	for _, h := range TopHubs(index, graph, reportHubs) {
+		fmt.Fprintf(bw, "- %s (%d edges)\n", wikiLink(h.ID, h.Title), h.Degree)
+	}
+
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	adj := graph.BuildAdjacency()
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "## Packs")
+	for _, id := range ids {
+		p := byID[id]
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "### %s: %s\n", p.ID, p.Title)
+		fmt.Fprintln(bw)
+		tier := p.DisclosureTier
+		if tier == "" {
+			tier = TierNone
+		}
+		fmt.Fprintf(bw, "Tier: %s\n", tier)
+
+		links := reportLinks(adj[id], id)
+		if len(links) == 0 {
+			continue
+		}
+		fmt.Fprintln(bw)
+		for _, l := range links {
+			fmt.Fprintf(bw, "- %s: %s\n", l.typ, wikiLink(l.id, byID[l.id].Title))
+		}
+	}
+
+	return bw.Flush()
+}
+
+// reportLink is one neighbor listed under a pack in the report
+type reportLink struct {
+	id, typ string
+}
+
+// reportLinks returns the distinct neighbors of id across edges, sorted
+// by neighbor ID and then edge type
+func reportLinks(edges []GraphEdge, id string) []reportLink {
+	seen := make(map[reportLink]bool)
+	var links []reportLink
+	for _, edge := range edges {
+		l := reportLink{id: otherEnd(edge, id), typ: edge.Type}
+		if !seen[l] {
+			seen[l] = true
+			links = append(links, l)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].id != links[j].id {
+			return links[i].id < links[j].id
+		}
+		return links[i].typ < links[j].typ
+	})
+	return links
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteMarkdownReport(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "C", Title: "Gamma", DisclosureTier: "public"},
+		{ID: "A", Title: "Alpha | One", DisclosureTier: "public"},
+		{ID: "B", Title: "Beta", DisclosureTier: "internal"},
+	}}
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "A", Target: "B", Type: "child"},
+		{Source: "C", Target: "A", Type: "related"},
+	}}
+
+	var first, second bytes.Buffer
+	if err := WriteMarkdownReport(&first, index, graph); err != nil {
+		t.Fatalf("WriteMarkdownReport: %v", err)
+	}
+	if err := WriteMarkdownReport(&second, index, graph); err != nil {
+		t.Fatalf("WriteMarkdownReport: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Error("report output is not deterministic")
+	}
+
+	out := first.String()
+	for _, want := range []string{
+		"| public | 2 |\n| internal | 1 |",
+		"- [[A|Alpha \\| One]] (3 edges)",
+		"### A: Alpha | One\n\nTier: public\n\n- child: [[B|Beta]]\n- related: [[C|Gamma]]\n",
+		"### C: Gamma\n\nTier: public\n\n- related: [[A|Alpha \\| One]]\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+	if a, b := strings.Index(out, "### A:"), strings.Index(out, "### B:"); a > b {
+		t.Error("pack sections not sorted by ID")
+	}
+}
@@ -0,0 +1,221 @@
+// ORIGIN Go Kit - lossless dist file round trips
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// metadataFields are the JSON keys of IndexMetadata
+var metadataFields = []string{"pack_count"}
+
+// unknownFields returns the members of the JSON object data whose keys are
+// not in known, or nil if there are none
+func unknownFields(data []byte, known []string) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, key := range known {
+		delete(fields, key)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// marshalJSON encodes v without escaping HTML characters, so text such as
+// "R&D" is written as it was read
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// withExtra encodes the struct v followed by the extra members, in key
+// order. Extra keys that v already encodes are skipped.
+func withExtra(v any, extra map[string]json.RawMessage, known []string) ([]byte, error) {
+	data, err := marshalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	first := len(data) == 2 // v encoded as {}
+	for _, key := range slices.Sorted(maps.Keys(extra)) {
+		if slices.Contains(known, key) {
+			continue
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		k, _ := marshalJSON(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(extra[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a pack, keeping unmodeled fields in Extra. An
+// empty or null updated_at leaves UpdatedAt zero.
+func (p *Pack) UnmarshalJSON(data []byte) error {
+	type plain Pack
+	aux := struct {
+		*plain
+		UpdatedAt string `json:"updated_at"`
+	}{plain: (*plain)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	p.UpdatedAt = time.Time{}
+	if aux.UpdatedAt != "" {
+		t, err := time.Parse(time.RFC3339, aux.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("updated_at: %w", err)
+		}
+		p.UpdatedAt = t
+	}
+	extra, err := unknownFields(data, packFields)
+	p.Extra = extra
+	return err
+}
+
+// MarshalJSON encodes a pack with its Extra fields after the modeled ones
+func (p Pack) MarshalJSON() ([]byte, error) {
+	type plain Pack
+	return withExtra(plain(p), p.Extra, packFields)
+}
+
+// UnmarshalJSON decodes index metadata, keeping unmodeled fields in Extra
+func (m *IndexMetadata) UnmarshalJSON(data []byte) error {
+	type plain IndexMetadata
+	if err := json.Unmarshal(data, (*plain)(m)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, metadataFields)
+	m.Extra = extra
+	return err
+}
+
+// MarshalJSON encodes index metadata with its Extra fields after
+// pack_count
+func (m IndexMetadata) MarshalJSON() ([]byte, error) {
+	type plain IndexMetadata
+	return withExtra(plain(m), m.Extra, metadataFields)
+}
+
+// packDetailJSON holds the members PackDetail adds to its pack
+type packDetailJSON struct {
+	Neighbors    []neighbor `json:"neighbors"`
+	RelatedPacks []Pack     `json:"related_packs,omitempty"`
+	Unresolved   int        `json:"unresolved_related,omitempty"`
+}
+
+// MarshalJSON encodes the pack's fields and the detail fields as one
+// object. Without it the embedded Pack's MarshalJSON would be promoted and
+// drop the detail fields.
+func (d PackDetail) MarshalJSON() ([]byte, error) {
+	pack, err := d.Pack.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	more, err := marshalJSON(packDetailJSON{d.Neighbors, d.RelatedPacks, d.Unresolved})
+	if err != nil {
+		return nil, err
+	}
+	return append(append(pack[:len(pack)-1], ','), more[1:]...), nil
+}
+
+// UnmarshalJSON decodes what MarshalJSON writes
+func (d *PackDetail) UnmarshalJSON(data []byte) error {
+	if err := d.Pack.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	var more packDetailJSON
+	if err := json.Unmarshal(data, &more); err != nil {
+		return err
+	}
+	d.Neighbors, d.RelatedPacks, d.Unresolved = more.Neighbors, more.RelatedPacks, more.Unresolved
+	for _, key := range []string{"neighbors", "related_packs", "unresolved_related"} {
+		delete(d.Extra, key)
+	}
+	if len(d.Extra) == 0 {
+		d.Extra = nil
+	}
+	return nil
+}
+
+// SaveIndex writes index to path in packs.index.json format: two-space
+// indentation and a trailing newline. Loading a file and saving it again
+// keeps every field, including ones the kit does not model. The output is
+// byte-identical to a file already in that form; otherwise it normalizes:
+//   - modeled fields come first in Pack order (id, title, disclosure_tier,
+//     related, tags), followed by the others sorted by key
+//   - metadata lists pack_count first, then the others sorted by key
+//   - a missing related list is written as null and empty tags are
+//     dropped
+//   - whitespace is re-indented and a final newline is added (the
+//     generated dist files end without one), and escapes in modeled strings, such as
+//     \u00e9, are written as the characters they stand for
+//   - top-level keys other than schema_version, metadata and packs are
+//     dropped
+func SaveIndex(path string, index PacksIndex) error {
+	return writeJSONFile(path, index.WriteJSON)
+}
+
+// rewriteGraphFile replaces the edges and metadata counts of the graph file
+// at path with graph's, keeping its other fields, such as nodes and
+// generated_at. Top-level and metadata keys are written sorted.
+func rewriteGraphFile(path string, graph Graph) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc, meta map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if raw, ok := doc["metadata"]; ok {
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return fmt.Errorf("%s: metadata: %w", path, err)
+		}
+	}
+	if doc == nil {
+		doc = make(map[string]json.RawMessage)
+	}
+	if meta == nil {
+		meta = make(map[string]json.RawMessage)
+	}
+
+	meta["node_count"], _ = marshalJSON(graph.Metadata.NodeCount)
+	meta["edge_count"], _ = marshalJSON(graph.Metadata.EdgeCount)
+	if doc["metadata"], err = marshalJSON(meta); err != nil {
+		return err
+	}
+	if doc["edges"], err = marshalJSON(graph.Edges); err != nil {
+		return err
+	}
+	return writeJSONFile(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(doc)
+	})
+}
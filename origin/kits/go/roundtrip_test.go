@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const goldenIndex = "testdata/packs.index.golden.json"
+
+// saveAndRead saves index to a temporary file and returns its contents
+func saveAndRead(t *testing.T, index PacksIndex) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), IndexFile)
+	if err := SaveIndex(path, index); err != nil {
+		t.Fatalf("SaveIndex: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSaveIndexGoldenRoundTrip(t *testing.T) {
+	want, err := os.ReadFile(goldenIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := LoadIndexAuto(goldenIndex)
+	if err != nil {
+		t.Fatalf("loading golden file: %v", err)
+	}
+	if got := saveAndRead(t, index); !bytes.Equal(got, want) {
+		t.Errorf("load and save changed the golden file:\n%s", got)
+	}
+}
+
+func TestSaveIndexNormalizes(t *testing.T) {
+	want, err := os.ReadFile(goldenIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The golden dataset with keys reordered and whitespace removed
+	const shuffled = `{"packs":[{"summary":"Line one.\nLine two.\n","tags":["vision","ui"],"scores":{"depth":0.5,"nested":{"ok":true}},` +
+		`"related":["C0002"],"id":"C0001","claims":["Caf\u00e9 escapes in unmodeled fields are kept as written"],"children":[],` +
+		`"disclosure_tier":"public","title":"R&D <Seed> for Whānau"},` +
+		`{"status":null,"related":[],"disclosure_tier":"internal","title":"Second","id":"C0002"}],` +
+		`"metadata":{"version":"1.0.0","generated_at":"2026-02-17T23:19:30.692Z","pack_count":2,"attribution":"Ande + Kai (OI) + Whānau (OIs)"}}`
+	path := filepath.Join(t.TempDir(), "shuffled.json")
+	writeFile(t, filepath.Dir(path), filepath.Base(path), shuffled)
+
+	index, err := LoadIndexAuto(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saveAndRead(t, index); !bytes.Equal(got, want) {
+		t.Errorf("normalized output differs from the golden file:\n%s", got)
+	}
+}
+
+func TestPackUpdatedAt(t *testing.T) {
+	var p Pack
+	if err := json.Unmarshal([]byte(`{"id":"A","updated_at":"2026-03-01T12:00:00+13:00"}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC); !p.UpdatedAt.Equal(want) {
+		t.Errorf("UpdatedAt = %v, want %v", p.UpdatedAt, want)
+	}
+	if p.Extra != nil {
+		t.Errorf("updated_at kept in Extra: %v", p.Extra)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":"A","title":"","disclosure_tier":"","related":null,"updated_at":"2026-03-01T12:00:00+13:00"}`; string(data) != want {
+		t.Errorf("marshaled %s, want %s", data, want)
+	}
+
+	for _, doc := range []string{`{"id":"A"}`, `{"id":"A","updated_at":""}`, `{"id":"A","updated_at":null}`} {
+		p := Pack{UpdatedAt: time.Now()}
+		if err := json.Unmarshal([]byte(doc), &p); err != nil {
+			t.Errorf("%s: %v", doc, err)
+		} else if !p.UpdatedAt.IsZero() {
+			t.Errorf("%s: UpdatedAt = %v, want zero", doc, p.UpdatedAt)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"id":"A","updated_at":"last week"}`), &p); err == nil {
+		t.Error("malformed updated_at: got no error")
+	}
+}
@@ -0,0 +1,215 @@
+// ORIGIN Go Kit - dist file structure checks
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// ErrMalformedDist is returned by ValidateSchema for a dist file whose
+// structure does not match packs.index.json or graph.json. It matches
+// ErrInvalidSchema.
+var ErrMalformedDist = invalidSchema(errors.New("malformed dist file"))
+
+// schemaField describes one member of a JSON object: its key, the JSON
+// kind it must have, and for objects and arrays of objects, their members
+type schemaField struct {
+	key         string
+	kind        string // "object", "array", "string", "number", "integer" or "boolean"
+	elem        string // kind of array elements or of object values
+	required    bool
+	nonNegative bool // for numbers: the value must not be below zero
+	fields      []schemaField
+}
+
+var packSchema = []schemaField{
+	{key: "id", kind: "string", required: true},
+	{key: "title", kind: "string"},
+	{key: "disclosure_tier", kind: "string"},
+	{key: "related", kind: "array", elem: "string"},
+	{key: "tags", kind: "array", elem: "string"},
+	{key: "updated_at", kind: "string"},
+	{key: "synthetic", kind: "boolean"},
+}
+
+var edgeSchema = []schemaField{
+	{key: "source", kind: "string", required: true},
+	{key: "target", kind: "string", required: true},
+	{key: "type", kind: "string"},
+	{key: "weight", kind: "number", nonNegative: true},
+	{key: "attributes", kind: "object", elem: "string"},
+}
+
+var indexSchema = []schemaField{
+	{key: "schema_version", kind: "integer"},
+	{key: "metadata", kind: "object", required: true, fields: []schemaField{
+		{key: "pack_count", kind: "integer"},
+	}},
+	{key: "packs", kind: "array", elem: "object", required: true, fields: packSchema},
+}
+
+var graphSchema = []schemaField{
+	{key: "schema_version", kind: "integer"},
+	{key: "metadata", kind: "object", required: true, fields: []schemaField{
+		{key: "node_count", kind: "integer"},
+		{key: "edge_count", kind: "integer"},
+	}},
+	{key: "edges", kind: "array", elem: "object", required: true, fields: edgeSchema},
+}
+
+// ValidateSchema checks that raw is a packs index or a graph file, told
+// apart by a top-level packs or edges key, with the required keys present
+// and every modeled field of the right JSON type. The error wraps
+// ErrMalformedDist and names the first problem by position, such as
+// "packs[3].id: got number, want string", or the line and column of a
+// syntax error. Unknown keys are allowed, and optional fields may be null.
+// A schema_version newer than the kit supports is reported as
+// ErrUnsupportedSchema instead, since its structure may differ.
+func ValidateSchema(raw []byte) error {
+	if err := json.Unmarshal(raw, new(json.RawMessage)); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			// Offset counts the offending byte
+			line, col := lineCol(raw, max(syntax.Offset-1, 0))
+			return fmt.Errorf("%w: invalid JSON at line %d, column %d: %v", ErrMalformedDist, line, col, err)
+		}
+		return fmt.Errorf("%w: %v", ErrMalformedDist, err)
+	}
+	if kind := jsonKind(raw); kind != "object" {
+		return fmt.Errorf("%w: top level: got %s, want object", ErrMalformedDist, kind)
+	}
+	var doc map[string]json.RawMessage
+	json.Unmarshal(raw, &doc)
+
+	// A newer schema may change the structure, so report the version
+	// rather than the first difference
+	var version int
+	if json.Unmarshal(doc["schema_version"], &version) == nil {
+		if err := CheckSchemaVersion(version); err != nil {
+			return err
+		}
+	}
+
+	var schema []schemaField
+	switch {
+	case doc["packs"] != nil:
+		schema = indexSchema
+	case doc["edges"] != nil:
+		schema = graphSchema
+	default:
+		return fmt.Errorf(`%w: missing "packs" (index) or "edges" (graph) key`, ErrMalformedDist)
+	}
+	if err := checkFields("", doc, schema); err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedDist, err)
+	}
+	return nil
+}
+
+// checkFields checks the members of obj, found at path, against fields
+func checkFields(path string, obj map[string]json.RawMessage, fields []schemaField) error {
+	for _, f := range fields {
+		at := f.key
+		if path != "" {
+			at = path + "." + f.key
+		}
+		raw, ok := obj[f.key]
+		if !ok {
+			if f.required {
+				return fmt.Errorf("%s: missing required key", at)
+			}
+			continue
+		}
+		if err := checkValue(at, raw, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkValue checks raw, found at path, against f
+func checkValue(path string, raw json.RawMessage, f schemaField) error {
+	kind := jsonKind(raw)
+	switch {
+	case kind == "null" && !f.required:
+		return nil
+	case kind == "number" && f.kind == "integer":
+		if bytes.ContainsAny(raw, ".eE") {
+			return fmt.Errorf("%s: got %s, want integer", path, raw)
+		}
+		return nil
+	case kind != f.kind:
+		return fmt.Errorf("%s: got %s, want %s", path, kind, f.kind)
+	}
+
+	switch kind {
+	case "number":
+		if f.nonNegative {
+			if v, err := strconv.ParseFloat(string(raw), 64); err == nil && v < 0 {
+				return fmt.Errorf("%s: got %s, want a non-negative number", path, raw)
+			}
+		}
+	case "object":
+		var obj map[string]json.RawMessage
+		json.Unmarshal(raw, &obj)
+		if f.elem != "" {
+			// A map: check every value, in key order so errors are stable
+			value := schemaField{kind: f.elem, required: true}
+			for _, k := range slices.Sorted(maps.Keys(obj)) {
+				if err := checkValue(path+"."+k, obj[k], value); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return checkFields(path, obj, f.fields)
+	case "array":
+		var elems []json.RawMessage
+		json.Unmarshal(raw, &elems)
+		elem := schemaField{kind: f.elem, required: true, fields: f.fields}
+		for i, e := range elems {
+			if err := checkValue(fmt.Sprintf("%s[%d]", path, i), e, elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonKind returns the kind of the valid JSON value raw: object, array,
+// string, number, boolean or null
+func jsonKind(raw []byte) string {
+	raw = bytes.TrimLeft(raw, " \t\r\n")
+	if len(raw) == 0 {
+		return "nothing"
+	}
+	switch raw[0] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+// lineCol converts a byte offset in data to a one-based line and column
+func lineCol(data []byte, offset int64) (int, int) {
+	offset = min(offset, int64(len(data)))
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
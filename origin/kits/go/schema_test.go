@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string // substring of the error; empty for valid input
+	}{
+		{"index", `{"schema_version":1,"metadata":{"pack_count":1},"packs":[{"id":"A","related":null,"tags":["x"],"extra":1}]}`, ""},
+		{"graph", `{"metadata":{"node_count":2},"edges":[{"source":"A","target":"B","type":"child","weight":1.5}],"nodes":[]}`, ""},
+		{"syntax", "{\n  \"metadata\": {},\n  \"packs\": [,]\n}", "line 3, column 13"},
+		{"truncated", `{"metadata":{}`, "invalid JSON"},
+		{"array", `[]`, "top level: got array, want object"},
+		{"neither", `{"metadata":{}}`, `missing "packs" (index) or "edges" (graph) key`},
+		{"no metadata", `{"packs":[]}`, "metadata: missing required key"},
+		{"metadata type", `{"metadata":[],"packs":[]}`, "metadata: got array, want object"},
+		{"fractional count", `{"metadata":{"pack_count":1.5},"packs":[]}`, "metadata.pack_count: got 1.5, want integer"},
+		{"null packs", `{"metadata":{},"packs":null}`, "packs: got null, want array"},
+		{"pack type", `{"metadata":{},"packs":["A"]}`, "packs[0]: got string, want object"},
+		{"missing id", `{"metadata":{},"packs":[{"id":"A"},{"title":"B"}]}`, "packs[1].id: missing required key"},
+		{"id type", `{"metadata":{},"packs":[{"id":7}]}`, "packs[0].id: got number, want string"},
+		{"related element", `{"metadata":{},"packs":[{"id":"A","related":["B",2]}]}`, "packs[0].related[1]: got number, want string"},
+		{"edge target", `{"metadata":{},"edges":[{"source":"A","target":null}]}`, "edges[0].target: got null, want string"},
+		{"newer schema", `{"schema_version":999,"packs":"changed"}`, "unsupported schema version 999"},
+		{"weight type", `{"metadata":{},"edges":[{"source":"A","target":"B","weight":"1"}]}`, "edges[0].weight: got string, want number"},
+		{"zero weight", `{"metadata":{},"edges":[{"source":"A","target":"B","weight":0}]}`, ""},
+		{"negative weight", `{"metadata":{},"edges":[{"source":"A","target":"B","weight":-2}]}`, "edges[0].weight: got -2, want a non-negative number"},
+		{"attributes", `{"metadata":{},"edges":[{"source":"A","target":"B","attributes":{"confidence":"high"}}]}`, ""},
+		{"attribute value", `{"metadata":{},"edges":[{"source":"A","target":"B","attributes":{"b":"x","a":0.9}}]}`, "edges[0].attributes.a: got number, want string"},
+	}
+	for _, tt := range tests {
+		err := ValidateSchema([]byte(tt.raw))
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want one mentioning %q", tt.name, err, tt.want)
+		} else if !errors.Is(err, ErrMalformedDist) && !errors.Is(err, ErrUnsupportedSchema) {
+			t.Errorf("%s: err = %v, want ErrMalformedDist", tt.name, err)
+		}
+	}
+}
+
+func TestLoaderValidatesSchemaWhenStrict(t *testing.T) {
+	fsys := fstest.MapFS{IndexFile: {Data: []byte(`{"metadata":{},"packs":[{"id":"A","related":"B"}]}`)}}
+
+	if _, err := (&Loader{FS: fsys}).LoadIndex(); err == nil || errors.Is(err, ErrMalformedDist) {
+		t.Errorf("lenient load: err = %v, want a plain decode error", err)
+	}
+	_, err := (&Loader{FS: fsys, StrictSchema: true}).LoadIndex()
+	if !errors.Is(err, ErrMalformedDist) || !strings.Contains(err.Error(), "packs[0].related: got string, want array") {
+		t.Errorf("strict load: err = %v, want the schema problem", err)
+	}
+}
@@ -0,0 +1,316 @@
+// ORIGIN Go Kit - HTTP server
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Server answers pack and graph queries over HTTP from a Cache
+type Server struct {
+	Cache *Cache
+	// EditToken enables POST /edges for requests bearing it; empty
+	// disables edits
+	EditToken string
+}
+
+// NewServer returns a server reading through c
+func NewServer(c *Cache) *Server {
+	return &Server{Cache: c}
+}
+
+// Handler returns the routes served by s
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /packs", s.handlePacks)
+	mux.HandleFunc("GET /packs/{id}", s.handlePack)
+	mux.HandleFunc("GET /packs/{id}/neighbors", s.handleNeighbors)
+	mux.HandleFunc("GET /path", s.handlePath)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("POST /edges", s.handleEdges)
+	return mux
+}
+
+// errorBody is the JSON body of every error response
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// writeJSON writes v as the JSON response body with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError writes a JSON error body, using 404 for unknown packs and
+// missing paths, 503 for a dist that is missing or does not decode, and
+// 500 otherwise
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrPackNotFound), errors.Is(err, ErrNoPath):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrDistNotFound), errors.Is(err, ErrInvalidSchema):
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, errorBody{Error: err.Error()})
+}
+
+// wantNDJSON reports whether the request's format query parameter asks
+// for NDJSON. It answers 400 for an unknown format and returns ok false.
+func wantNDJSON(w http.ResponseWriter, r *http.Request) (ndjson, ok bool) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		return false, true
+	case "ndjson":
+		return true, true
+	default:
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: fmt.Sprintf("unknown format %q (want json or ndjson)", format)})
+		return false, false
+	}
+}
+
+// writeNDJSON writes items as NDJSON, one JSON object per line, flushing
+// every ndjsonFlushLines lines, and stops early if the client goes away.
+// The items are already in memory; flushing only lets the client start
+// reading before the whole body is written. The 200 status is sent before
+// the first item, so an error partway through is signaled by a final
+// {"error": "..."} line instead.
+func writeNDJSON[T any](w http.ResponseWriter, r *http.Request, items iter.Seq[T]) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	ctx := r.Context()
+	live := func(yield func(T) bool) {
+		for item := range items {
+			if ctx.Err() != nil || !yield(item) {
+				return
+			}
+		}
+	}
+	encodeNDJSON(w, live, func() { rc.Flush() })
+}
+
+// load returns the cached index and graph
+func (s *Server) load() (PacksIndex, Graph, error) {
+	index, err := s.Cache.Index()
+	if err != nil {
+		return PacksIndex{}, Graph{}, err
+	}
+	graph, err := s.Cache.Graph()
+	if err != nil {
+		return PacksIndex{}, Graph{}, err
+	}
+	return index, graph, nil
+}
+
+func (s *Server) handlePacks(w http.ResponseWriter, r *http.Request) {
+	ndjson, ok := wantNDJSON(w, r)
+	if !ok {
+		return
+	}
+	index, err := s.Cache.Index()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if ndjson {
+		writeNDJSON(w, r, slices.Values(index.Packs))
+		return
+	}
+	packs := index.Packs
+	if packs == nil {
+		packs = []Pack{}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Packs []Pack `json:"packs"`
+	}{packs})
+}
+
+func (s *Server) handlePack(w http.ResponseWriter, r *http.Request) {
+	detail, err := s.Cache.PackDetail(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (s *Server) handleNeighbors(w http.ResponseWriter, r *http.Request) {
+	ndjson, ok := wantNDJSON(w, r)
+	if !ok {
+		return
+	}
+	index, graph, err := s.load()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	p, err := requirePack(index.ByID(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result := neighborsOf(index, graph.BuildAdjacency(), p, math.MaxInt)
+	if ndjson {
+		writeNDJSON(w, r, slices.Values(result.Neighbors))
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handlePath(w http.ResponseWriter, r *http.Request) {
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" || to == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: "query parameters from and to are required"})
+		return
+	}
+
+	index, graph, err := s.load()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	byID := index.ByID()
+	for _, id := range []string{from, to} {
+		if _, err := requirePack(byID, id); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	path, err := graph.ShortestPath(from, to)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newPathResult(graph.BuildAdjacency(), path))
+}
+
+// labelEscaper escapes a Prometheus label value
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes dataset gauges in the Prometheus text exposition
+// format
+func WriteMetrics(w io.Writer, index PacksIndex, graph Graph) error {
+	bw := bufio.NewWriter(w)
+	gauge := func(name, help string, value int) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, value)
+	}
+	gauge("origin_pack_count", "Number of packs in the index.", len(index.Packs))
+	gauge("origin_edge_count", "Number of edges in the graph.", len(graph.Edges))
+	gauge("origin_orphan_count", "Number of packs with no edges and no related packs.", len(FindOrphans(index, graph)))
+
+	tiers := TierCounts(index.Packs)
+	names := make([]string, 0, len(tiers))
+	for tier := range tiers {
+		names = append(names, tier)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(bw, "# HELP origin_packs_by_tier Number of packs in each disclosure tier.")
+	fmt.Fprintln(bw, "# TYPE origin_packs_by_tier gauge")
+	for _, tier := range names {
+		fmt.Fprintf(bw, "origin_packs_by_tier{tier=\"%s\"} %d\n", labelEscaper.Replace(tier), tiers[tier])
+	}
+	return bw.Flush()
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	index, graph, err := s.load()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	WriteMetrics(w, index, graph)
+}
+
+// maxEditBody bounds the size of a POST /edges request body
+const maxEditBody = 1 << 20
+
+// edgeEdit is the body of POST /edges
+type edgeEdit struct {
+	Add    []GraphEdge `json:"add"`
+	Remove []GraphEdge `json:"remove"`
+}
+
+// edgeEditResult reports what POST /edges changed. Duplicates were already
+// present and Missing were not found; neither is an error.
+type edgeEditResult struct {
+	Added      int         `json:"added"`
+	Removed    int         `json:"removed"`
+	Duplicates []GraphEdge `json:"duplicates"`
+	Missing    []GraphEdge `json:"missing"`
+	EdgeCount  int         `json:"edge_count"`
+}
+
+// handleEdges applies edge removals and then additions to the cached
+// graph. Edits live in memory only and are lost when the cache reloads.
+func (s *Server) handleEdges(w http.ResponseWriter, r *http.Request) {
+	if s.EditToken == "" {
+		writeJSON(w, http.StatusForbidden, errorBody{Error: "edge edits are disabled"})
+		return
+	}
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.EditToken)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, errorBody{Error: "missing or wrong edit token"})
+		return
+	}
+
+	var edit edgeEdit
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxEditBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&edit); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: fmt.Sprintf("decoding edits: %v", err)})
+		return
+	}
+	for _, e := range edit.Add {
+		if e.Source == "" || e.Target == "" {
+			writeJSON(w, http.StatusBadRequest, errorBody{Error: "every added edge needs a source and target"})
+			return
+		}
+	}
+
+	result := edgeEditResult{Duplicates: []GraphEdge{}, Missing: []GraphEdge{}}
+	graph, err := s.Cache.UpdateGraph(func(g *Graph) error {
+		for _, e := range edit.Remove {
+			if g.RemoveEdge(e) {
+				result.Removed++
+			} else {
+				result.Missing = append(result.Missing, e)
+			}
+		}
+		for _, e := range edit.Add {
+			if err := g.AddEdge(e); errors.Is(err, ErrDuplicateEdge) {
+				result.Duplicates = append(result.Duplicates, e)
+			} else if err != nil {
+				return err
+			} else {
+				result.Added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result.EdgeCount = len(graph.Edges)
+	writeJSON(w, http.StatusOK, result)
+}
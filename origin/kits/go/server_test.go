@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// testServer serves a small dist of A-B-C plus an unlinked D
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	fsys := fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha"},{"id":"B","title":"Beta"},{"id":"C","title":"Gamma"},{"id":"D","title":"Delta"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"related"},{"source":"C","target":"B","type":"child"}]}`)},
+	}
+	srv := httptest.NewServer(NewServer(NewCache(&Loader{FS: fsys})).Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// getJSON fetches url, checks the status and decodes the body into v
+func getJSON(t *testing.T, url string, wantStatus int, v any) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("GET %s: status %d, want %d", url, resp.StatusCode, wantStatus)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("GET %s: Content-Type %q", url, ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: decoding: %v", url, err)
+	}
+}
+
+func TestServerRoutes(t *testing.T) {
+	srv := testServer(t)
+
+	var packs struct{ Packs []Pack }
+	getJSON(t, srv.URL+"/packs", http.StatusOK, &packs)
+	if len(packs.Packs) != 4 {
+		t.Errorf("/packs returned %d packs, want 4", len(packs.Packs))
+	}
+
+	var p PackDetail
+	getJSON(t, srv.URL+"/packs/B", http.StatusOK, &p)
+	if p.Title != "Beta" || len(p.Neighbors) != 2 || p.Neighbors[1].Title != "Gamma" {
+		t.Errorf("/packs/B = %+v", p)
+	}
+
+	var n neighborsResult
+	getJSON(t, srv.URL+"/packs/B/neighbors", http.StatusOK, &n)
+	if n.Total != 2 || len(n.Neighbors) != 2 || n.Neighbors[0].Direction != "in" {
+		t.Errorf("/packs/B/neighbors = %+v", n)
+	}
+
+	var path pathResult
+	getJSON(t, srv.URL+"/path?from=A&to=C", http.StatusOK, &path)
+	if len(path.Path) != 3 || path.Path[2].ID != "C" || path.Path[2].Type != "child" {
+		t.Errorf("/path = %+v", path)
+	}
+}
+
+func TestServerNDJSON(t *testing.T) {
+	srv := testServer(t)
+
+	get := func(url string) (*http.Response, []string) {
+		t.Helper()
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	}
+
+	resp, lines := get(srv.URL + "/packs?format=ndjson")
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type %q", ct)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("/packs?format=ndjson: %d lines, want 4: %q", len(lines), lines)
+	}
+	var p Pack
+	if err := json.Unmarshal([]byte(lines[3]), &p); err != nil || p.ID != "D" {
+		t.Errorf("last line = %q (%v), want pack D", lines[3], err)
+	}
+
+	if _, lines := get(srv.URL + "/packs/B/neighbors?format=ndjson"); len(lines) != 2 || !strings.Contains(lines[1], `"id":"C"`) {
+		t.Errorf("neighbors NDJSON = %q, want A and C", lines)
+	}
+	if resp, _ := get(srv.URL + "/packs?format=xml"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown format: status %d, want 400", resp.StatusCode)
+	}
+	if resp, _ := get(srv.URL + "/packs/Z/neighbors?format=ndjson"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown pack: status %d, want 404 before streaming", resp.StatusCode)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	srv := testServer(t)
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"/packs/Z", http.StatusNotFound},
+		{"/packs/Z/neighbors", http.StatusNotFound},
+		{"/path?from=A", http.StatusBadRequest},
+		{"/path?from=A&to=Z", http.StatusNotFound},
+		{"/path?from=A&to=D", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		var body errorBody
+		getJSON(t, srv.URL+tt.url, tt.status, &body)
+		if body.Error == "" {
+			t.Errorf("%s: empty error body", tt.url)
+		}
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"missing dist":   {},
+		"invalid schema": {IndexFile: {Data: []byte(`{"packs":{}}`)}, GraphFile: {Data: []byte(`{"edges":[]}`)}},
+	} {
+		srv := httptest.NewServer(NewServer(NewCache(&Loader{FS: fsys})).Handler())
+		var body errorBody
+		getJSON(t, srv.URL+"/packs", http.StatusServiceUnavailable, &body)
+		srv.Close()
+		if body.Error == "" {
+			t.Errorf("%s: empty error body", name)
+		}
+	}
+}
+
+func TestServerIndexOnly(t *testing.T) {
+	fsys := fstest.MapFS{IndexFile: {Data: []byte(`{"packs":[{"id":"A","title":"Alpha"}]}`)}}
+	srv := httptest.NewServer(NewServer(NewCache(&Loader{FS: fsys})).Handler())
+	defer srv.Close()
+
+	var detail PackDetail
+	getJSON(t, srv.URL+"/packs/A", http.StatusOK, &detail)
+	if detail.ID != "A" || detail.Title != "Alpha" || detail.Neighbors == nil || len(detail.Neighbors) != 0 {
+		t.Errorf("GET /packs/A = %+v, want the pack with empty neighbors", detail)
+	}
+	var body errorBody
+	getJSON(t, srv.URL+"/packs/Z", http.StatusNotFound, &body)
+}
+
+func TestWriteMetrics(t *testing.T) {
+	index := PacksIndex{Packs: append(samplePacks(), Pack{ID: "D", DisclosureTier: `odd"tier`})}
+	graph := Graph{Edges: []GraphEdge{{Source: "A", Target: "B", Type: "related"}}}
+
+	var buf bytes.Buffer
+	if err := WriteMetrics(&buf, index, graph); err != nil {
+		t.Fatalf("WriteMetrics: %v", err)
+	}
+	want := `# HELP origin_pack_count Number of packs in the index.
+# TYPE origin_pack_count gauge
+origin_pack_count 4
+# HELP origin_edge_count Number of edges in the graph.
+# TYPE origin_edge_count gauge
+origin_edge_count 1
+# HELP origin_orphan_count Number of packs with no edges and no related packs.
+# TYPE origin_orphan_count gauge
+origin_orphan_count 2
+# HELP origin_packs_by_tier Number of packs in each disclosure tier.
+# TYPE origin_packs_by_tier gauge
+origin_packs_by_tier{tier="internal"} 1
+origin_packs_by_tier{tier="odd\"tier"} 1
+origin_packs_by_tier{tier="public"} 2
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestServerMetrics(t *testing.T) {
+	resp, err := http.Get(testServer(t).URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "origin_pack_count 4\n") || !strings.Contains(string(body), "origin_orphan_count 1\n") {
+		t.Errorf("unexpected metrics:\n%s", body)
+	}
+}
+
+func TestServerEditEdges(t *testing.T) {
+	fsys := fstest.MapFS{
+		IndexFile: {Data: []byte(`{"packs":[{"id":"A"},{"id":"B"},{"id":"C"},{"id":"D"}]}`)},
+		GraphFile: {Data: []byte(`{"edges":[{"source":"A","target":"B","type":"related"},{"source":"C","target":"B","type":"child"}]}`)},
+	}
+	server := NewServer(NewCache(&Loader{FS: fsys}))
+	srv := httptest.NewServer(server.Handler())
+	defer srv.Close()
+
+	post := func(token, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest("POST", srv.URL+"/edges", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := post("", `{}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("edits without a server token: status %d, want 403", resp.StatusCode)
+	}
+	server.EditToken = "s3cret"
+	if resp := post("wrong", `{}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong token: status %d, want 401", resp.StatusCode)
+	}
+	if resp := post("s3cret", `{"add":[{"source":"A"}]}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("edge without target: status %d, want 400", resp.StatusCode)
+	}
+
+	resp := post("s3cret", `{
+		"add": [{"source":"A","target":"D","type":"related"},{"source":"A","target":"B","type":"related"}],
+		"remove": [{"source":"C","target":"B","type":"child"},{"source":"X","target":"Y","type":"related"}]
+	}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("edit: status %d, want 200", resp.StatusCode)
+	}
+	var result edgeEditResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 1 || result.Removed != 1 || len(result.Duplicates) != 1 || len(result.Missing) != 1 || result.EdgeCount != 2 {
+		t.Errorf("edit result = %+v", result)
+	}
+
+	// The edits are live for later requests
+	var path pathResult
+	getJSON(t, srv.URL+"/path?from=B&to=D", http.StatusOK, &path)
+	if len(path.Path) != 3 {
+		t.Errorf("path after adding A-D = %+v, want B, A, D", path.Path)
+	}
+	getJSON(t, srv.URL+"/path?from=A&to=C", http.StatusNotFound, &errorBody{})
+}
@@ -0,0 +1,52 @@
+// ORIGIN Go Kit - dataset summaries
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+// summaryHubs is the number of hub packs included in a Summary
+const summaryHubs = 3
+
+// HubPack is a pack ranked by its number of incident edges
+type HubPack struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Degree int    `json:"degree"`
+}
+
+// Summary is a one-shot overview of a dataset
+type Summary struct {
+	PackCount  int            `json:"pack_count"`
+	EdgeCount  int            `json:"edge_count"`
+	Tiers      map[string]int `json:"tiers"`
+	Components int            `json:"components"`
+	Orphans    int            `json:"orphans"`
+	Hubs       []HubPack      `json:"hubs"`
+}
+
+// TopHubs returns the n packs with the highest degree, ties broken by ID
+func TopHubs(index PacksIndex, graph Graph, n int) []HubPack {
+	byID := index.ByID()
+	degree := graph.DegreeCentrality()
+	hubs := []HubPack{}
+	for i, id := range rankByScore(degree) {
+		if i >= n {
+			break
+		}
+		hubs = append(hubs, HubPack{ID: id, Title: byID[id].Title, Degree: degree[id]})
+	}
+	return hubs
+}
+
+// Summarize computes pack and edge totals, the tier breakdown, component
+// and orphan counts, and the top hub packs
+func Summarize(index PacksIndex, graph Graph) Summary {
+	return Summary{
+		PackCount:  len(index.Packs),
+		EdgeCount:  len(graph.Edges),
+		Tiers:      TierCounts(index.Packs),
+		Components: len(graph.ConnectedComponents()),
+		Orphans:    len(FindOrphans(index, graph)),
+		Hubs:       TopHubs(index, graph, summaryHubs),
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", Title: "Alpha", DisclosureTier: "public"},
+		{ID: "B", Title: "Beta", DisclosureTier: "public"},
+		{ID: "C", Title: "Gamma", DisclosureTier: "internal"},
+		{ID: "D", Title: "Delta", DisclosureTier: "public"},
+		{ID: "E", Title: "Epsilon"},
+	}}
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "D", Target: "X", Type: "related"},
+	}}
+
+	got := Summarize(index, graph)
+	want := Summary{
+		PackCount:  5,
+		EdgeCount:  3,
+		Tiers:      map[string]int{"public": 3, "internal": 1, TierNone: 1},
+		Components: 2,
+		Orphans:    1,
+		Hubs: []HubPack{
+			{ID: "A", Title: "Alpha", Degree: 2},
+			{ID: "B", Title: "Beta", Degree: 1},
+			{ID: "C", Title: "Gamma", Degree: 1},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Summarize =\n%+v\nwant\n%+v", got, want)
+	}
+}
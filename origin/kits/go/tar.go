@@ -0,0 +1,89 @@
+// ORIGIN Go Kit - dist tar archives
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// LoadFromTar loads the index and graph from the tar archive at path,
+// which may be gzipped (.tar.gz), without extracting it to disk. Entries
+// are matched by base name, so the dist files may sit in a directory
+// inside the archive; the first entry of each name is used, and gzipped
+// entries such as packs.index.json.gz are read too. A missing archive, or
+// one missing either file, fails with an error that wraps
+// ErrDistNotFound.
+func LoadFromTar(path string) (PacksIndex, Graph, error) {
+	files, err := readTarDist(path)
+	if err != nil {
+		return PacksIndex{}, Graph{}, err
+	}
+	for _, name := range []string{IndexFile, GraphFile} {
+		if files[name] == nil && files[name+".gz"] == nil {
+			return PacksIndex{}, Graph{}, fmt.Errorf("%w: %s has no %s entry: %w", ErrDistNotFound, path, name, fs.ErrNotExist)
+		}
+	}
+	return LoadAll(&Loader{FS: files})
+}
+
+// tarFiles holds dist files read from an archive, by base name
+type tarFiles map[string][]byte
+
+func (t tarFiles) Open(name string) (fs.File, error) {
+	data, ok := t[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memFile{Reader: bytes.NewReader(data), name: name, size: int64(len(data))}, nil
+}
+
+// readTarDist reads the dist file entries of archive, skipping the rest
+func readTarDist(archive string) (tarFiles, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, distNotFound(err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing %s: %w", archive, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	wanted := map[string]bool{IndexFile: true, IndexFile + ".gz": true, GraphFile: true, GraphFile + ".gz": true}
+	files := make(tarFiles)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", archive, err)
+		}
+		name := path.Base(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || !wanted[name] || files[name] != nil {
+			continue
+		}
+		if files[name], err = io.ReadAll(tr); err != nil {
+			return nil, fmt.Errorf("reading %s from %s: %w", hdr.Name, archive, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTar writes an archive of files, in order, to dir/name, gzipped
+// when name ends in .gz
+func writeTar(t *testing.T, dir, name string, files [][2]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f[0], Mode: 0o644, Size: int64(len(f[1]))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(f[1]))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if strings.HasSuffix(name, ".gz") {
+		data = gzipBytes(t, buf.String())
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFromTar(t *testing.T) {
+	dir := t.TempDir()
+	files := [][2]string{
+		{"dist/README.md", "not a dist file"},
+		{"dist/" + IndexFile, `{"metadata":{"pack_count":2},"packs":[{"id":"A"},{"id":"B"}]}`},
+		{"dist/" + GraphFile + ".gz", ""},
+		{"other/" + IndexFile, `{"packs":[{"id":"X"}]}`},
+	}
+	graph := gzipBytes(t, `{"edges":[{"source":"A","target":"B","type":"related"}]}`)
+	files[2][1] = string(graph)
+
+	for _, name := range []string{"dist.tar", "dist.tar.gz"} {
+		index, g, err := LoadFromTar(writeTar(t, dir, name, files))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(index.Packs) != 2 || index.Packs[0].ID != "A" || len(g.Edges) != 1 {
+			t.Errorf("%s: loaded %+v and %+v, want the dist/ entries", name, index, g)
+		}
+	}
+
+	path := writeTar(t, dir, "partial.tar", files[:2])
+	if _, _, err := LoadFromTar(path); !errors.Is(err, ErrDistNotFound) || !errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "no "+GraphFile+" entry") {
+		t.Errorf("missing graph: err = %v, want ErrDistNotFound naming %s", err, GraphFile)
+	}
+	if _, _, err := LoadFromTar(filepath.Join(dir, "absent.tar")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("absent archive: err = %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+// ORIGIN Go Kit - disclosure tiers
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// TierOrder ranks disclosure tiers from least to most restricted
+type TierOrder []string
+
+// DefaultTiers is the default tier order: public, internal, restricted,
+// secret
+var DefaultTiers = TierOrder{"public", "internal", "restricted", "secret"}
+
+// Rank returns the position of tier in o, or -1 if tier is not in o
+func (o TierOrder) Rank(tier string) int {
+	return slices.Index(o, tier)
+}
+
+// tierOrder is the order used by tier comparisons, set with SetTierOrder
+var tierOrder = DefaultTiers
+
+// SetTierOrder replaces the tier order used by ReachableWithinTier,
+// SortPacks and tier validation. An empty order restores DefaultTiers. It
+// is meant to be called once at startup and is not safe to call
+// concurrently with queries.
+func SetTierOrder(order []string) {
+	if len(order) == 0 {
+		tierOrder = DefaultTiers
+		return
+	}
+	tierOrder = slices.Clone(TierOrder(order))
+}
+
+// Tiers returns the current tier order
+func Tiers() TierOrder {
+	return slices.Clone(tierOrder)
+}
+
+// tierRank returns the rank of tier in the current order, or -1 if unknown
+func tierRank(tier string) int {
+	return tierOrder.Rank(tier)
+}
+
+// ReachableWithinTier returns pack IDs reachable from start along
+// outgoing edges, in breadth-first order, without entering any pack ranked
+// above maxTier. Packs with unknown tiers, or missing from the index, are
+// never entered.
+func ReachableWithinTier(index PacksIndex, graph Graph, start, maxTier string) []string {
+	byID := index.ByID()
+	limit := tierRank(maxTier)
+	allowed := func(id string) bool {
+		p, ok := byID[id]
+		if !ok {
+			return false
+		}
+		rank := tierRank(p.DisclosureTier)
+		return rank >= 0 && rank <= limit
+	}
+
+	if !allowed(start) {
+		return []string{}
+	}
+
+	adj := graph.BuildAdjacency()
+	visited := map[string]bool{start: true}
+	order := []string{start}
+	for queue := []string{start}; len(queue) > 0; queue = queue[1:] {
+		id := queue[0]
+		for _, edge := range filterDirection(adj[id], id, Outgoing) {
+			otherID := otherEnd(edge, id)
+			if visited[otherID] || !allowed(otherID) {
+				continue
+			}
+			visited[otherID] = true
+			order = append(order, otherID)
+			queue = append(queue, otherID)
+		}
+	}
+	return order
+}
+
+// NearestOfTier returns the pack of the given tier that a breadth-first
+// search from start reaches first, ignoring edge direction, and its hop
+// distance. start itself does not count, so the answer for a public pack
+// is the next public pack, not itself. Packs missing from index are
+// crossed but never returned. If no pack of the tier is reachable the
+// error wraps ErrNoPath.
+func (g Graph) NearestOfTier(index PacksIndex, start, tier string) (string, int, error) {
+	byID := index.ByID()
+	adj := g.BuildAdjacency()
+	dist := map[string]int{start: 0}
+	for queue := []string{start}; len(queue) > 0; queue = queue[1:] {
+		id := queue[0]
+		for _, edge := range adj[id] {
+			otherID := otherEnd(edge, id)
+			if _, seen := dist[otherID]; seen {
+				continue
+			}
+			dist[otherID] = dist[id] + 1
+			if p, ok := byID[otherID]; ok && p.DisclosureTier == tier {
+				return otherID, dist[otherID], nil
+			}
+			queue = append(queue, otherID)
+		}
+	}
+	return "", 0, fmt.Errorf("%w from %s to any %s pack", ErrNoPath, start, tier)
+}
+
+// FilterPacksExcludingTiers returns the IDs, in order, not naming a pack
+// of one of the exclude tiers. IDs missing from index have no tier and are
+// kept. It filters traversal results after expansion, so excluded packs
+// still connect the ones that are shown.
+func FilterPacksExcludingTiers(index PacksIndex, ids []string, exclude []string) []string {
+	byID := index.ByID()
+	kept := []string{}
+	for _, id := range ids {
+		if p, ok := byID[id]; !ok || !slices.Contains(exclude, p.DisclosureTier) {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// TierSubgraph returns the edges of graph whose endpoints are both packs
+// of the given tier, with metadata counts recomputed
+func TierSubgraph(index PacksIndex, graph Graph, tier string) Graph {
+	return tiersSubgraph(index, graph, []string{tier})
+}
+
+// tiersSubgraph is TierSubgraph for a set of tiers, as FilterByTier
+// selects them
+func tiersSubgraph(index PacksIndex, graph Graph, tiers []string) Graph {
+	var ids []string
+	for _, p := range FilterByTier(index.Packs, tiers) {
+		ids = append(ids, p.ID)
+	}
+	return graph.Subgraph(ids)
+}
+
+// CrossTierEdges returns the edges, in graph order, that join a pack of
+// lowerTier to a pack of higherTier in either direction
+func CrossTierEdges(index PacksIndex, graph Graph, lowerTier, higherTier string) []GraphEdge {
+	byID := index.ByID()
+	tierOf := func(id string) (string, bool) {
+		p, ok := byID[id]
+		return p.DisclosureTier, ok
+	}
+
+	var edges []GraphEdge
+	for _, edge := range graph.Edges {
+		src, okSrc := tierOf(edge.Source)
+		dst, okDst := tierOf(edge.Target)
+		if !okSrc || !okDst {
+			continue
+		}
+		if (src == lowerTier && dst == higherTier) || (src == higherTier && dst == lowerTier) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// TierAsymmetries returns the edges, in graph order, joining packs whose
+// tiers are more than one rank apart in the current tier order, such as
+// public and restricted under DefaultTiers. Such links may mean a pack is
+// miscategorized or was linked across tiers by accident. Edges touching a
+// pack that is missing from index, or whose tier is not in the order, are
+// skipped.
+func TierAsymmetries(index PacksIndex, graph Graph) []GraphEdge {
+	byID := index.ByID()
+	rankOf := func(id string) int {
+		p, ok := byID[id]
+		if !ok {
+			return -1
+		}
+		return tierRank(p.DisclosureTier)
+	}
+
+	var edges []GraphEdge
+	for _, edge := range graph.Edges {
+		src, dst := rankOf(edge.Source), rankOf(edge.Target)
+		if src >= 0 && dst >= 0 && max(src, dst)-min(src, dst) > 1 {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// TierWeightedPath returns a cheapest undirected path from from to to and
+// its cost, where each step costs the edge's weight plus the penalty of the
+// tier of the pack it enters. Tiers without a penalty, and packs missing
+// from index, add nothing, so a large penalty on restricted tiers keeps
+// paths in public packs unless a restricted hop is unavoidable. Negative
+// weights and penalties are rejected.
+func (g Graph) TierWeightedPath(index PacksIndex, from, to string, tierPenalty map[string]float64) ([]string, float64, error) {
+	if err := g.checkWeights(); err != nil {
+		return nil, 0, err
+	}
+	for tier, penalty := range tierPenalty {
+		if penalty < 0 {
+			return nil, 0, fmt.Errorf("tier %s has negative penalty %g", tier, penalty)
+		}
+	}
+	byID := index.ByID()
+	return g.dijkstra(from, to, func(edge GraphEdge, next string) float64 {
+		return edge.Cost() + tierPenalty[byID[next].DisclosureTier]
+	})
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReachableWithinTier(t *testing.T) {
+	// A - B - R - C, with a side branch B - D. R is restricted.
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", DisclosureTier: "public"},
+		{ID: "B", DisclosureTier: "public"},
+		{ID: "R", DisclosureTier: "restricted"},
+		{ID: "C", DisclosureTier: "public"},
+		{ID: "D", DisclosureTier: "internal"},
+	}}
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "R", Type: "related"},
+		{Source: "R", Target: "C", Type: "related"},
+		{Source: "B", Target: "D", Type: "related"},
+	}}
+
+	tests := []struct {
+		start, maxTier string
+		want           []string
+	}{
+		{"A", "public", []string{"A", "B"}},
+		{"A", "internal", []string{"A", "B", "D"}},
+		{"A", "restricted", []string{"A", "B", "R", "D", "C"}},
+		{"R", "public", []string{}},
+		{"C", "restricted", []string{"C"}}, // edges are followed source to target only
+		{"A", "bogus", []string{}},
+	}
+	for _, tt := range tests {
+		if got := ReachableWithinTier(index, graph, tt.start, tt.maxTier); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ReachableWithinTier(%s, %s) = %v, want %v", tt.start, tt.maxTier, got, tt.want)
+		}
+	}
+}
+
+func TestTierOrder(t *testing.T) {
+	order := TierOrder{"green", "amber", "red"}
+	for tier, want := range map[string]int{"green": 0, "red": 2, "public": -1, "": -1} {
+		if got := order.Rank(tier); got != want {
+			t.Errorf("Rank(%q) = %d, want %d", tier, got, want)
+		}
+	}
+
+	SetTierOrder(order)
+	defer SetTierOrder(nil)
+
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", DisclosureTier: "green"},
+		{ID: "B", DisclosureTier: "red"},
+		{ID: "C", DisclosureTier: "amber"},
+	}}
+	graph := Graph{Edges: []GraphEdge{{Source: "A", Target: "B"}, {Source: "A", Target: "C"}}}
+	if got := ReachableWithinTier(index, graph, "A", "amber"); !reflect.DeepEqual(got, []string{"A", "C"}) {
+		t.Errorf("ReachableWithinTier = %v, want [A C]", got)
+	}
+	if err := SortPacks(index.Packs, SortByTier); err != nil || index.Packs[1].ID != "C" {
+		t.Errorf("SortPacks by tier = %v, %v", index.Packs, err)
+	}
+
+	SetTierOrder(nil)
+	if got := Tiers(); !reflect.DeepEqual(got, DefaultTiers) {
+		t.Errorf("Tiers after reset = %v", got)
+	}
+}
+
+func TestTierSubgraph(t *testing.T) {
+	// A and C are public, B internal; D is not in the index
+	index := PacksIndex{Packs: samplePacks()}
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "C", Target: "D", Type: "child"},
+	}}
+
+	sub := TierSubgraph(index, graph, "public")
+	if want := []GraphEdge{{Source: "A", Target: "C", Type: "related"}}; !reflect.DeepEqual(sub.Edges, want) {
+		t.Errorf("public edges = %+v, want %+v", sub.Edges, want)
+	}
+	if sub.Metadata.NodeCount != 2 || sub.Metadata.EdgeCount != 1 {
+		t.Errorf("metadata = %+v", sub.Metadata)
+	}
+	if sub := TierSubgraph(index, graph, "internal"); len(sub.Edges) != 0 {
+		t.Errorf("internal edges = %+v, want none", sub.Edges)
+	}
+}
+
+func TestCrossTierEdges(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "P", DisclosureTier: "public"},
+		{ID: "Q", DisclosureTier: "public"},
+		{ID: "R", DisclosureTier: "restricted"},
+		{ID: "I", DisclosureTier: "internal"},
+	}}
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "P", Target: "R", Type: "related"},
+		{Source: "P", Target: "Q", Type: "related"},
+		{Source: "R", Target: "Q", Type: "child"},
+		{Source: "P", Target: "I", Type: "related"},
+		{Source: "I", Target: "R", Type: "related"},
+		{Source: "P", Target: "X", Type: "related"},
+	}}
+
+	got := CrossTierEdges(index, graph, "public", "restricted")
+	want := []GraphEdge{
+		{Source: "P", Target: "R", Type: "related"},
+		{Source: "R", Target: "Q", Type: "child"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CrossTierEdges = %v, want %v", got, want)
+	}
+}
+
+func TestTierWeightedPath(t *testing.T) {
+	// A - R - C is shortest by hops, but R is restricted; A - P - Q - C
+	// stays public
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", DisclosureTier: "public"},
+		{ID: "R", DisclosureTier: "restricted"},
+		{ID: "P", DisclosureTier: "public"},
+		{ID: "Q", DisclosureTier: "public"},
+		{ID: "C", DisclosureTier: "public"},
+		{ID: "X", DisclosureTier: "public"},
+	}}
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "R"}, {Source: "R", Target: "C"},
+		{Source: "A", Target: "P"}, {Source: "P", Target: "Q"}, {Source: "Q", Target: "C"},
+		{Source: "R", Target: "X"},
+	}}
+	penalty := map[string]float64{"restricted": 10}
+
+	path, cost, err := g.TierWeightedPath(index, "A", "C", nil)
+	if err != nil || !reflect.DeepEqual(path, []string{"A", "R", "C"}) || cost != 2 {
+		t.Errorf("no penalty: %v, %v, %v; want [A R C] at cost 2", path, cost, err)
+	}
+	path, cost, err = g.TierWeightedPath(index, "A", "C", penalty)
+	if err != nil || !reflect.DeepEqual(path, []string{"A", "P", "Q", "C"}) || cost != 3 {
+		t.Errorf("restricted penalty: %v, %v, %v; want [A P Q C] at cost 3", path, cost, err)
+	}
+	// X is only reachable through R, so the penalty is paid
+	path, cost, err = g.TierWeightedPath(index, "A", "X", penalty)
+	if err != nil || !reflect.DeepEqual(path, []string{"A", "R", "X"}) || cost != 12 {
+		t.Errorf("unavoidable hop: %v, %v, %v; want [A R X] at cost 12", path, cost, err)
+	}
+	if _, _, err := g.TierWeightedPath(index, "A", "C", map[string]float64{"public": -1}); err == nil {
+		t.Error("negative penalty accepted")
+	}
+}
+
+func TestNearestOfTier(t *testing.T) {
+	// I1 - I2 - P1 and I1 - R - P2 - P3: P1 and P2 are both two hops away,
+	// and P1's edge comes first
+	index := PacksIndex{Packs: []Pack{
+		{ID: "I1", DisclosureTier: "internal"},
+		{ID: "I2", DisclosureTier: "internal"},
+		{ID: "R", DisclosureTier: "restricted"},
+		{ID: "P1", DisclosureTier: "public"},
+		{ID: "P2", DisclosureTier: "public"},
+		{ID: "P3", DisclosureTier: "public"},
+	}}
+	g := Graph{Edges: []GraphEdge{
+		{Source: "I1", Target: "I2"}, {Source: "P1", Target: "I2"},
+		{Source: "I1", Target: "R"}, {Source: "R", Target: "P2"}, {Source: "P2", Target: "P3"},
+	}}
+
+	tests := []struct {
+		start, tier string
+		want        string
+		distance    int
+	}{
+		{"I1", "public", "P1", 2},
+		{"P2", "public", "P3", 1},
+		{"P3", "restricted", "R", 2},
+		{"P1", "internal", "I2", 1},
+	}
+	for _, tt := range tests {
+		got, distance, err := g.NearestOfTier(index, tt.start, tt.tier)
+		if err != nil || got != tt.want || distance != tt.distance {
+			t.Errorf("NearestOfTier(%s, %s) = %s, %d, %v; want %s, %d", tt.start, tt.tier, got, distance, err, tt.want, tt.distance)
+		}
+	}
+	if _, _, err := g.NearestOfTier(index, "I1", "secret"); !errors.Is(err, ErrNoPath) {
+		t.Errorf("no pack of tier: err = %v, want ErrNoPath", err)
+	}
+}
+
+func TestFilterPacksExcludingTiers(t *testing.T) {
+	index := PacksIndex{Packs: samplePacks()}
+	ids := []string{"C", "B", "X", "A"}
+
+	if got, want := FilterPacksExcludingTiers(index, ids, []string{"internal"}), []string{"C", "X", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("excluding internal = %v, want %v", got, want)
+	}
+	if got := FilterPacksExcludingTiers(index, ids, nil); !reflect.DeepEqual(got, ids) {
+		t.Errorf("excluding nothing = %v, want %v", got, ids)
+	}
+	if got := FilterPacksExcludingTiers(index, ids, []string{"public", "internal"}); !reflect.DeepEqual(got, []string{"X"}) {
+		t.Errorf("excluding both tiers = %v, want [X]", got)
+	}
+}
+
+func TestTierAsymmetries(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "P", DisclosureTier: "public"},
+		{ID: "I", DisclosureTier: "internal"},
+		{ID: "R", DisclosureTier: "restricted"},
+		{ID: "S", DisclosureTier: "secret"},
+		{ID: "U", DisclosureTier: "draft"},
+	}}
+	graph := Graph{Edges: []GraphEdge{
+		{Source: "P", Target: "I", Type: "related"},
+		{Source: "P", Target: "R", Type: "related"},
+		{Source: "S", Target: "I", Type: "child"},
+		{Source: "I", Target: "R", Type: "related"},
+		{Source: "P", Target: "U", Type: "related"},
+		{Source: "P", Target: "Z", Type: "related"},
+	}}
+
+	want := []GraphEdge{graph.Edges[1], graph.Edges[2]}
+	if got := TierAsymmetries(index, graph); !reflect.DeepEqual(got, want) {
+		t.Errorf("TierAsymmetries = %v, want %v", got, want)
+	}
+}
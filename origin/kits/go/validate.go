@@ -0,0 +1,385 @@
+// ORIGIN Go Kit - dataset validation
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ValidateOptions selects how ValidateWith runs. The zero value runs
+// every check and collects every problem, as Validate does.
+type ValidateOptions struct {
+	// FailFast stops at the first problem found, so at most one is returned
+	FailFast bool
+	// Checks names the checks to run, from ValidateChecks and Extra; empty
+	// runs all. Names that are not checks match nothing.
+	Checks []string
+	// Progress, if set, receives the number of edges checked so far
+	Progress Progress
+	// Extra are run after the built-in checks, subject to Checks and
+	// FailFast like them
+	Extra []ValidateCheck
+}
+
+// ValidateCheck is a named check a caller adds to ValidateWith
+type ValidateCheck struct {
+	Name string
+	Run  func() []error
+}
+
+// Enabled reports whether the check name is selected by o.Checks
+func (o ValidateOptions) Enabled(name string) bool {
+	return len(o.Checks) == 0 || slices.Contains(o.Checks, name)
+}
+
+// validation collects the problems of one Validate run
+type validation struct {
+	opts ValidateOptions
+	errs []error
+}
+
+// add records err and reports whether checking should go on
+func (v *validation) add(err error) bool {
+	v.errs = append(v.errs, err)
+	return !v.done()
+}
+
+// addAll records each of errs until checking should stop
+func (v *validation) addAll(errs []error) {
+	for _, err := range errs {
+		if !v.add(err) {
+			return
+		}
+	}
+}
+
+// done reports whether FailFast has been met
+func (v *validation) done() bool {
+	return v.opts.FailFast && len(v.errs) > 0
+}
+
+// validateChecks are the checks Validate runs, in order
+var validateChecks = []struct {
+	name string
+	run  func(v *validation, index PacksIndex, graph Graph)
+}{
+	{"pack-count", func(v *validation, index PacksIndex, _ Graph) {
+		if err := CheckPackCount(index); err != nil {
+			v.add(err)
+		}
+	}},
+	{"graph-counts", func(v *validation, _ PacksIndex, graph Graph) {
+		v.addAll(CheckGraphCounts(graph))
+	}},
+	{"duplicate-ids", func(v *validation, index PacksIndex, _ Graph) {
+		dups := FindDuplicateIDs(index)
+		ids := make([]string, 0, len(dups))
+		for id := range dups {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			if !v.add(fmt.Errorf("pack ID %s appears %d times", id, dups[id])) {
+				return
+			}
+		}
+	}},
+	{"dangling", checkDangling},
+	{"self-loops", func(v *validation, _ PacksIndex, graph Graph) {
+		for _, edge := range graph.SelfLoops() {
+			if !v.add(fmt.Errorf("edge %s -> %s (%s): self-loop", edge.Source, edge.Target, edge.Type)) {
+				return
+			}
+		}
+	}},
+	{"weights", func(v *validation, _ PacksIndex, graph Graph) {
+		for _, edge := range graph.Edges {
+			if edge.Weight < 0 && !v.add(fmt.Errorf("edge %s -> %s (%s): negative weight %g",
+				edge.Source, edge.Target, edge.Type, edge.Weight)) {
+				return
+			}
+		}
+	}},
+	{"related", func(v *validation, index PacksIndex, _ Graph) {
+		v.addAll(CheckRelatedReferences(index))
+	}},
+}
+
+// ValidateChecks returns the names of the checks Validate runs, in order
+func ValidateChecks() []string {
+	names := make([]string, len(validateChecks))
+	for i, c := range validateChecks {
+		names[i] = c.name
+	}
+	return names
+}
+
+// Validate cross-checks the index and graph and returns one error per problem
+func Validate(index PacksIndex, graph Graph) []error {
+	return ValidateWith(index, graph, ValidateOptions{})
+}
+
+// ValidateWithProgress is Validate reporting the edges checked so far to
+// progress, which may be nil
+func ValidateWithProgress(index PacksIndex, graph Graph, progress Progress) []error {
+	return ValidateWith(index, graph, ValidateOptions{Progress: progress})
+}
+
+// ValidateWith is Validate running the checks selected by opts
+func ValidateWith(index PacksIndex, graph Graph, opts ValidateOptions) []error {
+	v := &validation{opts: opts}
+	for _, c := range validateChecks {
+		if !opts.Enabled(c.name) {
+			continue
+		}
+		if c.run(v, index, graph); v.done() {
+			return v.errs
+		}
+	}
+	for _, c := range opts.Extra {
+		if !opts.Enabled(c.Name) {
+			continue
+		}
+		if v.addAll(c.Run()); v.done() {
+			break
+		}
+	}
+	return v.errs
+}
+
+// checkDangling reports edges whose source or target is not in the index
+func checkDangling(v *validation, index PacksIndex, graph Graph) {
+	known := index.ByID()
+	progress := v.opts.Progress
+	defer progress.report(len(graph.Edges), len(graph.Edges))
+	for i, edge := range graph.Edges {
+		if i%progressInterval == 0 {
+			progress.report(i, len(graph.Edges))
+		}
+		if _, ok := known[edge.Source]; !ok {
+			if !v.add(fmt.Errorf("edge %s -> %s (%s): unknown source %q",
+				edge.Source, edge.Target, edge.Type, edge.Source)) {
+				return
+			}
+		}
+		if _, ok := known[edge.Target]; !ok {
+			if !v.add(fmt.Errorf("edge %s -> %s (%s): unknown target %q",
+				edge.Source, edge.Target, edge.Type, edge.Target)) {
+				return
+			}
+		}
+	}
+}
+
+// CheckRelatedReferences reports each Related entry naming a pack ID that
+// is not in the index, such as one left behind when the pack was deleted
+func CheckRelatedReferences(index PacksIndex) []error {
+	known := index.ByID()
+	var errs []error
+	for _, p := range index.Packs {
+		for _, id := range p.Related {
+			if _, ok := known[id]; !ok {
+				errs = append(errs, fmt.Errorf("pack %s: related ID %q is not in the index", p.ID, id))
+			}
+		}
+	}
+	return errs
+}
+
+// CheckPackCount reports a declared pack_count that differs from the
+// number of packs in the index
+func CheckPackCount(index PacksIndex) error {
+	if declared, actual := index.Metadata.PackCount, len(index.Packs); declared != actual {
+		return fmt.Errorf("index metadata declares pack_count %d, found %d packs", declared, actual)
+	}
+	return nil
+}
+
+// FindDuplicateIDs returns each pack ID that appears more than once in
+// the index, mapped to its number of occurrences
+func FindDuplicateIDs(index PacksIndex) map[string]int {
+	counts := make(map[string]int, len(index.Packs))
+	for _, p := range index.Packs {
+		counts[p.ID]++
+	}
+	dups := make(map[string]int)
+	for id, n := range counts {
+		if n > 1 {
+			dups[id] = n
+		}
+	}
+	return dups
+}
+
+// FindDuplicateTitles returns each title shared by more than one pack,
+// compared case-insensitively, mapped to the IDs of those packs in index
+// order. Keys use the first pack's spelling; blank titles are ignored.
+func FindDuplicateTitles(packs []Pack) map[string][]string {
+	first := make(map[string]string)
+	ids := make(map[string][]string)
+	for _, p := range packs {
+		key := strings.ToLower(strings.TrimSpace(p.Title))
+		if key == "" {
+			continue
+		}
+		if _, ok := first[key]; !ok {
+			first[key] = p.Title
+		}
+		ids[key] = append(ids[key], p.ID)
+	}
+
+	dups := make(map[string][]string)
+	for key, group := range ids {
+		if len(group) > 1 {
+			dups[first[key]] = group
+		}
+	}
+	return dups
+}
+
+// CheckGraphCounts reports declared node_count and edge_count values that
+// differ from the graph. Nodes are the distinct edge endpoints.
+func CheckGraphCounts(graph Graph) []error {
+	var errs []error
+	if declared, actual := graph.Metadata.NodeCount, len(graph.BuildAdjacency()); declared != actual {
+		errs = append(errs, fmt.Errorf("graph metadata declares node_count %d, found %d nodes", declared, actual))
+	}
+	if declared, actual := graph.Metadata.EdgeCount, len(graph.Edges); declared != actual {
+		errs = append(errs, fmt.Errorf("graph metadata declares edge_count %d, found %d edges", declared, actual))
+	}
+	return errs
+}
+
+// ValidateTiers reports packs whose disclosure tier is not in allowed
+func ValidateTiers(packs []Pack, allowed []string) []error {
+	ok := make(map[string]bool, len(allowed))
+	for _, tier := range allowed {
+		ok[tier] = true
+	}
+
+	var errs []error
+	for _, p := range packs {
+		if !ok[p.DisclosureTier] {
+			errs = append(errs, fmt.Errorf("pack %s: unknown disclosure tier %q", p.ID, p.DisclosureTier))
+		}
+	}
+	return errs
+}
+
+// RelatedReciprocity reports each Related declaration that is not returned:
+// pack A lists B, but B, which is in index, does not list A. IDs missing
+// from the index and self-references are left to Validate; a repeated ID
+// is reported once.
+func RelatedReciprocity(index PacksIndex) []error {
+	lists := make(map[string]map[string]bool, len(index.Packs))
+	for _, p := range index.Packs {
+		set := lists[p.ID]
+		if set == nil {
+			set = make(map[string]bool, len(p.Related))
+			lists[p.ID] = set
+		}
+		for _, id := range p.Related {
+			set[id] = true
+		}
+	}
+
+	var errs []error
+	for _, p := range index.Packs {
+		reported := make(map[string]bool)
+		for _, id := range p.Related {
+			back, ok := lists[id]
+			if !ok || id == p.ID || back[p.ID] || reported[id] {
+				continue
+			}
+			reported[id] = true
+			errs = append(errs, fmt.Errorf("pack %s lists %s in related, but %s does not list %s", p.ID, id, id, p.ID))
+		}
+	}
+	return errs
+}
+
+// ValidateEdgeTypes reports edges whose type is not in allowed
+func ValidateEdgeTypes(graph Graph, allowed []string) []error {
+	ok := make(map[string]bool, len(allowed))
+	for _, t := range allowed {
+		ok[t] = true
+	}
+
+	var errs []error
+	for _, edge := range graph.Edges {
+		if !ok[edge.Type] {
+			errs = append(errs, fmt.Errorf("edge %s -> %s: unknown edge type %q", edge.Source, edge.Target, edge.Type))
+		}
+	}
+	return errs
+}
+
+// CheckSymmetry reports edges of the symmetric types that have no reverse
+// edge of the same type. Repeated edges are reported once.
+func CheckSymmetry(graph Graph, symmetricTypes []string) []error {
+	type key struct{ source, target, typ string }
+	present := make(map[key]bool, len(graph.Edges))
+	for _, edge := range graph.Edges {
+		present[key{edge.Source, edge.Target, edge.Type}] = true
+	}
+
+	var errs []error
+	reported := make(map[key]bool)
+	for _, edge := range graph.Edges {
+		k := key{edge.Source, edge.Target, edge.Type}
+		if !slices.Contains(symmetricTypes, edge.Type) || reported[k] {
+			continue
+		}
+		if !present[key{edge.Target, edge.Source, edge.Type}] {
+			reported[k] = true
+			errs = append(errs, fmt.Errorf("edge %s -> %s: symmetric type %q has no reverse edge %s -> %s",
+				edge.Source, edge.Target, edge.Type, edge.Target, edge.Source))
+		}
+	}
+	return errs
+}
+
+// EdgeVocabulary returns the edge types in use, sorted, for seeding an
+// allowed list
+func EdgeVocabulary(graph Graph) []string {
+	counts := graph.EdgeTypeCounts()
+	vocab := make([]string, 0, len(counts))
+	for t := range counts {
+		vocab = append(vocab, t)
+	}
+	sort.Strings(vocab)
+	return vocab
+}
+
+// LoadVocabulary reads one term per line from path, skipping blank lines
+// and lines starting with #
+func LoadVocabulary(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readLines(f)
+}
+
+// readLines returns the trimmed lines of r, skipping blank lines and lines
+// starting with #
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withCounts sets metadata counts that match the index and graph
+func withCounts(index PacksIndex, graph Graph) (PacksIndex, Graph) {
+	index.Metadata.PackCount = len(index.Packs)
+	graph.Metadata.NodeCount = len(graph.BuildAdjacency())
+	graph.Metadata.EdgeCount = len(graph.Edges)
+	return index, graph
+}
+
+func TestValidateDanglingEdges(t *testing.T) {
+	index, graph := withCounts(PacksIndex{Packs: samplePacks()}, Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "A", Target: "Z", Type: "related"},
+		{Source: "Y", Target: "Z", Type: "child"},
+	}})
+
+	errs := Validate(index, graph)
+	if len(errs) != 3 {
+		t.Fatalf("got %d errors, want 3: %v", len(errs), errs)
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	index, graph := withCounts(PacksIndex{Packs: samplePacks()}, Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "Z", Type: "related"},
+		{Source: "Y", Target: "Z", Type: "child"},
+		{Source: "C", Target: "C", Type: "child"},
+	}})
+	index.Metadata.PackCount = 9
+
+	if errs := Validate(index, graph); len(errs) != 5 {
+		t.Errorf("all checks: got %d errors, want 5: %v", len(errs), errs)
+	}
+	errs := ValidateWith(index, graph, ValidateOptions{FailFast: true})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "pack_count 9") {
+		t.Errorf("fail fast = %v, want just the pack count", errs)
+	}
+	errs = ValidateWith(index, graph, ValidateOptions{FailFast: true, Checks: []string{"dangling", "self-loops"}})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `unknown target "Z"`) {
+		t.Errorf("fail fast on dangling = %v, want the first dangling edge", errs)
+	}
+	errs = ValidateWith(index, graph, ValidateOptions{Checks: []string{"self-loops"}})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "self-loop") {
+		t.Errorf("self-loops only = %v", errs)
+	}
+	if errs := ValidateWith(index, graph, ValidateOptions{Checks: []string{"bogus"}}); len(errs) != 0 {
+		t.Errorf("unknown check ran: %v", errs)
+	}
+
+	extra := ValidateCheck{"extra", func() []error { return []error{errors.New("one"), errors.New("two")} }}
+	if errs := ValidateWith(index, graph, ValidateOptions{Checks: []string{"extra"}, Extra: []ValidateCheck{extra}}); len(errs) != 2 {
+		t.Errorf("extra check = %v, want both of its problems", errs)
+	}
+	errs = ValidateWith(index, graph, ValidateOptions{FailFast: true, Checks: []string{"extra"}, Extra: []ValidateCheck{extra}})
+	if len(errs) != 1 || errs[0].Error() != "one" {
+		t.Errorf("fail fast on extra = %v, want just its first problem", errs)
+	}
+}
+
+func TestValidateWeights(t *testing.T) {
+	index, graph := withCounts(PacksIndex{Packs: samplePacks()}, Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related", Weight: 2},
+		{Source: "B", Target: "C", Type: "child", Weight: -1},
+	}})
+
+	errs := Validate(index, graph)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "B -> C (child): negative weight -1") {
+		t.Errorf("errors = %v, want the negative weight", errs)
+	}
+}
+
+func TestValidateSelfLoops(t *testing.T) {
+	index, graph := withCounts(PacksIndex{Packs: samplePacks()}, Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "C", Target: "C", Type: "child"},
+	}})
+
+	errs := Validate(index, graph)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "C -> C (child): self-loop") {
+		t.Errorf("errors = %v, want one self-loop", errs)
+	}
+}
+
+func TestValidateClean(t *testing.T) {
+	index, graph := withCounts(PacksIndex{Packs: samplePacks()},
+		Graph{Edges: []GraphEdge{{Source: "A", Target: "C", Type: "related"}}})
+
+	if errs := Validate(index, graph); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestCheckRelatedReferences(t *testing.T) {
+	packs := samplePacks()
+	packs[0].Related = []string{"B", "X"}
+	packs[2].Related = []string{"Y"}
+
+	errs := CheckRelatedReferences(PacksIndex{Packs: packs})
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), `pack A: related ID "X"`) {
+		t.Errorf("got %v, want errors for X and Y", errs)
+	}
+	index, graph := withCounts(PacksIndex{Packs: packs}, Graph{})
+	if got := Validate(index, graph); len(got) != 2 {
+		t.Errorf("Validate: got %v, want the two broken references", got)
+	}
+}
+
+func TestFindDuplicateIDs(t *testing.T) {
+	index := PacksIndex{Packs: append(samplePacks(),
+		Pack{ID: "A", Title: "Alpha again"},
+		Pack{ID: "C", Title: "Gamma again"},
+		Pack{ID: "A", Title: "Alpha thrice"},
+	)}
+
+	got := FindDuplicateIDs(index)
+	if len(got) != 2 || got["A"] != 3 || got["C"] != 2 {
+		t.Errorf("FindDuplicateIDs = %v, want A:3 C:2", got)
+	}
+	if got := FindDuplicateIDs(PacksIndex{Packs: samplePacks()}); len(got) != 0 {
+		t.Errorf("no duplicates: got %v", got)
+	}
+
+	index, graph := withCounts(index, Graph{})
+	errs := Validate(index, graph)
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "A appears 3 times") {
+		t.Errorf("Validate = %v, want duplicate ID errors", errs)
+	}
+}
+
+func TestCheckCounts(t *testing.T) {
+	index := PacksIndex{Packs: samplePacks()}
+	index.Metadata.PackCount = 5
+	err := CheckPackCount(index)
+	if err == nil || !strings.Contains(err.Error(), "5") || !strings.Contains(err.Error(), "3") {
+		t.Errorf("CheckPackCount = %v, want declared 5 and actual 3", err)
+	}
+
+	graph := cycleGraph()
+	graph.Metadata.NodeCount = 4
+	graph.Metadata.EdgeCount = 10
+	if errs := CheckGraphCounts(graph); len(errs) != 1 {
+		t.Errorf("CheckGraphCounts = %v, want one edge_count error", errs)
+	}
+}
+
+func TestValidateTiers(t *testing.T) {
+	packs := append(samplePacks(),
+		Pack{ID: "D", DisclosureTier: "publik"},
+		Pack{ID: "E"},
+	)
+
+	errs := ValidateTiers(packs, DefaultTiers)
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "D") || !strings.Contains(errs[0].Error(), "publik") {
+		t.Errorf("default tiers: %v", errs)
+	}
+	if errs := ValidateTiers(packs, []string{"public"}); len(errs) != 3 {
+		t.Errorf("public only: got %d errors, want 3", len(errs))
+	}
+}
+
+func TestValidateEdgeTypes(t *testing.T) {
+	graph := cycleGraph()
+	graph.Edges = append(graph.Edges, GraphEdge{Source: "D", Target: "E", Type: "relatd"})
+
+	errs := ValidateEdgeTypes(graph, []string{"related", "child"})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "D -> E") || !strings.Contains(errs[0].Error(), `"relatd"`) {
+		t.Errorf("ValidateEdgeTypes = %v", errs)
+	}
+	if errs := ValidateEdgeTypes(graph, EdgeVocabulary(graph)); len(errs) != 0 {
+		t.Errorf("derived vocabulary should accept every edge: %v", errs)
+	}
+	if got := EdgeVocabulary(graph); !reflect.DeepEqual(got, []string{"child", "relatd", "related"}) {
+		t.Errorf("EdgeVocabulary = %v", got)
+	}
+}
+
+func TestLoadVocabulary(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "vocab.txt", "# relationship types\nrelated\n\n  child  \nparent\n")
+
+	got, err := LoadVocabulary(filepath.Join(dir, "vocab.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"related", "child", "parent"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadVocabulary = %v, want %v", got, want)
+	}
+}
+
+func TestCheckSymmetry(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "A", Type: "related"},
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "C", Target: "A", Type: "child"},
+		{Source: "D", Target: "D", Type: "related"},
+		{Source: "A", Target: "D", Type: "child"},
+	}}
+
+	errs := CheckSymmetry(g, []string{"related"})
+	if len(errs) != 1 {
+		t.Fatalf("errs = %v, want only A -> C", errs)
+	}
+	if msg := errs[0].Error(); !strings.Contains(msg, "A -> C") || !strings.Contains(msg, "C -> A") {
+		t.Errorf("error %q should name both directions", msg)
+	}
+	if errs := CheckSymmetry(g, nil); len(errs) != 0 {
+		t.Errorf("no symmetric types: errs = %v", errs)
+	}
+}
+
+func TestFindDuplicateTitles(t *testing.T) {
+	packs := []Pack{
+		{ID: "A", Title: "Holodeck Seed"},
+		{ID: "B", Title: "Other"},
+		{ID: "C", Title: "holodeck seed "},
+		{ID: "D"},
+		{ID: "E"},
+		{ID: "F", Title: "HOLODECK SEED"},
+	}
+
+	got := FindDuplicateTitles(packs)
+	want := map[string][]string{"Holodeck Seed": {"A", "C", "F"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindDuplicateTitles = %v, want %v", got, want)
+	}
+}
+
+func TestRelatedReciprocity(t *testing.T) {
+	index := PacksIndex{Packs: []Pack{
+		{ID: "A", Related: []string{"B", "C", "C", "A", "Z"}},
+		{ID: "B", Related: []string{"A"}},
+		{ID: "C", Related: []string{"B"}},
+	}}
+
+	var got []string
+	for _, err := range RelatedReciprocity(index) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"pack A lists C in related, but C does not list A",
+		"pack C lists B in related, but B does not list C",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RelatedReciprocity = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+// ORIGIN Go Kit - random walks
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"cmp"
+	"maps"
+	"math/rand/v2"
+	"slices"
+)
+
+// RandomWalk returns the nodes visited by a random walk of up to steps
+// steps from start, starting with start itself. Each step follows one of
+// the current node's edges, ignoring direction, chosen uniformly, so a
+// neighbor joined by several edges is that much more likely. Self-loops
+// are not followed, and the walk ends early at a node with no other
+// neighbors. The same seed always gives the same walk; a start that is not
+// in the graph gives nil.
+func (g Graph) RandomWalk(start string, steps int, seed int64) []string {
+	adj := g.BuildAdjacency()
+	if _, ok := adj[start]; !ok {
+		return nil
+	}
+	return randomWalk(adj, start, steps, rand.New(rand.NewPCG(uint64(seed), 0)))
+}
+
+// randomWalk is RandomWalk drawing from rng
+func randomWalk(adj map[string][]GraphEdge, start string, steps int, rng *rand.Rand) []string {
+	walk := []string{start}
+	at := start
+	for range steps {
+		var next []string
+		for _, edge := range adj[at] {
+			if other := otherEnd(edge, at); other != at {
+				next = append(next, other)
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		at = next[rng.IntN(len(next))]
+		walk = append(walk, at)
+	}
+	return walk
+}
+
+// WalkRecommendations runs walks random walks of up to steps steps from
+// start, as RandomWalk does, and returns the nodes they visit other than
+// start, most visited first, ties by ID. The same seed always gives the
+// same list.
+func (g Graph) WalkRecommendations(start string, walks, steps int, seed int64) []string {
+	adj := g.BuildAdjacency()
+	if _, ok := adj[start]; !ok {
+		return nil
+	}
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	visits := make(map[string]int)
+	for range walks {
+		for _, id := range randomWalk(adj, start, steps, rng)[1:] {
+			if id != start {
+				visits[id]++
+			}
+		}
+	}
+	return slices.SortedFunc(maps.Keys(visits), func(a, b string) int {
+		return cmp.Or(cmp.Compare(visits[b], visits[a]), cmp.Compare(a, b))
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRandomWalk(t *testing.T) {
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "B", Target: "C", Type: "child"},
+		{Source: "C", Target: "C", Type: "related"},
+	}}
+
+	walk := g.RandomWalk("A", 6, 1)
+	if len(walk) != 7 || walk[0] != "A" {
+		t.Fatalf("walk = %v, want A then 6 steps", walk)
+	}
+	for i := 1; i < len(walk); i++ {
+		if walk[i] == walk[i-1] {
+			t.Errorf("walk %v followed a self-loop", walk)
+		}
+	}
+	if !slices.Equal(walk, g.RandomWalk("A", 6, 1)) {
+		t.Error("the same seed gave different walks")
+	}
+
+	dead := Graph{Edges: []GraphEdge{{Source: "X", Target: "X", Type: "related"}}}
+	if got := dead.RandomWalk("X", 5, 1); !slices.Equal(got, []string{"X"}) {
+		t.Errorf("walk from a dead end = %v, want [X]", got)
+	}
+	if got := g.RandomWalk("Z", 5, 1); got != nil {
+		t.Errorf("walk from an unknown node = %v, want nil", got)
+	}
+}
+
+func TestWalkRecommendations(t *testing.T) {
+	// A has three edges to B and one to C; D hangs off C
+	g := Graph{Edges: []GraphEdge{
+		{Source: "A", Target: "B", Type: "related"},
+		{Source: "A", Target: "B", Type: "child"},
+		{Source: "B", Target: "A", Type: "related"},
+		{Source: "A", Target: "C", Type: "related"},
+		{Source: "C", Target: "D", Type: "related"},
+	}}
+
+	got := g.WalkRecommendations("A", 500, 1, 7)
+	if !slices.Equal(got, []string{"B", "C"}) {
+		t.Errorf("one-step recommendations = %v, want B (three edges) before C", got)
+	}
+	got = g.WalkRecommendations("A", 500, 3, 7)
+	if len(got) != 3 || got[0] != "B" || slices.Contains(got, "A") {
+		t.Errorf("recommendations = %v, want B first, D reached and A left out", got)
+	}
+	if !slices.Equal(got, g.WalkRecommendations("A", 500, 3, 7)) {
+		t.Error("the same seed gave different recommendations")
+	}
+	if got := g.WalkRecommendations("Z", 10, 3, 7); got != nil {
+		t.Errorf("unknown start = %v, want nil", got)
+	}
+}
@@ -0,0 +1,92 @@
+// ORIGIN Go Kit - watch mode
+//
+// Attribution: Ande + Kai (OI) + Whānau (OIs)
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// watchInterval is how often watched files are polled
+	watchInterval = 250 * time.Millisecond
+	// watchDebounce is how long files must stay unchanged before a rerun
+	watchDebounce = 500 * time.Millisecond
+)
+
+// fileStamp identifies one version of a file; the zero value means absent
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stampFiles returns the current stamp of each path
+func stampFiles(paths []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fileStamp{info.ModTime(), info.Size()}
+		} else {
+			stamps[path] = fileStamp{}
+		}
+	}
+	return stamps
+}
+
+// distFiles returns the files under the base directory that the loader
+// may read, or the bundle file itself. Index shards are the ones present
+// when it is called.
+func (l *Loader) distFiles() []string {
+	if l.bundle != nil {
+		return []string{l.BasePath}
+	}
+	names := []string{l.indexName(), l.graphName()}
+	switch {
+	case l.GraphOnly:
+		names = []string{l.graphName()}
+	case l.IndexShards != "":
+		shards, _ := l.shardNames()
+		names = append(shards, l.graphName())
+	}
+	var paths []string
+	for _, name := range names {
+		name = strings.TrimSuffix(name, ".gz")
+		paths = append(paths, filepath.Join(l.BasePath, name), filepath.Join(l.BasePath, name+".gz"))
+	}
+	return append(paths, filepath.Join(l.BasePath, AliasesFile))
+}
+
+// watchFiles polls paths and calls run each time they change, waiting
+// for writes to settle first. It returns when ctx is done.
+func watchFiles(ctx context.Context, paths []string, run func()) {
+	last := stampFiles(paths)
+	var changedAt time.Time
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			current := stampFiles(paths)
+			for _, path := range paths {
+				if current[path] != last[path] {
+					changedAt = now
+					break
+				}
+			}
+			last = current
+
+			if !changedAt.IsZero() && now.Sub(changedAt) >= watchDebounce {
+				changedAt = time.Time{}
+				run()
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchFilesDebouncesChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, IndexFile)
+	writeFile(t, dir, IndexFile, `{}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var runs atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		watchFiles(ctx, []string{path}, func() {
+			runs.Add(1)
+			cancel()
+		})
+		close(done)
+	}()
+
+	// A burst of writes should produce a single rerun
+	for i := 0; i < 3; i++ {
+		time.Sleep(watchInterval)
+		if err := os.WriteFile(path, []byte(`{"packs":[]}`+string(rune('a'+i))), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	if got := runs.Load(); got != 1 {
+		t.Errorf("run called %d times, want 1", got)
+	}
+}